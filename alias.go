@@ -0,0 +1,114 @@
+package tish
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+var aliasOperators = map[string]bool{
+	"|":  true,
+	";":  true,
+	"||": true,
+	"&&": true,
+}
+
+// SetAlias registers an alias. It returns false if the name is not valid for alias.
+func (s *Shell) SetAlias(name, value string) bool {
+	if name == "" || strings.ContainsAny(name, " \t\n=/$`'\"\\") {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.aliases[name] = value
+	return true
+}
+
+// Alias returns the value of an alias.
+func (s *Shell) Alias(name string) (string, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	value, ok := s.aliases[name]
+	return value, ok
+}
+
+// DelAlias removes an alias. It returns false if the alias doesn't exist.
+func (s *Shell) DelAlias(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.aliases[name]; !ok {
+		return false
+	}
+	delete(s.aliases, name)
+	return true
+}
+
+// ClearAliases removes all aliases.
+func (s *Shell) ClearAliases() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.aliases = map[string]string{}
+}
+
+// AliasNames returns sorted alias names.
+func (s *Shell) AliasNames() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	names := make([]string, 0, len(s.aliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandAlias replaces the first word of each simple command with its alias.
+//
+// If the value of alias ends with space, the next word is also checked like bash.
+// An alias that is already being expanded is not expanded again, so "alias ls='ls -h'" doesn't loop.
+func (s *Shell) expandAlias(cmdStr string) string {
+	s.lock.RLock()
+	aliases := copyMap(s.aliases)
+	s.lock.RUnlock()
+	if len(aliases) == 0 {
+		return cmdStr
+	}
+	return expandAliasText(aliases, cmdStr, map[string]bool{})
+}
+
+func expandAliasText(aliases map[string]string, src string, expanding map[string]bool) string {
+	words, err := parser.SplitWords(src)
+	if err != nil {
+		// parser reports the error later
+		return src
+	}
+	var b strings.Builder
+	last := 0
+	commandPos := true
+	for _, w := range words {
+		if !w.Quoted && aliasOperators[w.Text] {
+			commandPos = true
+			continue
+		}
+		if !commandPos {
+			continue
+		}
+		commandPos = false
+		if w.Quoted || expanding[w.Text] {
+			continue
+		}
+		value, ok := aliases[w.Text]
+		if !ok {
+			continue
+		}
+		expanding[w.Text] = true
+		b.WriteString(src[last:w.Start])
+		b.WriteString(expandAliasText(aliases, value, expanding))
+		delete(expanding, w.Text)
+		last = w.End
+		commandPos = strings.HasSuffix(value, " ") || strings.HasSuffix(value, "\t")
+	}
+	b.WriteString(src[last:])
+	return b.String()
+}
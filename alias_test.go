@@ -0,0 +1,107 @@
+package tish
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_expandAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]string
+		cmdStr  string
+		want    string
+	}{
+		{
+			name:    "no alias",
+			aliases: map[string]string{"ll": "ls -l"},
+			cmdStr:  "ls -a",
+			want:    "ls -a",
+		},
+		{
+			name:    "simple",
+			aliases: map[string]string{"ll": "ls -l"},
+			cmdStr:  "ll -a",
+			want:    "ls -l -a",
+		},
+		{
+			name:    "only first word",
+			aliases: map[string]string{"ll": "ls -l"},
+			cmdStr:  "echo ll",
+			want:    "echo ll",
+		},
+		{
+			name:    "quoted word is not expanded",
+			aliases: map[string]string{"ll": "ls -l"},
+			cmdStr:  `"ll"`,
+			want:    `"ll"`,
+		},
+		{
+			name:    "each command",
+			aliases: map[string]string{"ll": "ls -l"},
+			cmdStr:  "ll | ll && ll ; ll",
+			want:    "ls -l | ls -l && ls -l ; ls -l",
+		},
+		{
+			name:    "self reference",
+			aliases: map[string]string{"ls": "ls -h"},
+			cmdStr:  "ls",
+			want:    "ls -h",
+		},
+		{
+			name:    "nested",
+			aliases: map[string]string{"ll": "ls -l", "ls": "ls -h"},
+			cmdStr:  "ll",
+			want:    "ls -h -l",
+		},
+		{
+			name:    "mutual reference",
+			aliases: map[string]string{"a": "b 1", "b": "a 2"},
+			cmdStr:  "a",
+			want:    "a 2 1",
+		},
+		{
+			name:    "trailing space",
+			aliases: map[string]string{"sudo": "sudo ", "ll": "ls -l"},
+			cmdStr:  "sudo ll",
+			want:    "sudo  ls -l",
+		},
+		{
+			name:    "no trailing space",
+			aliases: map[string]string{"run": "run", "ll": "ls -l"},
+			cmdStr:  "run ll",
+			want:    "run ll",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(".", nil)
+			for name, value := range tt.aliases {
+				assert.True(t, s.SetAlias(name, value))
+			}
+			assert.Equal(t, tt.want, s.expandAlias(tt.cmdStr))
+		})
+	}
+}
+
+func TestShell_SetAlias_InvalidName(t *testing.T) {
+	s := NewShell(".", nil)
+	assert.False(t, s.SetAlias("", "ls"))
+	assert.False(t, s.SetAlias("a/b", "ls"))
+	assert.False(t, s.SetAlias("a b", "ls"))
+	assert.Empty(t, s.AliasNames())
+}
+
+func TestShell_Run_Alias(t *testing.T) {
+	s := NewShell(".", nil)
+	mock1 := registerMockCommand(t, s, "mock1")
+	s.SetAlias("m", "mock1 -x ")
+	s.SetAlias("arg", "value")
+
+	_, err := s.Run(context.Background(), "m arg", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-x", "value"}, mock1.Args)
+}
@@ -0,0 +1,94 @@
+package alias
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/shibukawa/tish"
+)
+
+func init() {
+	tish.RegisterCommand(AliasCommand())
+	tish.RegisterCommand(UnaliasCommand())
+}
+
+func AliasCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "alias",
+		Description: "Define or print aliases",
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			if len(env.Args) == 0 {
+				for _, name := range env.Shell.AliasNames() {
+					value, _ := env.Shell.Alias(name)
+					printAlias(env.Stdout, name, value)
+				}
+				result.SetInternalProcessResult(0)
+				return nil
+			}
+			exitCode := 0
+			for _, arg := range env.Args {
+				if i := strings.Index(arg, "="); i != -1 {
+					name, value := arg[:i], arg[i+1:]
+					if !env.Shell.SetAlias(name, value) {
+						fmt.Fprintf(env.Stderr, "alias: `%s': invalid alias name\n", name)
+						exitCode = 1
+					}
+				} else if value, ok := env.Shell.Alias(arg); ok {
+					printAlias(env.Stdout, arg, value)
+				} else {
+					fmt.Fprintf(env.Stderr, "alias: %s: not found\n", arg)
+					exitCode = 1
+				}
+			}
+			result.SetInternalProcessResult(exitCode)
+			return nil
+		},
+		Completer: nil,
+	}
+}
+
+func UnaliasCommand() *tish.Command {
+	options := struct {
+		All bool `short:"a" description:"remove all alias definitions"`
+	}{}
+	return &tish.Command{
+		Name:        "unalias",
+		Description: "Remove aliases",
+		Options:     options,
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			conf := options
+			args, err := flags.ParseArgs(&conf, env.Args)
+			if err != nil {
+				result.SetInternalProcessResult(1)
+				return err
+			}
+			if conf.All {
+				env.Shell.ClearAliases()
+				result.SetInternalProcessResult(0)
+				return nil
+			}
+			if len(args) == 0 {
+				io.WriteString(env.Stderr, "unalias: usage: unalias [-a] name [name ...]\n")
+				result.SetInternalProcessResult(1)
+				return tish.ErrRequireParameter
+			}
+			exitCode := 0
+			for _, name := range args {
+				if !env.Shell.DelAlias(name) {
+					fmt.Fprintf(env.Stderr, "unalias: %s: not found\n", name)
+					exitCode = 1
+				}
+			}
+			result.SetInternalProcessResult(exitCode)
+			return nil
+		},
+		Completer: nil,
+	}
+}
+
+func printAlias(w io.Writer, name, value string) {
+	fmt.Fprintf(w, "alias %s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`))
+}
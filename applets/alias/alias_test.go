@@ -0,0 +1,162 @@
+package alias
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/shibukawa/tish"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_aliasCommand(t *testing.T) {
+	type args struct {
+		aliases map[string]string
+		param   []string
+	}
+	type wants struct {
+		aliases  map[string]string
+		stdout   string
+		exitCode int
+	}
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "define",
+			args: args{
+				param: []string{"ll=ls -l", "la=ls -a"},
+			},
+			wants: wants{
+				aliases: map[string]string{"ll": "ls -l", "la": "ls -a"},
+			},
+		},
+		{
+			name: "list",
+			args: args{
+				aliases: map[string]string{"ll": "ls -l", "q": "echo 'a'"},
+			},
+			wants: wants{
+				aliases: map[string]string{"ll": "ls -l", "q": "echo 'a'"},
+				stdout:  "alias ll='ls -l'\nalias q='echo '\\''a'\\'''\n",
+			},
+		},
+		{
+			name: "print one",
+			args: args{
+				aliases: map[string]string{"ll": "ls -l", "la": "ls -a"},
+				param:   []string{"la"},
+			},
+			wants: wants{
+				aliases: map[string]string{"ll": "ls -l", "la": "ls -a"},
+				stdout:  "alias la='ls -a'\n",
+			},
+		},
+		{
+			name: "not found",
+			args: args{
+				param: []string{"la"},
+			},
+			wants: wants{
+				aliases:  map[string]string{},
+				exitCode: 1,
+			},
+		},
+		{
+			name: "invalid name",
+			args: args{
+				param: []string{"a/b=ls"},
+			},
+			wants: wants{
+				aliases:  map[string]string{},
+				exitCode: 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tish.NewShell("/dummy", nil)
+			for name, value := range tt.args.aliases {
+				s.SetAlias(name, value)
+			}
+			c := AliasCommand()
+			var stdout bytes.Buffer
+			p := tish.NewProcess(s, c.Executor, c.Name, tt.args.param, 10, 11, nil)
+			p.Stdout = &stdout
+			p.Stderr = &bytes.Buffer{}
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wants.exitCode, p.Result.ExitCode())
+			assert.Equal(t, tt.wants.stdout, stdout.String())
+			assert.Equal(t, tt.wants.aliases, aliasMap(s))
+		})
+	}
+}
+
+func Test_unaliasCommand(t *testing.T) {
+	type args struct {
+		param []string
+	}
+	type wants struct {
+		aliases  map[string]string
+		exitCode int
+	}
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "delete",
+			args: args{
+				param: []string{"ll"},
+			},
+			wants: wants{
+				aliases: map[string]string{"la": "ls -a"},
+			},
+		},
+		{
+			name: "delete all",
+			args: args{
+				param: []string{"-a"},
+			},
+			wants: wants{
+				aliases: map[string]string{},
+			},
+		},
+		{
+			name: "missing",
+			args: args{
+				param: []string{"l"},
+			},
+			wants: wants{
+				aliases:  map[string]string{"ll": "ls -l", "la": "ls -a"},
+				exitCode: 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tish.NewShell("/dummy", nil)
+			s.SetAlias("ll", "ls -l")
+			s.SetAlias("la", "ls -a")
+			c := UnaliasCommand()
+			p := tish.NewProcess(s, c.Executor, c.Name, tt.args.param, 10, 11, nil)
+			p.Stderr = &bytes.Buffer{}
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wants.exitCode, p.Result.ExitCode())
+			assert.Equal(t, tt.wants.aliases, aliasMap(s))
+		})
+	}
+}
+
+func aliasMap(s *tish.Shell) map[string]string {
+	result := map[string]string{}
+	for _, name := range s.AliasNames() {
+		result[name], _ = s.Alias(name)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/shibukawa/tish"
+	"github.com/shibukawa/tish/lineedit"
+)
+
+func init() {
+	tish.RegisterCommand(BindCommand())
+}
+
+type config struct {
+	Remove bool `short:"r" description:"remove the bindings of the keys"`
+}
+
+// BindCommand shows and sets key bindings of the line editor.
+//
+//	bind                print all bindings
+//	bind KEY ACTION     bind the key (like "ctrl-r" or "alt-f") to the action (see lineedit.Actions)
+//	bind -r KEY ...     remove the bindings
+func BindCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "bind",
+		Description: "Show or set key bindings of the line editor",
+		Options:     &config{},
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			c := &config{}
+			args, err := flags.ParseArgs(c, env.Args)
+			if err != nil {
+				result.SetInternalProcessResult(2)
+				return nil
+			}
+			switch {
+			case c.Remove:
+				exitCode := 0
+				for _, key := range args {
+					if !env.Shell.UnbindKey(key) {
+						fmt.Fprintf(env.Stderr, "bind: %s: not bound\n", key)
+						exitCode = 1
+					}
+				}
+				result.SetInternalProcessResult(exitCode)
+			case len(args) == 0:
+				bindings := env.Shell.KeyBindings()
+				keys := make([]string, 0, len(bindings))
+				for key := range bindings {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					fmt.Fprintf(env.Stdout, "%s: %s\n", key, bindings[key])
+				}
+				result.SetInternalProcessResult(0)
+			case len(args) == 2:
+				if !isAction(args[1]) {
+					fmt.Fprintf(env.Stderr, "bind: %s: unknown action\n", args[1])
+					result.SetInternalProcessResult(1)
+					return nil
+				}
+				if !env.Shell.BindKey(args[0], args[1]) {
+					fmt.Fprintf(env.Stderr, "bind: %s: invalid key\n", args[0])
+					result.SetInternalProcessResult(1)
+					return nil
+				}
+				result.SetInternalProcessResult(0)
+			default:
+				io.WriteString(env.Stderr, "bind: usage: bind [KEY ACTION] | bind -r KEY ...\n")
+				result.SetInternalProcessResult(2)
+			}
+			return nil
+		},
+		Completer: nil,
+	}
+}
+
+func isAction(name string) bool {
+	for _, action := range lineedit.Actions() {
+		if action == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package bind
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/shibukawa/tish"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bindCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    []string
+		stdout   string
+		stderr   string
+		exitCode int
+		bindings map[string]string
+	}{
+		{
+			name:     "list",
+			stdout:   "alt-f: forward-word\nctrl-r: reverse-search-history\n",
+			bindings: map[string]string{"ctrl-r": "reverse-search-history", "alt-f": "forward-word"},
+		},
+		{
+			name:     "bind",
+			param:    []string{`\C-a`, "beginning-of-line"},
+			bindings: map[string]string{"ctrl-r": "reverse-search-history", "alt-f": "forward-word", "ctrl-a": "beginning-of-line"},
+		},
+		{
+			name:     "invalid key",
+			param:    []string{"a", "beginning-of-line"},
+			stderr:   "bind: a: invalid key\n",
+			exitCode: 1,
+			bindings: map[string]string{"ctrl-r": "reverse-search-history", "alt-f": "forward-word"},
+		},
+		{
+			name:     "unknown action",
+			param:    []string{"C-a", "no-such-action"},
+			stderr:   "bind: no-such-action: unknown action\n",
+			exitCode: 1,
+			bindings: map[string]string{"ctrl-r": "reverse-search-history", "alt-f": "forward-word"},
+		},
+		{
+			name:     "remove",
+			param:    []string{"-r", "C-r", "C-x"},
+			stderr:   "bind: C-x: not bound\n",
+			exitCode: 1,
+			bindings: map[string]string{"alt-f": "forward-word"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tish.NewShell("/home/myname", []string{})
+			s.BindKey("ctrl-r", "reverse-search-history")
+			s.BindKey("alt-f", "forward-word")
+
+			e := BindCommand()
+			stdout := bytes.Buffer{}
+			stderr := bytes.Buffer{}
+			p := tish.NewProcess(s, e.Executor, e.Name, tt.param, 10, 11, nil)
+			p.Stdout = &stdout
+			p.Stderr = &stderr
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.stdout, stdout.String())
+			assert.Equal(t, tt.stderr, stderr.String())
+			assert.Equal(t, tt.exitCode, p.Result.ExitCode())
+			assert.Equal(t, tt.bindings, s.KeyBindings())
+		})
+	}
+}
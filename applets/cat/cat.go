@@ -2,8 +2,6 @@ package cat
 
 import (
 	"context"
-	"io"
-	"os"
 
 	"github.com/shibukawa/tish"
 )
@@ -14,15 +12,28 @@ func init() {
 
 func CatCommand() *tish.Command {
 	return &tish.Command{
-		Name: "cat",
+		Name:        "cat",
+		Description: "Concatenate files to the standard output",
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			for _, path := range p.Args {
-				f, err := os.Open(p.Shell.ExpandPath(path))
+				if err := ctx.Err(); err != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
+				path, err := p.Shell.ResolvePath(path)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
+				f, err := p.Shell.FileSystem().Open(path)
 				if err != nil {
 					return err
 				}
 				defer f.Close()
-				io.Copy(p.Stdout, f)
+				if _, err := tish.CopyContext(ctx, p.Stdout, f); err != nil && ctx.Err() != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
 			}
 			res.SetInternalProcessResult(0)
 			return nil
@@ -3,6 +3,7 @@ package cat
 import (
 	"bytes"
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/shibukawa/tish"
@@ -43,5 +44,38 @@ func TestCatCommand(t *testing.T) {
 			assert.Equal(t, 0, p.Result.ExitCode())
 			assert.Equal(t, tt.wants, buf.String())
 		})
+		t.Run(tt.name+" (memory file system)", func(t *testing.T) {
+			root, fsys := tish.CreateTestFileSystem(t, "cat", map[string]string{
+				"hello.txt": "hello\n",
+				"world.txt": "world\n",
+			})
+			s := tish.NewShell(root, []string{})
+			s.SetFileSystem(fsys)
+			c := CatCommand()
+			buf := bytes.Buffer{}
+			p := tish.NewProcess(s, c.Executor, c.Name, tt.args, 10, 11, nil)
+			p.Stdout = &buf
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, 0, p.Result.ExitCode())
+			assert.Equal(t, tt.wants, buf.String())
+		})
 	}
-}
\ No newline at end of file
+}
+func TestCatCommand_SafeMode(t *testing.T) {
+	root := tish.CreateTestFolders(t, "cat-safe-mode", map[string]string{
+		"work/hello.txt": "hello\n",
+		"secret.txt":     "secret\n",
+	})
+
+	s := tish.NewShell(filepath.Join(root, "work"), []string{}, tish.Option{SafeMode: true})
+	c := CatCommand()
+	buf := bytes.Buffer{}
+	p := tish.NewProcess(s, c.Executor, c.Name, []string{"../secret.txt"}, 10, 11, nil)
+	p.Stdout = &buf
+	err := p.StartAndWait(context.Background())
+	var notAllowed *tish.ErrPathNotAllowed
+	assert.ErrorAs(t, err, &notAllowed)
+	assert.Equal(t, 1, p.Result.ExitCode())
+	assert.Empty(t, buf.String())
+}
@@ -12,7 +12,8 @@ func init() {
 
 func CdCommand() *tish.Command {
 	return &tish.Command{
-		Name: "cd",
+		Name:        "cd",
+		Description: "Change the working directory",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
 			var dirName string
 			if len(env.Args) > 0 {
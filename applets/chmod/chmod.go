@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 
@@ -30,7 +29,9 @@ func calcMode(info os.FileInfo, plus, mask uint32) os.FileMode {
 
 func chmodCommand() *tish.Command {
 	return &tish.Command{
-		Name: "chmod",
+		Name:        "chmod",
+		Description: "Change file modes",
+		Options:     &config{},
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			c := &config{}
 			args, err := flags.ParseArgs(c, p.Args)
@@ -50,13 +51,28 @@ func chmodCommand() *tish.Command {
 			}
 
 			for _, file := range args[1:] {
-				path := p.Shell.ExpandPath(file)
+				if err := ctx.Err(); err != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
+				path, err := p.Shell.ResolvePath(file)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
 				if c.Recursive {
-					err := filepath.Walk(path, func(entryPath string, info os.FileInfo, err error) error {
+					err := tish.Walk(p.Shell.FileSystem(), path, func(entryPath string, info os.FileInfo, err error) error {
 						if err != nil {
 							return err
 						}
-						if err := os.Chmod(entryPath, calcMode(info, plus, mask)); err != nil {
+						if err := ctx.Err(); err != nil {
+							return err
+						}
+						// chmod follows symbolic link, so check each entry
+						if _, err := p.Shell.ResolvePath(entryPath); err != nil {
+							return err
+						}
+						if err := p.Shell.FileSystem().Chmod(entryPath, calcMode(info, plus, mask)); err != nil {
 							return err
 						}
 						return nil
@@ -66,11 +82,11 @@ func chmodCommand() *tish.Command {
 						return err
 					}
 				} else {
-					fi, err := os.Lstat(path)
+					fi, err := p.Shell.FileSystem().Lstat(path)
 					if err != nil {
 						return err
 					}
-					os.Chmod(path, calcMode(fi, plus, mask))
+					p.Shell.FileSystem().Chmod(path, calcMode(fi, plus, mask))
 				}
 			}
 			res.SetInternalProcessResult(0)
@@ -4,13 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/shibukawa/tish"
-	"github.com/shibukawa/tish/osutils"
 	"github.com/jessevdk/go-flags"
 )
 
@@ -28,7 +25,9 @@ func init() {
 
 func CpCommand() *tish.Command {
 	return &tish.Command{
-		Name: "cp",
+		Name:        "cp",
+		Description: "Copy files",
+		Options:     &config{},
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			c := &config{}
 
@@ -37,8 +36,11 @@ func CpCommand() *tish.Command {
 				res.SetInternalProcessResult(1)
 				return err
 			}
-			err = cp(args, p.Shell, c)
-			if err != nil {
+			err = cp(ctx, args, p.Shell, c)
+			if err != nil && ctx.Err() != nil {
+				res.SetInternalProcessResult(130)
+				return err
+			} else if err != nil {
 				res.SetInternalProcessResult(1)
 				return err
 			}
@@ -49,10 +51,14 @@ func CpCommand() *tish.Command {
 	}
 }
 
-func cp(files []string, s *tish.Shell, c *config) error {
-	dest := s.ExpandPath(files[len(files)-1])
+func cp(ctx context.Context, files []string, s *tish.Shell, c *config) error {
+	dest, err := s.ResolvePath(files[len(files)-1])
+	if err != nil {
+		return err
+	}
 
-	destIsDir := osutils.IsDir(dest)
+	fsys := s.FileSystem()
+	destIsDir := tish.IsDir(fsys, dest)
 
 	for _, src := range files[:len(files)-1] {
 		var dstPath string
@@ -61,19 +67,29 @@ func cp(files []string, s *tish.Shell, c *config) error {
 		} else {
 			dstPath = dest
 		}
-		if !c.Force && osutils.FileExists(dstPath) {
+		if !c.Force && tish.FileExists(fsys, dstPath) {
 			return os.ErrExist
 		}
-		srcPath := s.ExpandPath(src)
-		if err := cpOne(srcPath, dstPath, s, c); err != nil {
+		srcPath, err := s.ResolvePath(src)
+		if err != nil {
+			return err
+		}
+		if err := cpOne(ctx, srcPath, dstPath, s, c); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func cpOne(srcPath, dstPath string, s *tish.Shell, c *config) error {
-	si, err := os.Stat(srcPath)
+func cpOne(ctx context.Context, srcPath, dstPath string, s *tish.Shell, c *config) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// entries in directory may be symbolic links to outside of allowed paths
+	if _, err := s.ResolvePath(srcPath); err != nil {
+		return err
+	}
+	si, err := s.FileSystem().Stat(srcPath)
 	if err != nil {
 		return err
 	}
@@ -81,11 +97,11 @@ func cpOne(srcPath, dstPath string, s *tish.Shell, c *config) error {
 		if !c.Recursive {
 			return errors.New("source is a directory")
 		}
-		if err := cpDirectory(srcPath, dstPath, s, c); err != nil {
+		if err := cpDirectory(ctx, srcPath, dstPath, s, c); err != nil {
 			return err
 		}
 	} else {
-		if err := cpFile(srcPath, dstPath, s, c); err != nil {
+		if err := cpFile(ctx, srcPath, dstPath, s, c); err != nil {
 			return err
 		}
 	}
@@ -93,29 +109,30 @@ func cpOne(srcPath, dstPath string, s *tish.Shell, c *config) error {
 	return nil
 }
 
-func cpDirectory(srcPath, dstPath string, s *tish.Shell, c *config) error {
-	si, err := os.Stat(srcPath)
+func cpDirectory(ctx context.Context, srcPath, dstPath string, s *tish.Shell, c *config) error {
+	fsys := s.FileSystem()
+	si, err := fsys.Stat(srcPath)
 	if err != nil {
 		return err
 	}
 
 	// ensure dst dir does not already exist
-	if _, err := os.Open(dstPath); !os.IsNotExist(err) {
+	if _, err := fsys.Lstat(dstPath); !os.IsNotExist(err) {
 		return errors.New("destination already exists")
 	}
 
 	// create dst dir
-	if err := os.MkdirAll(dstPath, si.Mode()); err != nil {
+	if err := fsys.MkdirAll(dstPath, si.Mode()); err != nil {
 		return err
 	}
 
-	files, err := ioutil.ReadDir(srcPath)
+	files, err := fsys.ReadDir(srcPath)
 	if err != nil {
 		return err
 	}
 
 	for _, file := range files {
-		if err := cpOne(filepath.Join(srcPath, file.Name()),
+		if err := cpOne(ctx, filepath.Join(srcPath, file.Name()),
 			filepath.Join(dstPath, file.Name()), s, c); err != nil {
 			return err
 		}
@@ -123,8 +140,9 @@ func cpDirectory(srcPath, dstPath string, s *tish.Shell, c *config) error {
 	return nil
 }
 
-func cpFile(srcPath, dstPath string, s *tish.Shell, c *config) error {
-	si, err := os.Lstat(srcPath)
+func cpFile(ctx context.Context, srcPath, dstPath string, s *tish.Shell, c *config) (err error) {
+	fsys := s.FileSystem()
+	si, err := fsys.Lstat(srcPath)
 	if err != nil {
 		return err
 	}
@@ -133,7 +151,7 @@ func cpFile(srcPath, dstPath string, s *tish.Shell, c *config) error {
 		return cpSymlink(srcPath, dstPath, s)
 	}
 
-	di, err := os.Lstat(dstPath)
+	di, err := fsys.Lstat(dstPath)
 	if !os.IsNotExist(err) {
 		if !c.Force {
 			return fmt.Errorf("destination already exists: %s", dstPath)
@@ -144,14 +162,14 @@ func cpFile(srcPath, dstPath string, s *tish.Shell, c *config) error {
 	}
 
 	//open source
-	in, err := os.Open(srcPath)
+	in, err := fsys.Open(srcPath)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
 
 	//create dst
-	out, err := os.Create(dstPath)
+	out, err := fsys.OpenFile(dstPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
 	if err != nil {
 		return err
 	}
@@ -163,14 +181,14 @@ func cpFile(srcPath, dstPath string, s *tish.Shell, c *config) error {
 	}()
 
 	// copy
-	if _, err = io.Copy(out, in); err != nil {
+	if _, err = tish.CopyContext(ctx, out, in); err != nil {
 		return err
 	}
-	if err = out.Chmod(si.Mode()); err != nil {
+	if err = fsys.Chmod(dstPath, si.Mode()); err != nil {
 		return err
 	}
 	if c.Preserve {
-		if err = os.Chtimes(dstPath, si.ModTime(), si.ModTime()); err != nil {
+		if err = fsys.Chtimes(dstPath, si.ModTime(), si.ModTime()); err != nil {
 			return err
 		}
 	}
@@ -180,9 +198,9 @@ func cpFile(srcPath, dstPath string, s *tish.Shell, c *config) error {
 }
 
 func cpSymlink(src, dst string, s *tish.Shell) error {
-	linkTarget, err := os.Readlink(src)
+	linkTarget, err := s.FileSystem().Readlink(src)
 	if err != nil {
 		return err
 	}
-	return os.Symlink(linkTarget, dst)
+	return s.FileSystem().Symlink(linkTarget, dst)
 }
@@ -15,13 +15,18 @@ func init() {
 }
 
 func EchoCommand() *tish.Command {
+	options := struct {
+		NoTrailingNewLine bool `short:"n" description:"do not output the trailing newline"`
+	}{}
 	return &tish.Command{
-		Name: "echo",
+		Name:        "echo",
+		Description: "Write arguments to the standard output",
+		Options:     options,
+		NoHelp:      true,
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
-			conf := &struct {
-				NoTrailingNewLine bool `short:"n"`
-			}{}
-			args, err := flags.ParseArgs(conf, env.Args)
+			conf := options
+			// unknown flags like "--help" and arguments after the first operand are written as they are
+			args, err := flags.NewParser(&conf, flags.IgnoreUnknown|flags.PassAfterNonOption).ParseArgs(env.Args)
 			if err != nil {
 				log.Println(err)
 				result.SetInternalProcessResult(1)
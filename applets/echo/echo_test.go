@@ -41,6 +41,24 @@ func Test_echoCommand(t *testing.T) {
 				stdout: "hello world\n",
 			},
 		},
+		{
+			name: "help is not an option",
+			args: args{
+				args: []string{"--help", "-x", "a"},
+			},
+			wants: wants{
+				stdout: "--help -x a\n",
+			},
+		},
+		{
+			name: "options after operand",
+			args: args{
+				args: []string{"a", "-n"},
+			},
+			wants: wants{
+				stdout: "a -n\n",
+			},
+		},
 		{
 			name: "single arg, no trailing new line options",
 			args: args{
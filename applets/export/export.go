@@ -18,14 +18,17 @@ func init() {
 var envVarPattern = regexp.MustCompile(`([a-zA-Z_]+[a-zA-Z0-9_]*)=(.*)`)
 
 func ExportCommand() *tish.Command {
+	options := struct {
+		Delete bool `short:"n" description:"remove the variables"`
+		Print  bool `short:"p" description:"print all variables"`
+	}{}
 	return &tish.Command{
-		Name: "export",
+		Name:        "export",
+		Description: "Set environment variables",
+		Options:     options,
 		Executor: func(ctx context.Context, res *tish.ExecResult, env *tish.Process) (err error) {
-			conf := &struct {
-				Delete bool `short:"n"`
-				Print  bool `short:"p"`
-			}{}
-			args, err := flags.ParseArgs(conf, env.Args)
+			conf := options
+			args, err := flags.ParseArgs(&conf, env.Args)
 			if err != nil {
 				res.SetInternalProcessResult(1)
 				log.Println(err)
@@ -101,7 +101,7 @@ declare -x C="D"
 			p.Stdout = &buf
 			err := p.StartAndWait(context.Background())
 			assert.NoError(t, err)
-			assert.Equal(t, tt.wants.envs, s.Env)
+			assert.Equal(t, tt.wants.envs, s.Environ())
 			assert.Equal(t, tt.wants.stdout, buf.String())
 		})
 	}
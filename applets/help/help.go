@@ -0,0 +1,53 @@
+package help
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/shibukawa/tish"
+)
+
+func init() {
+	tish.RegisterCommand(HelpCommand())
+}
+
+// HelpCommand lists commands of the shell with their descriptions, or writes the help of the given commands.
+func HelpCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "help",
+		Description: "List commands or show the help of the commands",
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			commands := env.Shell.Commands()
+			if len(env.Args) == 0 {
+				w := tabwriter.NewWriter(env.Stdout, 0, 8, 2, ' ', 0)
+				for _, cmd := range commands {
+					fmt.Fprintf(w, "%s\t%s\n", cmd.Name, cmd.Description)
+				}
+				w.Flush()
+				result.SetInternalProcessResult(0)
+				return nil
+			}
+			byName := make(map[string]*tish.Command, len(commands))
+			for _, cmd := range commands {
+				byName[cmd.Name] = cmd
+			}
+			exitCode := 0
+			for i, name := range env.Args {
+				cmd, ok := byName[name]
+				if !ok {
+					fmt.Fprintf(env.Stderr, "help: %s: command not found\n", name)
+					exitCode = 1
+					continue
+				}
+				if i != 0 {
+					fmt.Fprintln(env.Stdout)
+				}
+				cmd.WriteHelp(env.Stdout)
+			}
+			result.SetInternalProcessResult(exitCode)
+			return nil
+		},
+		Completer: nil,
+	}
+}
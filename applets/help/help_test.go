@@ -0,0 +1,78 @@
+package help
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/shibukawa/tish"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_helpCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    []string
+		stdout   string
+		stderr   string
+		exitCode int
+	}{
+		{
+			name:  "list",
+			param: []string{},
+			stdout: "greet  Say hello\n" +
+				"help   List commands or show the help of the commands\n" +
+				"noop   \n",
+		},
+		{
+			name:  "command",
+			param: []string{"greet"},
+			stdout: "Usage:\n  greet [OPTIONS]\n\nSay hello\n\n" +
+				"Options:\n  -u, --upper  greet loudly\n\n" +
+				"Help Options:\n  -h, --help   Show this help message\n",
+		},
+		{
+			name:   "command without options",
+			param:  []string{"noop"},
+			stdout: "Usage:\n  noop\n",
+		},
+		{
+			name:     "not found",
+			param:    []string{"unknown"},
+			stderr:   "help: unknown: command not found\n",
+			exitCode: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tish.NewShell("/home/myname", []string{})
+			s.RegisterCommand(&tish.Command{
+				Name:        "greet",
+				Description: "Say hello",
+				Options: &struct {
+					Upper bool `short:"u" long:"upper" description:"greet loudly"`
+				}{},
+				Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) error {
+					return result.SetInternalProcessResult(0)
+				},
+			})
+			s.RegisterCommand(&tish.Command{
+				Name: "noop",
+				Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) error {
+					return result.SetInternalProcessResult(0)
+				},
+			})
+			e := HelpCommand()
+			stdout := bytes.Buffer{}
+			stderr := bytes.Buffer{}
+			p := tish.NewProcess(s, e.Executor, e.Name, tt.param, 10, 11, nil)
+			p.Stdout = &stdout
+			p.Stderr = &stderr
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.stdout, stdout.String())
+			assert.Equal(t, tt.stderr, stderr.String())
+			assert.Equal(t, tt.exitCode, p.Result.ExitCode())
+		})
+	}
+}
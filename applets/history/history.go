@@ -0,0 +1,107 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/shibukawa/tish"
+)
+
+func init() {
+	tish.RegisterCommand(HistoryCommand())
+}
+
+type config struct {
+	Clear   bool   `short:"c" description:"clear the history"`
+	Delete  *int   `short:"d" value-name:"OFFSET" description:"delete the history entry at OFFSET (negative OFFSET counts from the end)"`
+	Dir     string `long:"dir" value-name:"DIR" description:"show only commands run in DIR"`
+	Verbose bool   `short:"v" long:"verbose" description:"show time, exit code and directory of commands"`
+}
+
+// HistoryCommand shows and edits the command history.
+//
+//	history [-v] [--dir DIR] [N]  show the last N entries
+//	history -d OFFSET             delete the entry (-1 is the last entry)
+//	history -c                    clear the history
+func HistoryCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "history",
+		Description: "Show or edit the command history",
+		Options:     &config{},
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			c := &config{}
+			args, err := flags.ParseArgs(c, env.Args)
+			if err != nil {
+				result.SetInternalProcessResult(2)
+				return nil
+			}
+			h := env.Shell.History()
+			switch {
+			case c.Clear:
+				if err := h.Clear(); err != nil {
+					fmt.Fprintf(env.Stderr, "history: %v\n", err)
+					result.SetInternalProcessResult(1)
+					return nil
+				}
+			case c.Delete != nil:
+				if err := deleteEntry(h, *c.Delete); err != nil {
+					fmt.Fprintf(env.Stderr, "history: %v\n", err)
+					result.SetInternalProcessResult(1)
+					return nil
+				}
+			default:
+				entries := h.Entries()
+				if c.Dir != "" {
+					dir := c.Dir
+					if !filepath.IsAbs(dir) {
+						dir = filepath.Join(env.Shell.WorkingDir(), dir)
+					}
+					dir = filepath.Clean(dir)
+					var filtered []tish.HistoryEntry
+					for _, e := range entries {
+						if e.WorkingDir == dir {
+							filtered = append(filtered, e)
+						}
+					}
+					entries = filtered
+				}
+				if len(args) > 0 {
+					n, err := strconv.Atoi(args[0])
+					if err != nil || n < 0 {
+						fmt.Fprintf(env.Stderr, "history: %s: numeric argument required\n", args[0])
+						result.SetInternalProcessResult(1)
+						return nil
+					}
+					if n < len(entries) {
+						entries = entries[len(entries)-n:]
+					}
+				}
+				for _, e := range entries {
+					if c.Verbose {
+						fmt.Fprintf(env.Stdout, "%5d  %s  %3d  %s  %s\n", e.Number, e.Time.Format("2006-01-02 15:04:05"), e.ExitCode, e.WorkingDir, e.Command)
+					} else {
+						fmt.Fprintf(env.Stdout, "%5d  %s\n", e.Number, e.Command)
+					}
+				}
+			}
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+		Completer: nil,
+	}
+}
+
+// deleteEntry deletes the entry of the history number. Negative offset counts from the end like bash.
+func deleteEntry(h *tish.History, offset int) error {
+	if offset >= 0 {
+		return h.Delete(offset)
+	}
+	entries := h.Entries()
+	if len(entries) < -offset {
+		return fmt.Errorf("%d: history position out of range", offset)
+	}
+	return h.Delete(entries[len(entries)+offset].Number)
+}
@@ -0,0 +1,128 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shibukawa/tish"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_historyCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    []string
+		stdout   string
+		stderr   string
+		exitCode int
+		remains  int
+		// commands are remaining commands if it is not nil
+		commands []string
+	}{
+		{
+			name:    "list",
+			param:   []string{},
+			stdout:  "    1  ls\n    2  cd /tmp\n    3  cat a.txt\n",
+			remains: 3,
+		},
+		{
+			name:    "last n",
+			param:   []string{"2"},
+			stdout:  "    2  cd /tmp\n    3  cat a.txt\n",
+			remains: 3,
+		},
+		{
+			name:    "filter by directory",
+			param:   []string{"--dir", "."},
+			stdout:  "    1  ls\n    2  cd /tmp\n",
+			remains: 3,
+		},
+		{
+			name:    "verbose",
+			param:   []string{"-v", "1"},
+			stdout:  "    3  2021-01-02 03:04:05    1  /tmp  cat a.txt\n",
+			remains: 3,
+		},
+		{
+			name:    "delete",
+			param:   []string{"-d", "2"},
+			remains: 2,
+		},
+		{
+			name:     "delete negative offset",
+			param:    []string{"-d", "-1"},
+			remains:  2,
+			commands: []string{"ls", "cd /tmp"},
+		},
+		{
+			name:     "delete first by negative offset",
+			param:    []string{"-d", "-3"},
+			remains:  2,
+			commands: []string{"cd /tmp", "cat a.txt"},
+		},
+		{
+			name:     "delete zero",
+			param:    []string{"-d", "0"},
+			stderr:   "history: 0: history position out of range\n",
+			exitCode: 1,
+			remains:  3,
+		},
+		{
+			name:     "delete negative offset out of range",
+			param:    []string{"-d", "-4"},
+			stderr:   "history: -4: history position out of range\n",
+			exitCode: 1,
+			remains:  3,
+		},
+		{
+			name:     "delete out of range",
+			param:    []string{"-d", "5"},
+			stderr:   "history: 5: history position out of range\n",
+			exitCode: 1,
+			remains:  3,
+		},
+		{
+			name:    "clear",
+			param:   []string{"-c"},
+			remains: 0,
+		},
+		{
+			name:     "invalid number",
+			param:    []string{"x"},
+			stderr:   "history: x: numeric argument required\n",
+			exitCode: 1,
+			remains:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tish.NewShell("/home/myname", []string{})
+			at := time.Date(2021, 1, 2, 3, 4, 5, 0, time.Local)
+			s.History().Add(tish.HistoryEntry{Command: "ls", Time: at, WorkingDir: "/home/myname"})
+			s.History().Add(tish.HistoryEntry{Command: "cd /tmp", Time: at, WorkingDir: "/home/myname"})
+			s.History().Add(tish.HistoryEntry{Command: "cat a.txt", Time: at, ExitCode: 1, WorkingDir: "/tmp"})
+
+			e := HistoryCommand()
+			stdout := bytes.Buffer{}
+			stderr := bytes.Buffer{}
+			p := tish.NewProcess(s, e.Executor, e.Name, tt.param, 10, 11, nil)
+			p.Stdout = &stdout
+			p.Stderr = &stderr
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.stdout, stdout.String())
+			assert.Equal(t, tt.stderr, stderr.String())
+			assert.Equal(t, tt.exitCode, p.Result.ExitCode())
+			assert.Len(t, s.History().Entries(), tt.remains)
+			if tt.commands != nil {
+				var commands []string
+				for _, e := range s.History().Entries() {
+					commands = append(commands, e.Command)
+				}
+				assert.Equal(t, tt.commands, commands)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shibukawa/tish"
+)
+
+func init() {
+	tish.RegisterCommand(HookCommand())
+}
+
+// HookCommand shows and sets command hooks of the shell (see tish.Shell.AddHookCommand).
+//
+//	hook                      print all hook commands
+//	hook add HOOK COMMAND...  run the command at the hook (preexec, precmd or chpwd)
+//	hook rm HOOK COMMAND...   remove the command from the hook
+func HookCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "hook",
+		Description: "Show or set commands run at preexec, precmd and chpwd",
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			if len(env.Args) == 0 {
+				for _, hook := range tish.Hooks {
+					for _, cmd := range env.Shell.HookCommands(hook) {
+						fmt.Fprintf(env.Stdout, "%s: %s\n", hook, cmd)
+					}
+				}
+				result.SetInternalProcessResult(0)
+				return nil
+			}
+			if len(env.Args) < 3 || (env.Args[0] != "add" && env.Args[0] != "rm") {
+				io.WriteString(env.Stderr, "hook: usage: hook [add|rm HOOK COMMAND...]\n")
+				result.SetInternalProcessResult(2)
+				return nil
+			}
+			hook, ok := parseHook(env.Args[1])
+			if !ok {
+				fmt.Fprintf(env.Stderr, "hook: %s: unknown hook\n", env.Args[1])
+				result.SetInternalProcessResult(1)
+				return nil
+			}
+			cmd := strings.Join(env.Args[2:], " ")
+			if env.Args[0] == "add" {
+				env.Shell.AddHookCommand(hook, cmd)
+			} else if !env.Shell.RemoveHookCommand(hook, cmd) {
+				fmt.Fprintf(env.Stderr, "hook: %s: not registered to %s\n", cmd, hook)
+				result.SetInternalProcessResult(1)
+				return nil
+			}
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+		Completer: nil,
+	}
+}
+
+func parseHook(name string) (tish.Hook, bool) {
+	for _, hook := range tish.Hooks {
+		if string(hook) == name {
+			return hook, true
+		}
+	}
+	return "", false
+}
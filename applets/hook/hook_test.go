@@ -0,0 +1,52 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/shibukawa/tish"
+	"github.com/shibukawa/tish/applets/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_hookCommand(t *testing.T) {
+	s := tish.NewShell("/home/myname", []string{})
+	s.RegisterCommand(HookCommand())
+	s.RegisterCommand(echo.EchoCommand())
+
+	run := func(cmd string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code, _ := s.Run(context.Background(), cmd, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, _, _ := run("hook add preexec echo before")
+	assert.Equal(t, 0, code)
+	code, _, _ = run("hook add precmd echo after")
+	assert.Equal(t, 0, code)
+
+	code, stdout, _ := run("echo main")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "before echo main\nmain\nafter\n", stdout)
+
+	_, stdout, _ = run("hook")
+	assert.Equal(t, "before hook\npreexec: echo before\nprecmd: echo after\nafter\n", stdout)
+
+	code, _, _ = run("hook rm preexec echo before")
+	assert.Equal(t, 0, code)
+	_, stdout, _ = run("echo main")
+	assert.Equal(t, "main\nafter\n", stdout)
+
+	code, _, stderr := run("hook rm preexec echo before")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "hook: echo before: not registered to preexec\n", stderr)
+
+	code, _, stderr = run("hook add postexec echo x")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "hook: postexec: unknown hook\n", stderr)
+
+	code, _, stderr = run("hook add preexec")
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "hook: usage: hook [add|rm HOOK COMMAND...]\n", stderr)
+}
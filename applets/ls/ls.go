@@ -3,7 +3,6 @@ package ls
 import (
 	"context"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -58,7 +57,9 @@ type config struct {
 
 func lsCommand() *tish.Command {
 	return &tish.Command{
-		Name: "ls",
+		Name:        "ls",
+		Description: "List directory contents",
+		Options:     &config{},
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			c := &config{}
 			args, err := flags.ParseArgs(c, p.Args)
@@ -97,9 +98,14 @@ func gather(paths []string, s *tish.Shell, opt *config) ([]Directory, error) {
 	if len(paths) == 0 {
 		paths = []string{"."}
 	}
+	fsys := s.FileSystem()
 	ret := make([]Directory, 0)
 	for _, path := range paths {
-		cur, err := os.Lstat(s.ExpandPath(filepath.Join(path, ".")))
+		curPath, err := s.ResolvePath(filepath.Join(path, "."))
+		if err != nil {
+			return nil, err
+		}
+		cur, err := fsys.Lstat(curPath)
 		if err != nil {
 			return nil, err
 		}
@@ -120,8 +126,9 @@ func gather(paths []string, s *tish.Shell, opt *config) ([]Directory, error) {
 				direct: direct,
 			})
 		}
-		if path != "/" {
-			par, err := os.Lstat(s.ExpandPath(filepath.Join(path, "..")))
+		// parent directory is not shown if it is out of allowed paths
+		if parPath, err := s.ResolvePath(filepath.Join(path, "..")); path != "/" && err == nil {
+			par, err := fsys.Lstat(parPath)
 			if err != nil {
 				return nil, err
 			}
@@ -145,8 +152,8 @@ func gather(paths []string, s *tish.Shell, opt *config) ([]Directory, error) {
 		isDir := cur.IsDir()
 		absPath := s.ExpandPath(path)
 		if cur.Mode()&os.ModeSymlink != 0 {
-			if link, err := os.Readlink(absPath); err == nil {
-				if lfi, err :=  os.Lstat(link); err == nil && lfi.IsDir() {
+			if link, err := fsys.Readlink(absPath); err == nil {
+				if lfi, err := fsys.Lstat(link); err == nil && lfi.IsDir() {
 					isDir = true
 					absPath = link
 				}
@@ -154,13 +161,17 @@ func gather(paths []string, s *tish.Shell, opt *config) ([]Directory, error) {
 			log.Println(isDir, absPath)
 		}
 		if isDir {
-			ff, err := ioutil.ReadDir(absPath)
+			ff, err := fsys.ReadDir(absPath)
 			if err != nil {
 				return nil, err
 			}
 			for _, f := range ff {
+				fi, err := f.Info()
+				if err != nil {
+					return nil, err
+				}
 				files = append(files, fileEntry{
-					fi:     f,
+					fi:     fi,
 					orig:   f.Name(),
 					direct: false,
 				})
@@ -183,7 +194,7 @@ func gather(paths []string, s *tish.Shell, opt *config) ([]Directory, error) {
 			} else if mode&os.ModeSymlink != 0 {
 				type_ = TypeSymLink
 				if opt.FollowLink {
-					fi, err = followLink(s.ExpandPath(path), fi)
+					fi, err = followLink(fsys, s.ExpandPath(path), fi)
 					if err != nil {
 						return nil, err
 					}
@@ -228,10 +239,10 @@ func skip(fn string, direct bool, opt *config) bool {
 	return false
 }
 
-func followLink(dirPath string, fi os.FileInfo) (os.FileInfo, error) {
-	path, err := os.Readlink(filepath.Join(dirPath, fi.Name()))
+func followLink(fsys tish.FileSystem, dirPath string, fi os.FileInfo) (os.FileInfo, error) {
+	path, err := fsys.Readlink(filepath.Join(dirPath, fi.Name()))
 	if err != nil {
 		return nil, err
 	}
-	return os.Lstat(path)
+	return fsys.Lstat(path)
 }
@@ -18,7 +18,9 @@ type config struct {
 
 func mkdirCommand() *tish.Command {
 	return &tish.Command{
-		Name: "mkdir",
+		Name:        "mkdir",
+		Description: "Make directories",
+		Options:     &config{},
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			c := &config{}
 			dirs, err := flags.ParseArgs(c, p.Args)
@@ -28,15 +30,23 @@ func mkdirCommand() *tish.Command {
 			}
 
 			for _, dir := range dirs {
-				dir = p.Shell.ExpandPath(dir)
+				if err := ctx.Err(); err != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
+				dir, err := p.Shell.ResolvePath(dir)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
 				if c.P {
-					err := os.MkdirAll(dir, 0755)
-					if !os.IsExist(err) {
+					err := p.Shell.FileSystem().MkdirAll(dir, 0755)
+					if err != nil && !os.IsExist(err) {
 						res.SetInternalProcessResult(1)
 						return err
 					}
 				} else {
-					if err := os.Mkdir(dir, 0755); err != nil {
+					if err := p.Shell.FileSystem().Mkdir(dir, 0755); err != nil {
 						res.SetInternalProcessResult(1)
 						return err
 					}
@@ -4,11 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/shibukawa/tish"
-	"github.com/shibukawa/tish/osutils"
 )
 
 func init() {
@@ -17,14 +15,19 @@ func init() {
 
 func MvCommand() *tish.Command {
 	return &tish.Command{
-		Name: "mv",
+		Name:        "mv",
+		Description: "Move or rename files",
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			if len(p.Args) < 2 {
 				res.SetInternalProcessResult(1)
 				return errors.New("usage: mv source ... directory")
 			}
-			dest := p.Shell.ExpandPath(p.Args[len(p.Args)-1])
-			destIsDir := osutils.IsDir(dest)
+			dest, err := p.Shell.ResolvePath(p.Args[len(p.Args)-1])
+			if err != nil {
+				res.SetInternalProcessResult(1)
+				return err
+			}
+			destIsDir := tish.IsDir(p.Shell.FileSystem(), dest)
 			if len(p.Args) == 2 {
 				src := p.Args[0]
 				var destPath string
@@ -33,7 +36,12 @@ func MvCommand() *tish.Command {
 				} else {
 					destPath = dest
 				}
-				err := os.Rename(p.Shell.ExpandPath(src), destPath)
+				srcPath, err := p.Shell.ResolvePath(src)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
+				err = p.Shell.FileSystem().Rename(srcPath, destPath)
 				if err != nil {
 					res.SetInternalProcessResult(1)
 					return err
@@ -44,8 +52,17 @@ func MvCommand() *tish.Command {
 					return fmt.Errorf("destination '%s' should be directory", p.Args[len(p.Args)-1])
 				}
 				for _, src := range p.Args[:len(p.Args)-1] {
+					if err := ctx.Err(); err != nil {
+						res.SetInternalProcessResult(130)
+						return err
+					}
 					destPath := filepath.Join(dest, filepath.Base(src))
-					err := os.Rename(p.Shell.ExpandPath(src), destPath)
+					srcPath, err := p.Shell.ResolvePath(src)
+					if err != nil {
+						res.SetInternalProcessResult(1)
+						return err
+					}
+					err = p.Shell.FileSystem().Rename(srcPath, destPath)
 					if err != nil {
 						res.SetInternalProcessResult(1)
 						return err
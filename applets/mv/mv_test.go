@@ -121,5 +121,31 @@ func Test_mvCommand(t *testing.T) {
 				}
 			}
 		})
+		t.Run(tt.name+" (memory file system)", func(t *testing.T) {
+			root, fsys := tish.CreateTestFileSystem(t, "mv", map[string]string{
+				"test.txt":       "hello world",
+				"dir/first.txt":  "hello world",
+				"dir/second.txt": "hello world",
+				"dest1/":         "",
+			})
+
+			s := tish.NewShell(root, []string{})
+			s.SetFileSystem(fsys)
+			m := MvCommand()
+
+			p := tish.NewProcess(s, m.Executor, m.Name, tt.args, 10, 11, nil)
+			err := p.StartAndWait(context.Background())
+			if tt.wants.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				for _, p := range tt.wants.exists {
+					assert.True(t, tish.FileExists(fsys, filepath.Join(root, p)), p)
+				}
+				for _, p := range tt.wants.notExists {
+					assert.False(t, tish.FileExists(fsys, filepath.Join(root, p)), p)
+				}
+			}
+		})
 	}
 }
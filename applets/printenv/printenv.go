@@ -14,7 +14,8 @@ func init() {
 
 func PrintenvCommand() *tish.Command {
 	return &tish.Command{
-		Name: "printenv",
+		Name:        "printenv",
+		Description: "Print environment variables",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
 			e := env.Env()
 			if len(env.Args) > 0 {
@@ -17,7 +17,8 @@ func init() {
 
 func pushdCommand() *tish.Command {
 	return &tish.Command{
-		Name: "pushd",
+		Name:        "pushd",
+		Description: "Push the directory to the directory stack and change to it",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
 			current := env.Shell.WorkingDir()
 			var dirName string
@@ -29,7 +30,7 @@ func pushdCommand() *tish.Command {
 				result.SetInternalProcessResult(1)
 				return err
 			}
-			env.Shell.Dirs = append(env.Shell.Dirs, current)
+			env.Shell.PushDir(current)
 			showDirStack(env.Shell, env.Stdout)
 			result.SetInternalProcessResult(0)
 			return nil
@@ -40,15 +41,15 @@ func pushdCommand() *tish.Command {
 
 func popdCommand() *tish.Command {
 	return &tish.Command{
-		Name: "popd",
+		Name:        "popd",
+		Description: "Pop the directory stack and change to the top directory",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
-			if len(env.Shell.Dirs) == 0 {
+			last, ok := env.Shell.PopDir()
+			if !ok {
 				io.WriteString(env.Stderr, "popd: directory stack empty\n")
 				result.SetInternalProcessResult(1)
 				return tish.ErrStackEmpty
 			}
-			last := env.Shell.Dirs[len(env.Shell.Dirs)-1]
-			env.Shell.Dirs = env.Shell.Dirs[:len(env.Shell.Dirs)-1]
 			env.Shell.SetWorkingDir("popd", last, env.Stderr)
 			showDirStack(env.Shell, env.Stdout)
 			result.SetInternalProcessResult(0)
@@ -71,7 +72,8 @@ func normalizeDir(home, target string) string {
 
 func dirsCommand() *tish.Command {
 	return &tish.Command{
-		Name: "dirs",
+		Name:        "dirs",
+		Description: "Print the directory stack",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
 			showDirStack(env.Shell, env.Stdout)
 			result.SetInternalProcessResult(0)
@@ -84,8 +86,9 @@ func dirsCommand() *tish.Command {
 func showDirStack(s *tish.Shell, stdout io.Writer) {
 	home, _ := s.HomeDir()
 	io.WriteString(stdout, normalizeDir(home, s.WorkingDir()))
-	for i := len(s.Dirs) - 1; i >= 0; i-- {
-		io.WriteString(stdout, " "+normalizeDir(home, s.Dirs[i]))
+	dirs := s.DirStack()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		io.WriteString(stdout, " "+normalizeDir(home, dirs[i]))
 	}
 	io.WriteString(stdout, "\n")
 }
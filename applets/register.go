@@ -1,8 +1,14 @@
 package applets
 
 import (
+	_ "github.com/shibukawa/tish/applets/alias"
+	_ "github.com/shibukawa/tish/applets/bind"
 	_ "github.com/shibukawa/tish/applets/export"
+	_ "github.com/shibukawa/tish/applets/history"
+	_ "github.com/shibukawa/tish/applets/hook"
 	_ "github.com/shibukawa/tish/applets/printenv"
+	_ "github.com/shibukawa/tish/applets/setopt"
+	_ "github.com/shibukawa/tish/applets/source"
 	_ "github.com/shibukawa/tish/applets/unset"
 
 	_ "github.com/shibukawa/tish/applets/cd"
@@ -18,6 +24,7 @@ import (
 
 	_ "github.com/shibukawa/tish/applets/cat"
 	_ "github.com/shibukawa/tish/applets/echo"
+	_ "github.com/shibukawa/tish/applets/help"
 	_ "github.com/shibukawa/tish/applets/wc"
 
 	_ "github.com/shibukawa/tish/applets/sleep"
@@ -3,7 +3,6 @@ package rm
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/shibukawa/tish"
@@ -21,7 +20,9 @@ type config struct {
 
 func rmCommand() *tish.Command {
 	return &tish.Command{
-		Name: "rm",
+		Name:        "rm",
+		Description: "Remove files",
+		Options:     &config{},
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			c := &config{}
 			dirs, err := flags.ParseArgs(c, p.Args)
@@ -31,12 +32,19 @@ func rmCommand() *tish.Command {
 			}
 
 			for _, dir := range dirs {
-				dirPath := p.Shell.ExpandPath(dir)
-				var err error
+				if err := ctx.Err(); err != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
+				dirPath, err := p.Shell.ResolvePath(dir)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
 				if c.Recursive || c.RecursiveAlias {
-					err = os.RemoveAll(dirPath)
+					err = p.Shell.FileSystem().RemoveAll(dirPath)
 				} else {
-					fi, err := os.Lstat(dirPath)
+					fi, err := p.Shell.FileSystem().Lstat(dirPath)
 					if err != nil {
 						res.SetInternalProcessResult(1)
 						return err
@@ -45,7 +53,7 @@ func rmCommand() *tish.Command {
 						res.SetInternalProcessResult(1)
 						return fmt.Errorf("rm: %s: is a directory", dir)
 					}
-					err = os.Remove(dirPath)
+					err = p.Shell.FileSystem().Remove(dirPath)
 				}
 				if err != nil {
 					res.SetInternalProcessResult(1)
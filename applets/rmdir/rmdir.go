@@ -3,7 +3,6 @@ package rmdir
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/shibukawa/tish"
 )
@@ -14,11 +13,20 @@ func init() {
 
 func rmdirCommand() *tish.Command {
 	return &tish.Command{
-		Name: "rmdir",
+		Name:        "rmdir",
+		Description: "Remove empty directories",
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			for _, dir := range p.Args {
-				dirPath := p.Shell.ExpandPath(dir)
-				fi, err := os.Lstat(dirPath)
+				if err := ctx.Err(); err != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
+				dirPath, err := p.Shell.ResolvePath(dir)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
+				fi, err := p.Shell.FileSystem().Lstat(dirPath)
 				if err != nil {
 					res.SetInternalProcessResult(1)
 					return err
@@ -27,7 +35,7 @@ func rmdirCommand() *tish.Command {
 					res.SetInternalProcessResult(1)
 					return fmt.Errorf("rmdir: %s: Not a directory", dir)
 				}
-				if err = os.Remove(dirPath); err != nil {
+				if err = p.Shell.FileSystem().Remove(dirPath); err != nil {
 					res.SetInternalProcessResult(1)
 					return err
 				}
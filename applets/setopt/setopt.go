@@ -0,0 +1,79 @@
+package setopt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shibukawa/tish"
+)
+
+func init() {
+	tish.RegisterCommand(SetoptCommand())
+}
+
+// SetoptCommand shows and sets options of the shell (tish.Option) by their JSON names.
+//
+//	setopt                   print all options
+//	setopt NAME              set the boolean option to true
+//	setopt NAME=VALUE ...    set the options
+//	setopt '{"NAME": VALUE}' set the options in JSON
+func SetoptCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "setopt",
+		Description: "Show or set shell options",
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			if len(env.Args) == 0 {
+				values := env.Shell.OptionValues()
+				for _, name := range tish.OptionNames() {
+					fmt.Fprintf(env.Stdout, "%s=%s\n", name, values[name])
+				}
+				result.SetInternalProcessResult(0)
+				return nil
+			}
+			exitCode := 0
+			for _, arg := range env.Args {
+				values := map[string]string{}
+				if strings.HasPrefix(strings.TrimSpace(arg), "{") {
+					var raw map[string]json.RawMessage
+					if err := json.Unmarshal([]byte(arg), &raw); err != nil {
+						fmt.Fprintf(env.Stderr, "setopt: %v\n", err)
+						exitCode = 1
+						continue
+					}
+					for name, value := range raw {
+						values[name] = string(value)
+					}
+				} else if i := strings.Index(arg, "="); i != -1 {
+					values[arg[:i]] = arg[i+1:]
+				} else {
+					values[arg] = "true"
+				}
+				names := make([]string, 0, len(values))
+				for name := range values {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					if err := env.Shell.SetOption(name, values[name]); err != nil {
+						fmt.Fprintf(env.Stderr, "setopt: %v\n", err)
+						exitCode = 1
+					}
+				}
+			}
+			result.SetInternalProcessResult(exitCode)
+			return nil
+		},
+		Completer: func(input string) []string {
+			var result []string
+			for _, name := range tish.OptionNames() {
+				if strings.HasPrefix(name, input) {
+					result = append(result, name)
+				}
+			}
+			return result
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package setopt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shibukawa/tish"
+	"github.com/shibukawa/tish/applets/sleep"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setoptCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    []string
+		stderr   string
+		exitCode int
+		check    func(t *testing.T, opt tish.Option)
+	}{
+		{
+			name:  "boolean",
+			param: []string{"globstar", "dotglob=true"},
+			check: func(t *testing.T, opt tish.Option) {
+				assert.True(t, opt.GlobStar)
+				assert.True(t, opt.DotGlob)
+			},
+		},
+		{
+			name:  "json",
+			param: []string{`{"no_match": 2, "xtrace": true}`},
+			check: func(t *testing.T, opt tish.Option) {
+				assert.Equal(t, tish.FailGlob, opt.NoMatch)
+				assert.True(t, opt.XTrace)
+			},
+		},
+		{
+			name:     "limits in safe mode",
+			param:    []string{"command_timeout=0", `{"max_processes": 0}`},
+			stderr:   "setopt: command_timeout: read-only option\nsetopt: max_processes: read-only option\n",
+			exitCode: 1,
+			check: func(t *testing.T, opt tish.Option) {
+				assert.Equal(t, time.Second, opt.CommandTimeout)
+				assert.Equal(t, 4, opt.MaxProcesses)
+			},
+		},
+		{
+			name:     "read-only",
+			param:    []string{"safe_mode=false", "xtrace"},
+			stderr:   "setopt: safe_mode: read-only option\n",
+			exitCode: 1,
+			check: func(t *testing.T, opt tish.Option) {
+				assert.True(t, opt.SafeMode)
+				assert.True(t, opt.XTrace)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tish.NewShell("/home/myname", []string{}, tish.Option{SafeMode: true, CommandTimeout: time.Second, MaxProcesses: 4})
+			e := SetoptCommand()
+			stderr := bytes.Buffer{}
+			p := tish.NewProcess(s, e.Executor, e.Name, tt.param, 10, 11, nil)
+			p.Stdout = &bytes.Buffer{}
+			p.Stderr = &stderr
+			err := p.StartAndWait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.stderr, stderr.String())
+			assert.Equal(t, tt.exitCode, p.Result.ExitCode())
+			tt.check(t, s.Option())
+		})
+	}
+}
+
+func Test_setoptCommand_SafeModeTimeout(t *testing.T) {
+	s := tish.NewShell("/home/myname", []string{}, tish.Option{SafeMode: true, CommandTimeout: 200 * time.Millisecond})
+	s.RegisterCommand(SetoptCommand())
+	s.RegisterCommand(sleep.SleepCommand())
+	start := time.Now()
+	code, err := s.Run(context.Background(), "setopt command_timeout=0 ; sleep 1", &bytes.Buffer{}, &bytes.Buffer{})
+	var limitErr *tish.ErrLimitExceeded
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, 124, code)
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+}
+
+func Test_setoptCommand_List(t *testing.T) {
+	s := tish.NewShell("/home/myname", []string{}, tish.Option{GlobStar: true})
+	e := SetoptCommand()
+	stdout := bytes.Buffer{}
+	p := tish.NewProcess(s, e.Executor, e.Name, nil, 10, 11, nil)
+	p.Stdout = &stdout
+	p.Stderr = &bytes.Buffer{}
+	assert.NoError(t, p.StartAndWait(context.Background()))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Len(t, lines, len(tish.OptionNames()))
+	assert.Contains(t, lines, "globstar=true")
+	assert.Contains(t, lines, "kill_timeout=0s")
+}
@@ -18,7 +18,8 @@ func init() {
 
 func SleepCommand() *tish.Command {
 	return &tish.Command{
-		Name: "sleep",
+		Name:        "sleep",
+		Description: "Wait for the seconds",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
 			if len(env.Args) == 0 {
 				io.WriteString(env.Stderr, "usage: sleep seconds\n")
@@ -33,7 +34,10 @@ func SleepCommand() *tish.Command {
 					duration = time.Duration(num * float64(time.Second))
 				}
 			}
-			time.Sleep(duration)
+			if err := tish.SleepContext(ctx, duration); err != nil {
+				result.SetInternalProcessResult(130)
+				return err
+			}
 			result.SetInternalProcessResult(0)
 			return nil
 		},
@@ -53,3 +53,16 @@ func Test_sleepCommand(t *testing.T) {
 		})
 	}
 }
+
+func Test_sleepCommand_Cancel(t *testing.T) {
+	t.Parallel()
+	s := tish.NewShell("/dummy", nil)
+	sleep := SleepCommand()
+	p := tish.NewProcess(s, sleep.Executor, sleep.Name, []string{"10"}, 10, 11, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := p.StartAndWait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 130, p.Result.ExitCode())
+	assert.True(t, p.Result.WallTime() < time.Second)
+}
@@ -0,0 +1,44 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/shibukawa/tish"
+)
+
+func init() {
+	tish.RegisterCommand(SourceCommand("source"))
+	tish.RegisterCommand(SourceCommand("."))
+}
+
+// SourceCommand runs the script file in the current shell.
+//
+//	source FILE
+func SourceCommand(name string) *tish.Command {
+	return &tish.Command{
+		Name:        name,
+		Description: "Run commands of the file in the current shell",
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			if len(env.Args) == 0 {
+				fmt.Fprintf(env.Stderr, "%s: usage: %s FILE\n", name, name)
+				result.SetInternalProcessResult(2)
+				return tish.ErrRequireParameter
+			}
+			code, err := env.Shell.Source(ctx, env.Args[0], env.Stdout, env.Stderr)
+			if errors.Is(err, tish.ErrExit) || errors.Is(err, tish.ErrInterrupted) {
+				result.SetInternalProcessResult(code)
+				return err
+			} else if err != nil {
+				io.WriteString(env.Stderr, fmt.Sprintf("%s: %v\n", name, err))
+				result.SetInternalProcessResult(1)
+				return nil
+			}
+			result.SetInternalProcessResult(code)
+			return nil
+		},
+		Completer: nil,
+	}
+}
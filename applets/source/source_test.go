@@ -0,0 +1,46 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/shibukawa/tish"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sourceCommand(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "env.sh"), []byte("set-var FOO=1\nset-var BAR=2\n"), 0o644))
+	s := tish.NewShell(dir, []string{})
+	s.RegisterCommand(&tish.Command{
+		Name: "set-var",
+		Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) error {
+			for _, arg := range p.Args {
+				m := tish.EnvVarPattern.FindStringSubmatch(arg)
+				p.Shell.SetEnv(m[1], m[2])
+			}
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+
+	e := SourceCommand("source")
+	p := tish.NewProcess(s, e.Executor, e.Name, []string{"env.sh"}, 10, 11, nil)
+	p.Stdout = &bytes.Buffer{}
+	p.Stderr = &bytes.Buffer{}
+	assert.NoError(t, p.StartAndWait(context.Background()))
+	assert.Equal(t, 0, p.Result.ExitCode())
+	assert.Equal(t, "1", s.Getenv("FOO"))
+	assert.Equal(t, "2", s.Getenv("BAR"))
+
+	stderr := bytes.Buffer{}
+	p = tish.NewProcess(s, e.Executor, e.Name, []string{"missing.sh"}, 10, 11, nil)
+	p.Stdout = &bytes.Buffer{}
+	p.Stderr = &stderr
+	assert.NoError(t, p.StartAndWait(context.Background()))
+	assert.Equal(t, 1, p.Result.ExitCode())
+	assert.Contains(t, stderr.String(), "source: ")
+}
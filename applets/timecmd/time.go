@@ -2,36 +2,53 @@ package timecmd
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"strings"
-	"time"
 
 	"github.com/shibukawa/tish"
 )
 
 func init() {
 	tish.RegisterCommand(TimeCommand())
+	tish.RegisterCommand(TimesCommand())
 }
 
+// TimeCommand runs a command and reports its time.
+//
+// "time" at the beginning of pipeline is handled by the shell to measure whole pipeline.
+// This applet is used when it is called as a normal command.
 func TimeCommand() *tish.Command {
 	return &tish.Command{
-		Name: "time",
+		Name:        "time",
+		Description: "Run the command and report its time",
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
-			if len(env.Args) > 0 {
-				cmd := env.Args[0]
-				args := env.Args[1:]
-				childRes, err := env.Shell.RunChildProcess(ctx, env, cmd, args)
-				if childRes.ExitCode() != 0 {
-					result.SetInternalProcessResult(childRes.ExitCode())
+			args := env.Args
+			posix := false
+			if len(args) > 0 && args[0] == "-p" {
+				posix = true
+				args = args[1:]
+			}
+			if len(args) > 0 {
+				cmd := args[0]
+				childRes, err := env.Shell.RunChildProcess(ctx, env, cmd, args[1:])
+				if childRes == nil {
+					result.SetInternalProcessResult(127)
 					return err
 				}
-				user := float64(childRes.UserTime()) / float64(time.Second)
-				system := float64(childRes.SystemTime()) / float64(time.Second)
-				wall := float64(childRes.WallTime()) / float64(time.Second)
-				cpu := childRes.CPUUsage()
-
-				fmt.Fprintf(env.Stdout, "%s  %.2fs user %.2fs system %d%% cpu %.3f total", strings.Join(env.Args, " "), user, system, cpu, wall)
+				// the report goes to stderr like time keyword, so it is not mixed with redirected output
+				var report string
+				if posix {
+					report = tish.FormatTime(tish.PosixTimeFormat, childRes)
+				} else if format, ok := env.Env()["TIMEFORMAT"]; ok {
+					report = tish.FormatTime(format, childRes)
+				} else {
+					report = tish.FormatTimeSummary(strings.Join(args, " "), childRes)
+				}
+				io.WriteString(env.Stderr, report+"\n")
+				result.SetInternalProcessResult(childRes.ExitCode())
+				return err
 			}
+			result.SetInternalProcessResult(0)
 			return nil
 		},
 		Completer: nil,
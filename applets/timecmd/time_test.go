@@ -31,7 +31,16 @@ func Test_timeCommand(t *testing.T) {
 				args: []string{"sleep", "0.1"},
 			},
 			wants: wants{
-				pattern: regexp.MustCompile(`sleep 0.1  0.00s user 0.00s system 0% cpu 0.1\d* total`),
+				pattern: regexp.MustCompile(`^sleep 0.1  0.00s user 0.00s system 0% cpu 0.1\d* total\n$`),
+			},
+		},
+		{
+			name: "posix",
+			args: args{
+				args: []string{"-p", "sleep", "0.1"},
+			},
+			wants: wants{
+				pattern: regexp.MustCompile(`^real 0.1\d*\nuser 0.00\nsys 0.00\n$`),
 			},
 		},
 	}
@@ -39,12 +48,14 @@ func Test_timeCommand(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			s := tish.NewShell("/dummy", []string{})
 			timecmd := TimeCommand()
-			var stdout bytes.Buffer
+			var stdout, stderr bytes.Buffer
 			p := tish.NewProcess(s, timecmd.Executor, timecmd.Name, tt.args.args, 10, 11, nil)
 			p.Stdout = &stdout
+			p.Stderr = &stderr
 			err := p.StartAndWait(context.Background())
 			assert.NoError(t, err)
-			assert.True(t, tt.wants.pattern.MatchString(stdout.String()))
+			assert.Equal(t, "", stdout.String())
+			assert.Regexp(t, tt.wants.pattern, stderr.String())
 		})
 	}
 }
@@ -0,0 +1,25 @@
+package timecmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shibukawa/tish"
+)
+
+// TimesCommand prints cumulative user and system times of the shell and its children.
+func TimesCommand() *tish.Command {
+	return &tish.Command{
+		Name:        "times",
+		Description: "Print the accumulated times of the shell and its children",
+		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
+			t := env.Shell.Times()
+			fmt.Fprintf(env.Stdout, "%s %s\n%s %s\n",
+				tish.FormatDuration(t.User, 3, true), tish.FormatDuration(t.System, 3, true),
+				tish.FormatDuration(t.ChildUser, 3, true), tish.FormatDuration(t.ChildSystem, 3, true))
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+		Completer: nil,
+	}
+}
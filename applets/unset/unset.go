@@ -12,19 +12,22 @@ func init() {
 }
 
 func UnsetCommand() *tish.Command {
+	options := struct {
+		Variable bool `short:"v" description:"treat names as variables"`
+	}{}
 	return &tish.Command{
-		Name: "unset",
+		Name:        "unset",
+		Description: "Remove environment variables",
+		Options:     options,
 		Executor: func(ctx context.Context, result *tish.ExecResult, env *tish.Process) (err error) {
-			conf := &struct {
-				Variable bool `short:"v"`
-			}{}
-			args, err := flags.ParseArgs(conf, env.Args)
+			conf := options
+			args, err := flags.ParseArgs(&conf, env.Args)
 			if err != nil {
 				result.SetInternalProcessResult(1)
 				return err
 			}
 			for _, key := range args {
-				delete(env.Shell.Env, key)
+				env.Shell.DelEnv(key)
 			}
 			result.SetInternalProcessResult(0)
 			return nil
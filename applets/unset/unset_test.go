@@ -65,7 +65,7 @@ func Test_unsetCommand(t *testing.T) {
 			p.Stdout = &buf
 			err := p.StartAndWait(context.Background())
 			assert.NoError(t, err)
-			assert.Equal(t, tt.wants.envs, s.Env)
+			assert.Equal(t, tt.wants.envs, s.Environ())
 		})
 	}
 }
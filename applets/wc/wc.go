@@ -6,7 +6,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 
@@ -26,7 +25,9 @@ type config struct {
 
 func WordCountCommand() *tish.Command {
 	return &tish.Command{
-		Name: "wc",
+		Name:        "wc",
+		Description: "Count lines, words and bytes",
+		Options:     &config{},
 		Executor: func(ctx context.Context, res *tish.ExecResult, p *tish.Process) (err error) {
 			c := &config{}
 
@@ -36,7 +37,7 @@ func WordCountCommand() *tish.Command {
 				return nil
 			}
 			if len(args) == 0 || args[0] == "-" {
-				if err := wc(p.Stdout, "", p.Stdin, c); err != nil {
+				if err := wc(ctx, p.Stdout, "", p.Stdin, c); err != nil {
 					res.SetInternalProcessResult(1)
 					return err
 				}
@@ -44,13 +45,22 @@ func WordCountCommand() *tish.Command {
 				return nil
 			}
 			for _, path := range args {
-				f, err := os.Open(p.Shell.ExpandPath(path))
+				if err := ctx.Err(); err != nil {
+					res.SetInternalProcessResult(130)
+					return err
+				}
+				absPath, err := p.Shell.ResolvePath(path)
+				if err != nil {
+					res.SetInternalProcessResult(1)
+					return err
+				}
+				f, err := p.Shell.FileSystem().Open(absPath)
 				if err != nil {
 					return err
 				}
 				defer f.Close()
 
-				if err := wc(p.Stdout, path, f, c); err != nil {
+				if err := wc(ctx, p.Stdout, path, f, c); err != nil {
 					res.SetInternalProcessResult(1)
 					return err
 				}
@@ -68,13 +78,15 @@ type result struct {
 	bytes int
 }
 
-func wc(w io.Writer, path string, f io.Reader, c *config) error {
+func wc(ctx context.Context, w io.Writer, path string, f io.Reader, c *config) error {
 	var ret result
-	reader := bufio.NewReaderSize(f, 4096)
+	reader := bufio.NewReaderSize(tish.ContextReader(ctx, f), 4096)
 	for {
 		line, _, err := reader.ReadLine()
 		if err == io.EOF {
 			break
+		} else if err != nil && ctx.Err() != nil {
+			return err
 		}
 		ret.lines++
 		ret.bytes += len(line) + 1 // +1 means NewLine
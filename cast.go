@@ -0,0 +1,104 @@
+package tish
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CastHeader is a header of asciicast v2 file.
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// CastWriter writes terminal output in asciicast v2 format (https://docs.asciinema.org/manual/asciicast/v2/).
+//
+// Each Write becomes an "o" event with the time relative to the header.
+// It is safe to use from multiple goroutines.
+type CastWriter struct {
+	lock    *sync.Mutex
+	w       io.Writer
+	start   time.Time
+	pending []byte
+}
+
+// NewCastWriter writes the header and returns CastWriter.
+// If Version or Timestamp of the header is zero, 2 and current time are used.
+func NewCastWriter(w io.Writer, header CastHeader) (*CastWriter, error) {
+	start := time.Now()
+	if header.Version == 0 {
+		header.Version = 2
+	}
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(h, '\n')); err != nil {
+		return nil, err
+	}
+	return &CastWriter{
+		lock:  &sync.Mutex{},
+		w:     w,
+		start: start,
+	}, nil
+}
+
+// Write writes output event. "\n" is converted into "\r\n" like terminal does.
+func (c *CastWriter) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data := append(c.pending, p...)
+	// keep incomplete UTF-8 sequence for next write
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	c.pending = append([]byte{}, data[end:]...)
+	if end == 0 {
+		return len(p), nil
+	}
+	if err := c.event("o", data[:end]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *CastWriter) event(code string, data []byte) error {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	elapsed := float64(time.Since(c.start)) / float64(time.Second)
+	e, err := json.Marshal([]interface{}{elapsed, code, string(data)})
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(append(e, '\n'))
+	return err
+}
+
+// Flush writes the incomplete UTF-8 sequence kept for the next Write. It should be called before closing the file.
+func (c *CastWriter) Flush() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.pending) == 0 {
+		return nil
+	}
+	data := c.pending
+	c.pending = nil
+	return c.event("o", data)
+}
@@ -0,0 +1,55 @@
+package tish
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCastWriter(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := NewCastWriter(&buf, CastHeader{Width: 80, Height: 24, Title: "demo"})
+	assert.NoError(t, err)
+
+	c.Write([]byte("hello\n"))
+	// "あ" is split into two writes
+	c.Write([]byte("\xe3\x81"))
+	c.Write([]byte("\x82\r\n"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		var header CastHeader
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+		assert.Equal(t, 2, header.Version)
+		assert.Equal(t, 80, header.Width)
+		assert.Equal(t, "demo", header.Title)
+		assert.NotZero(t, header.Timestamp)
+
+		var events [][]interface{}
+		for _, line := range lines[1:] {
+			var e []interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &e))
+			events = append(events, e)
+		}
+		assert.Equal(t, "o", events[0][1])
+		assert.Equal(t, "hello\r\n", events[0][2])
+		assert.Equal(t, "あ\r\n", events[1][2])
+		assert.True(t, events[0][0].(float64) <= events[1][0].(float64))
+	}
+}
+
+func TestCastWriter_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := NewCastWriter(&buf, CastHeader{Width: 80, Height: 24})
+	assert.NoError(t, err)
+
+	c.Write([]byte("ok\xe3\x81"))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	assert.NoError(t, c.Flush())
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+	assert.NoError(t, c.Flush())
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"), "nothing is pending")
+}
@@ -9,12 +9,14 @@ import (
 	"os"
 	"os/signal"
 	"os/user"
+	"path/filepath"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/jessevdk/go-flags"
 	"github.com/shibukawa/tish"
 	_ "github.com/shibukawa/tish/applets"
-	"github.com/peterh/liner"
+	"github.com/shibukawa/tish/lineedit"
 )
 
 var (
@@ -27,13 +29,42 @@ func init() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 }
 
-func completor(input string) []string {
-	return nil
+type options struct {
+	Log string `long:"log" value-name:"FILE" description:"Write execution log in JSON to the file"`
+	Rec string `long:"rec" value-name:"FILE" description:"Record the session in asciicast v2 format to the file"`
+
+	Plugins string `long:"plugins" value-name:"DIR" description:"Load plugin commands from the directory (default: ~/.config/tish/plugins)"`
+	Wasm    string `long:"wasm" value-name:"DIR" description:"Load WebAssembly applets (*.wasm) from the directory (default: ~/.config/tish/wasm)"`
+	Theme   string `long:"theme" value-name:"NAME|FILE" description:"Prompt theme: pureline, simple or JSON file (default: ~/.config/tish/theme.json)"`
+	XTrace  bool   `short:"x" long:"xtrace" description:"Print commands and their arguments as they are executed"`
+
+	Login     bool   `short:"l" long:"login" description:"Act as a login shell (run the profile before tishrc)"`
+	NoProfile bool   `long:"noprofile" description:"Don't run the system profile and ~/.config/tish/profile"`
+	NoRC      bool   `long:"norc" description:"Don't run the system tishrc and ~/.config/tish/tishrc"`
+	RCFile    string `long:"rcfile" value-name:"FILE" description:"Run the file instead of ~/.config/tish/tishrc"`
 }
 
 func main() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+	var opts options
+	replay := &replayCommand{}
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.SubcommandsOptional = true
+	parser.AddCommand("replay", "Replay recorded log", "Re-run commands in the log recorded by --log and report differences of stdout, stderr and exit code.", replay)
+	if _, err := parser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+	if parser.Active != nil {
+		os.Exit(replay.status)
+	}
+	if opts.Rec != "" {
+		os.Exit(record(opts.Rec, os.Args[1:]))
+	}
+
+	// keep the shell alive when Ctrl-C is pressed outside of commands
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt)
 
 	wd, err := os.Getwd()
 	if err != nil {
@@ -56,38 +87,196 @@ func main() {
 		os.Exit(1)
 	}
 
-	shell := tish.NewShell(wd, os.Environ())
-	line := liner.NewLiner()
-	line.SetCtrlCAborts(true)
-	line.SetCompleter(completor)
-	defer line.Close()
+	shell := tish.NewShell(wd, os.Environ(), tish.Option{
+		Log:    opts.Log != "",
+		XTrace: opts.XTrace,
+	})
+	themeFile := opts.Theme
+	if themeFile == "" {
+		themeFile = filepath.Join(homedir, ".config", "tish", "theme.json")
+	}
+	if _, err := os.Stat(themeFile); err == nil || opts.Theme != "" {
+		theme, err := loadTheme(themeFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "can't load theme: %v\n", err)
+		} else {
+			shell.SetTheme(theme)
+		}
+	}
+	pluginDir := opts.Plugins
+	if pluginDir == "" {
+		pluginDir = filepath.Join(homedir, ".config", "tish", "plugins")
+	}
+	if _, err := os.Stat(pluginDir); err == nil || opts.Plugins != "" {
+		if err := shell.LoadPlugins(context.Background(), pluginDir, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "can't load plugins: %v\n", err)
+		}
+	}
+	wasmDir := opts.Wasm
+	if wasmDir == "" {
+		wasmDir = filepath.Join(homedir, ".config", "tish", "wasm")
+	}
+	if _, err := os.Stat(wasmDir); err == nil || opts.Wasm != "" {
+		if err := shell.LoadWasmApplets(context.Background(), wasmDir); err != nil {
+			fmt.Fprintf(os.Stderr, "can't load wasm applets: %v\n", err)
+		}
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+
+	// startup files can set HISTFILE and HISTSIZE, so they run before loading the history
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	status, err := runStartupFiles(ctx, shell, startupFiles(opts, homedir), stdout, stderr)
+	stop()
+	if errors.Is(err, tish.ErrExit) {
+		os.Exit(status)
+	}
+
+	if err := shell.LoadHistory(filepath.Join(homedir, ".tish_history")); err != nil {
+		fmt.Fprintf(os.Stderr, "can't load history: %v\n", err)
+	}
+
+	line := lineedit.New(os.Stdin, stdout)
+	for _, e := range shell.History().Entries() {
+		line.AppendHistory(e.Command)
+	}
+	line.SetCompleter(shell.Complete)
+	line.SetSuggester(shell.Suggest)
+	plain := false
+	line.SetHighlighter(func(cmd string) string {
+		return shell.Theme().HighlightLine(cmd, shell.Highlight(cmd), plain)
+	})
+	// "bind" can change key bindings at any time, so they are applied before each prompt
+	var bindings map[string]string
 	// pushd := []string{wd}
 
-	color.New(color.FgYellow).Println("🐸 tiny shell")
+	color.New(color.FgYellow).Fprintln(stdout, "🐸 tiny shell")
 	lastStatus := 0
+	var lastDuration time.Duration
+	exitStatus := 0
+	// prompt prints the prompt variable and reads a line
+	prompt := func(name string, pc tish.PromptContext, fallback string) (string, error) {
+		head, last := splitPrompt(renderPrompt(shell, name, pc, fallback))
+		if name == "PS1" {
+			width, _, _ := terminalSize()
+			head = "\n" + withRightPrompt(head, renderPrompt(shell, "RPROMPT", pc, ""), width)
+		}
+		fmt.Fprint(stdout, head)
+		return line.Prompt(last)
+	}
+loop:
 	for {
 		wd = shell.WorkingDir()
 
-		fmt.Printf("\n" + tish.Prompt(user.Username, hostName, wd, homedir, time.Now(), lastStatus, false))
-		if cmd, err := line.Prompt(" "); err == nil {
+		pc := shell.NewPromptContext()
+		pc.User = user.Username
+		pc.Host = hostName
+		pc.HomeDir = homedir
+		pc.Status = lastStatus
+		pc.Duration = lastDuration
+		plain = pc.Plain
+		bindings = syncBindings(line, bindings, shell.KeyBindings(), stderr)
+		// files may be changed by other programs while the prompt is not shown
+		shell.ResetSuggestions()
+		cmd, err := prompt("PS1", pc, "$ ")
+		for err == nil && tish.NeedsContinuation(cmd) {
+			var next string
+			next, err = prompt("PS2", pc, "> ")
+			if err == nil {
+				cmd = tish.JoinContinuation(cmd, next)
+			}
+		}
+		if err == nil {
 			if cmd == "" {
 				continue
 			}
-			status, err := shell.Run(ctx, cmd, os.Stdout, os.Stderr)
+			expanded, err := shell.History().Expand(cmd)
+			if err != nil {
+				fmt.Fprintf(stderr, "tish: %v\n", err)
+				lastStatus = 1
+				continue
+			}
+			if expanded != cmd {
+				// show the expanded command like bash
+				fmt.Fprintln(stdout, expanded)
+				cmd = expanded
+			}
+			// each command has its own context, so Ctrl-C stops only the running command
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			start := time.Now()
+			status, err := shell.Run(ctx, cmd, stdout, stderr)
+			lastDuration = time.Since(start)
+			stop()
+			lastStatus = status
+			if _, err := shell.AddHistory(tish.HistoryEntry{Command: cmd, ExitCode: status, WorkingDir: wd}); err != nil {
+				log.Print("Error writing history: ", err)
+			}
 			if errors.Is(err, tish.ErrExit) {
-				os.Exit(status)
-				break
+				exitStatus = status
+				break loop
+			} else if errors.Is(err, tish.ErrInterrupted) {
+				fmt.Fprintln(stdout)
 			} else if err != nil {
 				log.Print("Error reading line: ", err)
 			}
 			line.AppendHistory(cmd)
 		} else if errors.Is(err, io.EOF) {
-			break
-		} else if err == liner.ErrPromptAborted {
-			log.Print("Aborted")
-			break
+			break loop
+		} else if err == lineedit.ErrPromptAborted {
+			lastStatus = 130
 		} else {
 			log.Print("Error reading line: ", err)
 		}
 	}
+	if opts.Log != "" {
+		if err := writeLog(shell, opts.Log); err != nil {
+			fmt.Fprintf(os.Stderr, "can't write log: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(exitStatus)
+}
+
+// syncBindings applies the changes of key bindings from applied to bindings and returns bindings.
+// Removed keys revert to the default bindings of the editor.
+func syncBindings(line *lineedit.Editor, applied, bindings map[string]string, stderr io.Writer) map[string]string {
+	for key := range applied {
+		if _, ok := bindings[key]; !ok {
+			line.Unbind(key)
+		}
+	}
+	for key, action := range bindings {
+		if applied[key] == action {
+			continue
+		}
+		if err := line.Bind(key, action); err != nil {
+			fmt.Fprintf(stderr, "tish: bind %s: %v\n", key, err)
+			line.Unbind(key)
+		}
+	}
+	return bindings
+}
+
+func writeLog(shell *tish.Shell, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return shell.DumpLog(f)
+}
+
+func newCastWriter(w io.Writer) (*tish.CastWriter, error) {
+	width, height, ok := terminalSize()
+	if !ok {
+		width, height = 80, 24
+	}
+	return tish.NewCastWriter(w, tish.CastHeader{
+		Width:  width,
+		Height: height,
+		Title:  "tish",
+		Env: map[string]string{
+			"SHELL": "tish",
+			"TERM":  os.Getenv("TERM"),
+		},
+	})
 }
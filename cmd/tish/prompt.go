@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/shibukawa/tish"
+)
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+// renderPrompt renders the prompt variable. It reports the error and returns fallback if the template is broken.
+func renderPrompt(shell *tish.Shell, name string, pc tish.PromptContext, fallback string) string {
+	prompt, err := shell.RenderPrompt(context.Background(), name, pc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tish: %s: %v\n", name, err)
+		return fallback
+	}
+	return prompt
+}
+
+// splitPrompt splits the prompt into lines printed before the line editor and the last line that is passed to it.
+// Colors that are not closed are reset at the end of both parts, so they don't leak into the input line.
+func splitPrompt(prompt string) (head, last string) {
+	if i := strings.LastIndexByte(prompt, '\n'); i != -1 {
+		head, last = prompt[:i+1], prompt[i+1:]
+	} else {
+		last = prompt
+	}
+	if ansiPattern.MatchString(prompt) {
+		head += "\x1b[0m"
+		last += "\x1b[0m"
+	}
+	return head, last
+}
+
+// withRightPrompt puts the right prompt at the end of the first line of head.
+// If head doesn't have lines, the right prompt is put in its own line.
+func withRightPrompt(head, right string, width int) string {
+	if right == "" || width <= 0 {
+		return head
+	}
+	first, rest := head, ""
+	if i := strings.IndexByte(head, '\n'); i != -1 {
+		first, rest = head[:i], head[i:]
+	} else {
+		rest = "\n"
+	}
+	pad := width - runewidth.StringWidth(ansiPattern.ReplaceAllString(first, "")) - runewidth.StringWidth(ansiPattern.ReplaceAllString(right, "")) - 1
+	if pad < 1 {
+		return head
+	}
+	return first + strings.Repeat(" ", pad) + right + rest
+}
+
+// loadTheme loads the builtin theme of the name or the theme file.
+func loadTheme(nameOrPath string) (*tish.Theme, error) {
+	if t, ok := tish.BuiltinTheme(nameOrPath); ok {
+		return t, nil
+	}
+	return tish.LoadThemeFile(nameOrPath)
+}
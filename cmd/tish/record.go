@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// record runs tish without --rec in a pseudo terminal and records its output in asciicast v2 format like script
+// command. The shell and the commands in it use the terminal as usual, so they keep colors and interactive behavior.
+func record(path string, args []string) int {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't create %s: %v\n", path, err)
+		return 1
+	}
+	defer f.Close()
+	cast, err := newCastWriter(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't write %s: %v\n", path, err)
+		return 1
+	}
+	status, err := runInPty(withoutRecOption(args), io.MultiWriter(os.Stdout, cast))
+	if err := cast.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "can't write %s: %v\n", path, err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't record: %v\n", err)
+		return 1
+	}
+	return status
+}
+
+// withoutRecOption removes "--rec FILE" and "--rec=FILE" from the command line arguments.
+func withoutRecOption(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return append(result, args[i:]...)
+		case args[i] == "--rec":
+			i++
+		case strings.HasPrefix(args[i], "--rec="):
+		default:
+			result = append(result, args[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// runInPty is not supported, because recording needs a pseudo terminal to keep commands attached to the terminal.
+func runInPty(args []string, out io.Writer) (int, error) {
+	return 0, errors.New("--rec is not supported on this platform")
+}
@@ -0,0 +1,75 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/shibukawa/tish/lineedit"
+	"golang.org/x/sys/unix"
+)
+
+// runInPty runs this executable with args in a new pseudo terminal and copies the terminal output to out.
+// It returns the exit code of the child.
+func runInPty(args []string, out io.Writer) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	master, slave, err := openPty()
+	if err != nil {
+		return 0, err
+	}
+	defer master.Close()
+
+	resize := func() {
+		if ws, err := unix.IoctlGetWinsize(int(os.Stdin.Fd()), unix.TIOCGWINSZ); err == nil {
+			unix.IoctlSetWinsize(int(master.Fd()), unix.TIOCSWINSZ, ws)
+		}
+	}
+	resize()
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	go func() {
+		for range winch {
+			resize()
+		}
+	}()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	// the child becomes a session leader that has the pseudo terminal as its controlling terminal
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	err = cmd.Start()
+	slave.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	// keys (including Ctrl-C) are sent to the pseudo terminal as is
+	if restore, err := lineedit.MakeRaw(os.Stdin); err == nil {
+		defer restore()
+	}
+	go io.Copy(master, os.Stdin)
+	copied := make(chan struct{})
+	go func() {
+		// reading the master fails with EIO after the child closes the terminal
+		io.Copy(out, master)
+		close(copied)
+	}()
+
+	err = cmd.Wait()
+	<-copied
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/shibukawa/tish"
+)
+
+type replayCommand struct {
+	Verbose bool `short:"v" long:"verbose" description:"Show commands that have no difference too"`
+
+	status int
+}
+
+func (c *replayCommand) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: tish replay log.json")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var root tish.LogRoot
+	if err := json.NewDecoder(f).Decode(&root); err != nil {
+		return fmt.Errorf("can't read log %s: %w", args[0], err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results := tish.Replay(ctx, root)
+	if writeReplayReport(os.Stdout, results, c.Verbose) > 0 {
+		c.status = 1
+	}
+	return nil
+}
+
+// writeReplayReport writes differences and returns the number of commands that have differences.
+func writeReplayReport(w io.Writer, results []tish.ReplayResult, verbose bool) int {
+	failed := 0
+	for _, r := range results {
+		if r.OK() {
+			if verbose {
+				fmt.Fprintf(w, "ok   #%d %s\n", r.Index+1, r.Command)
+			}
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL #%d %s (in %s)\n", r.Index+1, r.Command, r.WorkingDir)
+		if r.ExpectedExitCode != r.ActualExitCode {
+			fmt.Fprintf(w, "  exit code: expected %d, actual %d\n", r.ExpectedExitCode, r.ActualExitCode)
+		}
+		writeDiff(w, "stdout", r.StdoutDiff)
+		writeDiff(w, "stderr", r.StderrDiff)
+	}
+	fmt.Fprintf(w, "%d commands, %d differences\n", len(results), failed)
+	return failed
+}
+
+func writeDiff(w io.Writer, name string, diff []string) {
+	if diff == nil {
+		return
+	}
+	fmt.Fprintf(w, "  %s:\n", name)
+	for _, line := range diff {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
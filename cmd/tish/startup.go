@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/shibukawa/tish"
+)
+
+// startupFiles returns the startup files to run in order. Missing files are skipped.
+//
+// Login shells run the system profile and ~/.config/tish/profile (or ~/.tish_profile) first.
+// Then the system tishrc and ~/.config/tish/tishrc (or ~/.tishrc) run. --rcfile replaces the user's tishrc.
+func startupFiles(opts options, homedir string) []string {
+	var files []string
+	if (opts.Login || isLoginShell()) && !opts.NoProfile {
+		files = append(files, filepath.Join(systemConfigDir(), "profile"))
+		files = append(files, firstExisting(
+			filepath.Join(homedir, ".config", "tish", "profile"),
+			filepath.Join(homedir, ".tish_profile"),
+		))
+	}
+	if !opts.NoRC {
+		files = append(files, filepath.Join(systemConfigDir(), "tishrc"))
+		if opts.RCFile != "" {
+			files = append(files, opts.RCFile)
+		} else {
+			files = append(files, firstExisting(
+				filepath.Join(homedir, ".config", "tish", "tishrc"),
+				filepath.Join(homedir, ".tishrc"),
+			))
+		}
+	}
+	var result []string
+	for _, file := range files {
+		if file == opts.RCFile {
+			// a missing file given by the flag is reported
+			result = append(result, file)
+		} else if _, err := os.Stat(file); err == nil {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// isLoginShell returns true if the shell is started as a login shell ("-tish" in argv[0]).
+func isLoginShell() bool {
+	return len(os.Args[0]) > 0 && os.Args[0][0] == '-'
+}
+
+// firstExisting returns the first existing file or the first path if none of them exists.
+func firstExisting(paths ...string) string {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return paths[0]
+}
+
+// runStartupFiles runs the files in the shell. Errors are written to stderr with the file name and line number.
+// It returns ErrExit if a file runs exit.
+func runStartupFiles(ctx context.Context, shell *tish.Shell, files []string, stdout, stderr io.Writer) (int, error) {
+	for _, file := range files {
+		code, err := shell.Source(ctx, file, stdout, stderr)
+		if errors.Is(err, tish.ErrExit) || errors.Is(err, tish.ErrInterrupted) {
+			return code, err
+		} else if err != nil {
+			io.WriteString(stderr, "tish: "+err.Error()+"\n")
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// systemConfigDir is the directory of the system wide startup files.
+func systemConfigDir() string {
+	return "/etc/tish"
+}
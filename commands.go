@@ -10,17 +10,26 @@ type externalCommand struct {
 }
 
 func (e externalCommand) Executor(ctx context.Context, result *ExecResult, p *Process) (err error) {
-	cmd := exec.CommandContext(ctx, e.fullPath, p.Args...)
+	cmd := exec.Command(e.fullPath, p.Args...)
 	cmd.Stdin = p.Stdin
 	cmd.Stdout = p.Stdout
 	cmd.Stderr = p.Stderr
 	cmd.Dir = p.Shell.WorkingDir()
-	// todo: env
+	cmd.Env = envList(p.Env())
 	err = cmd.Start()
 	if err != nil {
 		return err
 	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			terminate(cmd.Process, done, p.Shell.killTimeout())
+		case <-done:
+		}
+	}()
 	err = cmd.Wait()
+	close(done)
 	p.Result.SetExternalProcessResult(cmd.ProcessState)
 	return err
 }
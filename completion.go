@@ -0,0 +1,400 @@
+package tish
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// CompletionKind is a kind of the word at the cursor.
+type CompletionKind int
+
+const (
+	// CompleteCommand is a word at command position.
+	CompleteCommand CompletionKind = iota
+	// CompleteArgument is an argument of the command.
+	CompleteArgument
+	// CompleteRedirect is a file name after redirection like ">".
+	CompleteRedirect
+	// CompleteVariable is a variable name after "$".
+	CompleteVariable
+)
+
+// CompletionContext is a result of analyzing the partial command line.
+type CompletionContext struct {
+	Kind CompletionKind
+	// Command is the command name of the argument. It is empty at command position.
+	Command string
+	// Args are arguments before the cursor.
+	Args []string
+	// Word is the word at the cursor without quotes. For CompleteVariable, it is a part of the name after "$".
+	Word string
+	// Start is a byte offset where the word begins. The completion replaces the line from Start to the cursor.
+	Start int
+	// Quote is an opening quote of the word (' or ") that isn't closed. It is zero if the word is not quoted.
+	Quote byte
+}
+
+var redirectOperators = map[string]bool{
+	"<":   true,
+	">":   true,
+	">>":  true,
+	"2>":  true,
+	"2>>": true,
+	"&>":  true,
+	"&>>": true,
+}
+
+var sessionOperators = map[string]bool{
+	"|":  true,
+	";":  true,
+	"||": true,
+	"&&": true,
+}
+
+// AnalyzeCompletion analyzes the line before pos (byte offset of the cursor) to find what should be completed.
+func AnalyzeCompletion(line string, pos int) CompletionContext {
+	src := line[:pos]
+	words, err := parser.SplitWords(src)
+	var quote byte
+	if err == parser.ErrQuoteNotClosed {
+		quote = openQuote(src[words[len(words)-1].Start:])
+	}
+	// the cursor is at new word if the line ends with space
+	current := parser.Word{Start: pos, End: pos}
+	if len(words) > 0 && (words[len(words)-1].End == pos || quote != 0) {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	result := CompletionContext{
+		Kind:  CompleteArgument,
+		Word:  current.Text,
+		Start: current.Start,
+		Quote: quote,
+	}
+
+	// words of the current session
+	var session []parser.Word
+	for _, w := range words {
+		if !w.Quoted && sessionOperators[w.Text] {
+			session = nil
+		} else {
+			session = append(session, w)
+		}
+	}
+	var cmdWords []string
+	redirect := false
+	for _, w := range session {
+		if redirect {
+			redirect = false
+			continue
+		}
+		if !w.Quoted && redirectOperators[w.Text] {
+			redirect = true
+			continue
+		}
+		cmdWords = append(cmdWords, w.Text)
+	}
+	// time keyword runs the following command
+	if len(cmdWords) > 0 && cmdWords[0] == "time" {
+		cmdWords = cmdWords[1:]
+		for len(cmdWords) > 0 && (cmdWords[0] == "-p" || cmdWords[0] == "--") {
+			cmdWords = cmdWords[1:]
+		}
+	}
+
+	switch {
+	case quote != '\'' && variablePrefix(current, src) >= 0:
+		i := variablePrefix(current, src)
+		result.Kind = CompleteVariable
+		result.Word = src[i:]
+		result.Start = i
+		result.Quote = 0
+	case redirect:
+		result.Kind = CompleteRedirect
+	case len(cmdWords) == 0:
+		result.Kind = CompleteCommand
+	default:
+		result.Command = cmdWords[0]
+		result.Args = cmdWords[1:]
+	}
+	return result
+}
+
+// openQuote returns the quote that isn't closed in the word.
+func openQuote(word string) byte {
+	var quote byte
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == c:
+			quote = 0
+		case c == '\\' && quote != '\'':
+			i++
+		}
+	}
+	return quote
+}
+
+// variablePrefix returns the offset of variable name if the cursor is just after "$NAME" or "${NAME".
+// It returns -1 if the cursor is not in variable name.
+func variablePrefix(current parser.Word, src string) int {
+	i := len(src)
+	for i > current.Start && isVariableChar(src[i-1]) {
+		i--
+	}
+	if i > current.Start && src[i-1] == '{' {
+		i--
+	}
+	if i > current.Start && src[i-1] == '$' {
+		return i
+	}
+	return -1
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// Complete returns completion candidates of the word at pos (byte offset of the cursor).
+// The signature is as same as lineedit.Completer: the new line is head + candidate + tail.
+//
+// It completes command names (applets, aliases and executables in PATH), variable names, file names,
+// flags of commands that have Options and arguments of commands that have Completer.
+// If nothing matches for the argument, file names are completed.
+func (s *Shell) Complete(line string, pos int) (head string, completions []string, tail string) {
+	head, candidates, tail := s.Candidates(line, pos)
+	for _, c := range candidates {
+		completions = append(completions, c.Text)
+	}
+	return head, completions, tail
+}
+
+// Candidates is as same as Complete, but the candidates have descriptions of flags.
+func (s *Shell) Candidates(line string, pos int) (head string, candidates []Candidate, tail string) {
+	c := AnalyzeCompletion(line, pos)
+	var words []string
+	switch c.Kind {
+	case CompleteVariable:
+		braced := strings.HasPrefix(c.Word, "{")
+		prefix := strings.TrimPrefix(c.Word, "{")
+		for name := range s.Environ() {
+			if strings.HasPrefix(name, prefix) {
+				if braced {
+					words = append(words, "{"+name+"}")
+				} else {
+					words = append(words, name)
+				}
+			}
+		}
+		sort.Strings(words)
+		for _, w := range words {
+			candidates = append(candidates, Candidate{Text: w})
+		}
+		return line[:c.Start], candidates, line[pos:]
+	case CompleteCommand:
+		if strings.ContainsRune(c.Word, '/') || strings.ContainsRune(c.Word, filepath.Separator) {
+			words = s.completeFiles(c.Word, true)
+		} else {
+			words = s.completeCommands(c.Word)
+		}
+	case CompleteRedirect:
+		words = s.completeFiles(c.Word, false)
+	case CompleteArgument:
+		cmd := s.lookupCommand(c.Command)
+		if cmd != nil && cmd.Completer != nil {
+			words = cmd.Completer(c.Word)
+		}
+		if cmd != nil && strings.HasPrefix(c.Word, "-") {
+			candidates = completeFlags(cmd, c.Word, words)
+		}
+		if len(words) == 0 && len(candidates) == 0 {
+			words = s.completeFiles(c.Word, false)
+		}
+	}
+	for _, w := range words {
+		candidates = append(candidates, Candidate{Text: quoteCompletion(w, c.Quote)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Text < candidates[j].Text
+	})
+	return line[:c.Start], candidates, line[pos:]
+}
+
+// completeFlags returns flags of the command that begin with the word except ones in exclude.
+func completeFlags(cmd *Command, word string, exclude []string) []Candidate {
+	excluded := map[string]bool{}
+	for _, e := range exclude {
+		excluded[e] = true
+	}
+	var result []Candidate
+	for _, f := range cmd.FlagCandidates(word) {
+		if !excluded[f.Text] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// completeCommands returns command names that begin with the prefix.
+func (s *Shell) completeCommands(prefix string) []string {
+	found := map[string]bool{}
+	for _, cmd := range s.Commands() {
+		if strings.HasPrefix(cmd.Name, prefix) {
+			found[cmd.Name] = true
+		}
+	}
+	for _, name := range s.AliasNames() {
+		if strings.HasPrefix(name, prefix) {
+			found[name] = true
+		}
+	}
+	if !s.Option().SafeMode {
+		for _, dir := range filepath.SplitList(s.Getenv("PATH")) {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				name := e.Name()
+				if runtime.GOOS == "windows" {
+					name = strings.TrimSuffix(name, filepath.Ext(name))
+				}
+				if !strings.HasPrefix(name, prefix) || found[name] || e.IsDir() {
+					continue
+				}
+				if isExecutable(filepath.Join(dir, e.Name())) {
+					found[name] = true
+				}
+			}
+		}
+	}
+	result := make([]string, 0, len(found))
+	for name := range found {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isExecutable(path string) bool {
+	stat, err := os.Stat(path)
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext == ".exe" || ext == ".bat" || ext == ".cmd" || ext == ".com"
+	}
+	return stat.Mode()&0o111 != 0
+}
+
+// completeFiles returns file names that begin with the word. Directories end with "/".
+// Files that begin with "." are included only if the word begins with ".".
+func (s *Shell) completeFiles(word string, executableOnly bool) []string {
+	dirPart, base := "", word
+	if i := strings.LastIndexAny(word, "/"+string(filepath.Separator)); i != -1 {
+		dirPart, base = word[:i+1], word[i+1:]
+	}
+	dir := dirPart
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := s.ResolvePath(dir)
+	if err != nil {
+		return nil
+	}
+	entries, err := s.FileSystem().ReadDir(abs)
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		isDir := e.IsDir()
+		if e.Type()&os.ModeSymlink != 0 {
+			if stat, err := s.FileSystem().Stat(filepath.Join(abs, name)); err == nil {
+				isDir = stat.IsDir()
+			}
+		}
+		if isDir {
+			result = append(result, dirPart+name+"/")
+		} else if !executableOnly || isExecutable(filepath.Join(abs, name)) {
+			result = append(result, dirPart+name)
+		}
+	}
+	return result
+}
+
+// quoteCompletion quotes the candidate to be parsed as same text.
+// If the word has an open quote, the candidate is quoted with it and the quote is left open.
+func quoteCompletion(candidate string, quote byte) string {
+	switch quote {
+	case '\'':
+		return "'" + candidate
+	case '"':
+		var b strings.Builder
+		b.WriteByte('"')
+		for i := 0; i < len(candidate); i++ {
+			if c := candidate[i]; c == '"' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(candidate[i])
+		}
+		return b.String()
+	}
+	var b strings.Builder
+	for i := 0; i < len(candidate); i++ {
+		if strings.IndexByte(" \t\n'\"\\$`|;&<>*?[]#", candidate[i]) != -1 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(candidate[i])
+	}
+	return b.String()
+}
+
+// NeedsContinuation returns true if the line is incomplete and the next line (PS2 prompt) should be joined:
+// a quote isn't closed, the line ends with back slash or the line ends with "|", "&&" or "||".
+func NeedsContinuation(line string) bool {
+	words, err := parser.SplitWords(line)
+	if err == parser.ErrQuoteNotClosed {
+		return true
+	}
+	if endsWithBackslash(line) {
+		return true
+	}
+	if len(words) == 0 {
+		return false
+	}
+	last := words[len(words)-1]
+	return !last.Quoted && last.Text != ";" && sessionOperators[last.Text]
+}
+
+// JoinContinuation joins the incomplete line and the next line.
+// The new line is kept in quotes, and back slash at the end of line is removed with the new line like bash.
+func JoinContinuation(line, next string) string {
+	if _, err := parser.SplitWords(line); err == parser.ErrQuoteNotClosed {
+		return line + "\n" + next
+	}
+	if endsWithBackslash(line) {
+		return line[:len(line)-1] + next
+	}
+	return line + " " + next
+}
+
+func endsWithBackslash(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
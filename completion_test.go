@@ -0,0 +1,257 @@
+package tish
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeCompletion(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want CompletionContext
+	}{
+		{
+			name: "empty",
+			line: "",
+			want: CompletionContext{Kind: CompleteCommand},
+		},
+		{
+			name: "command",
+			line: "ec",
+			want: CompletionContext{Kind: CompleteCommand, Word: "ec"},
+		},
+		{
+			name: "new argument",
+			line: "ls -l ",
+			want: CompletionContext{Kind: CompleteArgument, Command: "ls", Args: []string{"-l"}, Start: 6},
+		},
+		{
+			name: "argument",
+			line: "cat a.txt b",
+			want: CompletionContext{Kind: CompleteArgument, Command: "cat", Args: []string{"a.txt"}, Word: "b", Start: 10},
+		},
+		{
+			name: "after pipe",
+			line: "ls | w",
+			want: CompletionContext{Kind: CompleteCommand, Word: "w", Start: 5},
+		},
+		{
+			name: "after logical and",
+			line: "mkdir a && c",
+			want: CompletionContext{Kind: CompleteCommand, Word: "c", Start: 11},
+		},
+		{
+			name: "time keyword",
+			line: "time -p sl",
+			want: CompletionContext{Kind: CompleteCommand, Word: "sl", Start: 8},
+		},
+		{
+			name: "redirect",
+			line: "echo hello > out",
+			want: CompletionContext{Kind: CompleteRedirect, Word: "out", Start: 13},
+		},
+		{
+			name: "argument after redirect",
+			line: "cat < in.txt -",
+			want: CompletionContext{Kind: CompleteArgument, Command: "cat", Args: []string{}, Word: "-", Start: 13},
+		},
+		{
+			name: "quoted operator is argument",
+			line: "echo '|' x",
+			want: CompletionContext{Kind: CompleteArgument, Command: "echo", Args: []string{"|"}, Word: "x", Start: 9},
+		},
+		{
+			name: "open quote",
+			line: `cat "my d`,
+			want: CompletionContext{Kind: CompleteArgument, Command: "cat", Args: []string{}, Word: "my d", Start: 4, Quote: '"'},
+		},
+		{
+			name: "escaped space",
+			line: `cat my\ d`,
+			want: CompletionContext{Kind: CompleteArgument, Command: "cat", Args: []string{}, Word: "my d", Start: 4},
+		},
+		{
+			name: "variable",
+			line: "echo $HO",
+			want: CompletionContext{Kind: CompleteVariable, Word: "HO", Start: 6},
+		},
+		{
+			name: "braced variable in double quote",
+			line: `echo "path: ${PA`,
+			want: CompletionContext{Kind: CompleteVariable, Word: "{PA", Start: 13},
+		},
+		{
+			name: "dollar in single quote is not variable",
+			line: `echo '$HO`,
+			want: CompletionContext{Kind: CompleteArgument, Command: "echo", Args: []string{}, Word: "$HO", Start: 5, Quote: '\''},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, AnalyzeCompletion(tt.line, len(tt.line)))
+		})
+	}
+}
+
+func TestShell_Complete(t *testing.T) {
+	root := CreateTestFolders(t, "complete", map[string]string{
+		"main.go":          "",
+		"main_test.go":     "",
+		"my dir/file.txt":  "",
+		"doc/readme.md":    "",
+		".hidden":          "",
+		"bin/run|755":      "",
+		"bin/data.txt|644": "",
+	})
+
+	s := NewShell(root, []string{"HOME=/home/tish", "HOSTNAME=tish", "PATH="}, Option{SafeMode: true, AllowPath: []string{root}})
+	registerMockCommand(t, s, "mock")
+	registerMockCommand(t, s, "mkdir")
+	s.RegisterCommand(&Command{
+		Name:     "greet",
+		Executor: (&mockExecutor{}).Executor,
+		Completer: func(input string) []string {
+			if input == "--" {
+				return []string{"--name", "--upper"}
+			}
+			return nil
+		},
+	})
+	s.SetAlias("mm", "mock -m")
+
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		wantHead string
+		want     []string
+		wantTail string
+	}{
+		{
+			name:     "commands and aliases",
+			line:     "m",
+			wantHead: "",
+			want:     []string{"mkdir", "mm", "mock"},
+		},
+		{
+			name:     "files",
+			line:     "mock ma",
+			wantHead: "mock ",
+			want:     []string{"main.go", "main_test.go"},
+		},
+		{
+			name:     "hidden files",
+			line:     "mock .",
+			wantHead: "mock ",
+			want:     []string{".hidden"},
+		},
+		{
+			name:     "directory and escape",
+			line:     "mock my",
+			wantHead: "mock ",
+			want:     []string{`my\ dir/`},
+		},
+		{
+			name:     "open quote",
+			line:     `mock "my`,
+			wantHead: "mock ",
+			want:     []string{`"my dir/`},
+		},
+		{
+			name:     "in directory",
+			line:     "mock my\\ dir/",
+			wantHead: "mock ",
+			want:     []string{`my\ dir/file.txt`},
+		},
+		{
+			name:     "executable path",
+			line:     "bin/",
+			wantHead: "",
+			want:     []string{"bin/run"},
+		},
+		{
+			name:     "redirect",
+			line:     "mock > d",
+			wantHead: "mock > ",
+			want:     []string{"doc/"},
+		},
+		{
+			name:     "command completer",
+			line:     "greet --",
+			wantHead: "greet ",
+			want:     []string{"--name", "--upper"},
+		},
+		{
+			name:     "fallback to files",
+			line:     "greet do",
+			wantHead: "greet ",
+			want:     []string{"doc/"},
+		},
+		{
+			name:     "variable",
+			line:     "mock $HO",
+			wantHead: "mock $",
+			want:     []string{"HOME", "HOSTNAME"},
+		},
+		{
+			name:     "braced variable",
+			line:     "mock ${HOM",
+			wantHead: "mock $",
+			want:     []string{"{HOME}"},
+		},
+		{
+			name:     "cursor in the middle",
+			line:     "mock ma | mock",
+			pos:      7,
+			wantHead: "mock ",
+			want:     []string{"main.go", "main_test.go"},
+			wantTail: " | mock",
+		},
+		{
+			name:     "outside of allowed path",
+			line:     "mock ../",
+			wantHead: "mock ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := tt.pos
+			if pos == 0 {
+				pos = len(tt.line)
+			}
+			head, got, tail := s.Complete(tt.line, pos)
+			assert.Equal(t, tt.wantHead, head)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTail, tail)
+		})
+	}
+}
+
+func TestNeedsContinuation(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		next string
+		want bool
+		join string
+	}{
+		{name: "complete", line: "ls -l", want: false},
+		{name: "semicolon", line: "ls ;", want: false},
+		{name: "quote", line: `echo "hello`, next: `world"`, want: true, join: "echo \"hello\nworld\""},
+		{name: "back slash", line: `echo hello \`, next: "world", want: true, join: "echo hello world"},
+		{name: "escaped back slash", line: `echo \\`, want: false},
+		{name: "pipe", line: "ls |", next: "wc", want: true, join: "ls | wc"},
+		{name: "logical and", line: "mkdir a &&", next: "cd a", want: true, join: "mkdir a && cd a"},
+		{name: "quoted operator", line: "echo '|'", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NeedsContinuation(tt.line))
+			if tt.want {
+				assert.Equal(t, tt.join, JoinContinuation(tt.line, tt.next))
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package tish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// DefaultKillTimeout is a default value of Option.KillTimeout.
+const DefaultKillTimeout = 2 * time.Second
+
+// ContextReader returns a reader that stops with ctx.Err() after the context is cancelled.
+//
+// Applets should wrap their inputs with it so that Ctrl-C can interrupt long copies.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// CopyContext is io.Copy that stops when the context is cancelled.
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, ContextReader(ctx, src))
+}
+
+// SleepContext pauses the current goroutine like time.Sleep. It returns ctx.Err() if the context is cancelled.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func interrupted(ctx context.Context) error {
+	return fmt.Errorf("%w: %v", ErrInterrupted, ctx.Err())
+}
+
+// terminate sends SIGINT, then SIGTERM to the process and waits timeout for each signal.
+// If the process is still alive, it is killed.
+func terminate(proc *os.Process, done <-chan struct{}, timeout time.Duration) {
+	for _, sig := range []os.Signal{os.Interrupt, syscall.SIGTERM} {
+		// Windows doesn't support sending signals
+		if err := proc.Signal(sig); err != nil {
+			continue
+		}
+		timer := time.NewTimer(timeout)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	proc.Kill()
+}
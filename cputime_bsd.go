@@ -0,0 +1,19 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package tish
+
+import (
+	"syscall"
+	"time"
+)
+
+// threadCPUTime returns CPU time of the whole process because per-thread usage is not available.
+// It is an approximation when other goroutines are busy.
+func threadCPUTime() (user, system time.Duration) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0
+	}
+	return time.Duration(ru.Utime.Nano()), time.Duration(ru.Stime.Nano())
+}
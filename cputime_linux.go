@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package tish
+
+import (
+	"syscall"
+	"time"
+)
+
+// threadCPUTime returns CPU time of the current OS thread.
+// The caller should lock the goroutine to the thread by runtime.LockOSThread.
+func threadCPUTime() (user, system time.Duration) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_THREAD, &ru); err != nil {
+		return 0, 0
+	}
+	return time.Duration(ru.Utime.Nano()), time.Duration(ru.Stime.Nano())
+}
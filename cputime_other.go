@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package tish
+
+import "time"
+
+// threadCPUTime is not supported on this platform.
+func threadCPUTime() (user, system time.Duration) {
+	return 0, 0
+}
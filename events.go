@@ -0,0 +1,165 @@
+package tish
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// Event is an event that Shell emits. Use type switch to handle each event.
+type Event interface {
+	event()
+}
+
+// CommandParsed is emitted when the command line passed to Run is parsed.
+type CommandParsed struct {
+	// Command is the command line before alias expansion.
+	Command string
+	// SessionGroups are the parsed pipelines.
+	SessionGroups [][]*parser.Session
+}
+
+// ProcessStarted is emitted when a process (applet or external command) starts.
+type ProcessStarted struct {
+	Pid       int
+	ParentPid int
+	Cmd       string
+	Args      []string
+}
+
+// ProcessFinished is emitted when a process finishes.
+type ProcessFinished struct {
+	Pid       int
+	ParentPid int
+	Cmd       string
+	Args      []string
+	Result    *ExecResult
+	ExitCode  int
+	Err       error
+}
+
+// WorkingDirChanged is emitted when the working directory is changed by SetWorkingDir.
+type WorkingDirChanged struct {
+	Old string
+	New string
+}
+
+// EnvChanged is emitted when an environment variable is set or removed by SetEnv or DelEnv.
+type EnvChanged struct {
+	Key     string
+	Value   string
+	Deleted bool
+}
+
+// JobState is a state of job (pipeline).
+type JobState int
+
+const (
+	JobRunning JobState = iota
+	JobDone
+	JobInterrupted
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "running"
+	case JobDone:
+		return "done"
+	case JobInterrupted:
+		return "interrupted"
+	}
+	return "unknown"
+}
+
+// JobStateChanged is emitted when a pipeline starts and finishes.
+type JobStateChanged struct {
+	JobID    int
+	Command  string
+	State    JobState
+	ExitCode int
+}
+
+func (CommandParsed) event()     {}
+func (ProcessStarted) event()    {}
+func (ProcessFinished) event()   {}
+func (WorkingDirChanged) event() {}
+func (EnvChanged) event()        {}
+func (JobStateChanged) event()   {}
+
+// eventBus keeps subscribers and hooks. It is shared by forked shells.
+type eventBus struct {
+	lock        *sync.RWMutex
+	lastID      int
+	subscribers map[int]func(Event)
+	hooks       map[Hook][]*hookEntry
+	lastJobID   int
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		lock:        &sync.RWMutex{},
+		subscribers: map[int]func(Event){},
+		hooks:       map[Hook][]*hookEntry{},
+	}
+}
+
+// Subscribe registers the handler that receives all events of the shell and its forks.
+// It returns a function to unsubscribe.
+//
+// The handler is called synchronously in the goroutine that emits the event.
+// Processes in pipeline run in parallel, so the handler may be called from multiple goroutines at the same time.
+func (s *Shell) Subscribe(handler func(e Event)) (unsubscribe func()) {
+	b := s.events
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.lastID++
+	id := b.lastID
+	b.subscribers[id] = handler
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+func (s *Shell) emit(e Event) {
+	b := s.events
+	b.lock.RLock()
+	if len(b.subscribers) == 0 {
+		b.lock.RUnlock()
+		return
+	}
+	handlers := make([]func(Event), 0, len(b.subscribers))
+	for id := 1; id <= b.lastID; id++ {
+		if h, ok := b.subscribers[id]; ok {
+			handlers = append(handlers, h)
+		}
+	}
+	b.lock.RUnlock()
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+func (s *Shell) newJobID() int {
+	b := s.events
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.lastJobID++
+	return b.lastJobID
+}
+
+// sessionGroupString returns the command line of the pipeline.
+func sessionGroupString(sessions []*parser.Session) string {
+	var cmds []string
+	for _, ses := range sessions {
+		var words []string
+		for _, f := range ses.Fragments {
+			words = append(words, f.Term)
+		}
+		cmds = append(cmds, strings.Join(words, " "))
+	}
+	return strings.Join(cmds, " | ")
+}
@@ -0,0 +1,140 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func registerStateCommands(s *Shell) {
+	s.RegisterCommand(&Command{
+		Name: "chdir",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			if err := p.Shell.SetWorkingDir("chdir", p.Args[0], p.Stderr); err != nil {
+				result.SetInternalProcessResult(1)
+				return nil
+			}
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+	s.RegisterCommand(&Command{
+		Name: "setenv",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			p.Shell.SetEnv(p.Args[0], p.Args[1])
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+}
+
+func TestShell_Subscribe(t *testing.T) {
+	root := CreateTestFolders(t, "events", map[string]string{
+		"sub/file.txt": "",
+	})
+
+	s := NewShell(root, []string{})
+	registerStateCommands(s)
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock1.ExitCode = 2
+	registerMockCommand(t, s, "mock2")
+
+	var lock sync.Mutex
+	var events []Event
+	unsubscribe := s.Subscribe(func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	})
+
+	_, err := s.Run(context.Background(), "chdir sub ; setenv KEY value", io.Discard, io.Discard)
+	assert.NoError(t, err)
+
+	if assert.Len(t, events, 11) {
+		assert.Equal(t, "chdir sub ; setenv KEY value", events[0].(CommandParsed).Command)
+		assert.Equal(t, JobStateChanged{JobID: 1, Command: "chdir sub", State: JobRunning}, events[1])
+		started := events[2].(ProcessStarted)
+		assert.Equal(t, "chdir", started.Cmd)
+		assert.Equal(t, []string{"sub"}, started.Args)
+		assert.Equal(t, s.Pid, started.ParentPid)
+		assert.Equal(t, WorkingDirChanged{Old: root, New: filepath.Join(root, "sub")}, events[3])
+		finished := events[4].(ProcessFinished)
+		assert.Equal(t, started.Pid, finished.Pid)
+		assert.Equal(t, 0, finished.ExitCode)
+		assert.NotNil(t, finished.Result)
+		assert.Equal(t, JobStateChanged{JobID: 1, Command: "chdir sub", State: JobDone}, events[5])
+		assert.IsType(t, JobStateChanged{}, events[6])
+		assert.IsType(t, ProcessStarted{}, events[7])
+		assert.Equal(t, EnvChanged{Key: "KEY", Value: "value"}, events[8])
+		assert.IsType(t, ProcessFinished{}, events[9])
+		assert.IsType(t, JobStateChanged{}, events[10])
+	}
+
+	events = nil
+	_, err = s.Run(context.Background(), "mock1 | mock2", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	last := events[len(events)-1].(JobStateChanged)
+	assert.Equal(t, JobStateChanged{JobID: 3, Command: "mock1 | mock2", State: JobDone}, last)
+
+	unsubscribe()
+	events = nil
+	s.DelEnv("KEY")
+	assert.Empty(t, events)
+}
+
+func TestShell_Hooks(t *testing.T) {
+	root := CreateTestFolders(t, "hooks", map[string]string{
+		"sub/file.txt": "",
+	})
+
+	s := NewShell(root, []string{})
+	registerStateCommands(s)
+	echo := registerMockCommand(t, s, "say")
+	s.SetAlias("show-command", "say preexec")
+
+	var calls []string
+	s.AddHook(PreExec, func(ctx context.Context, s *Shell, args []string, stdout, stderr io.Writer) error {
+		calls = append(calls, "preexec:"+args[0])
+		return nil
+	})
+	s.AddHook(PreCmd, func(ctx context.Context, s *Shell, args []string, stdout, stderr io.Writer) error {
+		calls = append(calls, "precmd")
+		return nil
+	})
+	removeChpwd := s.AddHook(ChPwd, func(ctx context.Context, s *Shell, args []string, stdout, stderr io.Writer) error {
+		calls = append(calls, "chpwd:"+s.WorkingDir())
+		return nil
+	})
+	s.AddHookCommand(PreExec, "show-command")
+
+	var stdout bytes.Buffer
+	_, err := s.Run(context.Background(), "chdir sub", &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"preexec:chdir sub",
+		"chpwd:" + filepath.Join(root, "sub"),
+		"precmd",
+	}, calls)
+	// hook commands get arguments and don't run hooks recursively
+	assert.Equal(t, []string{"preexec", "chdir sub"}, echo.Args)
+
+	calls = nil
+	removeChpwd()
+	_, err = s.Run(context.Background(), "chdir ..", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"preexec:chdir ..", "precmd"}, calls)
+
+	assert.Equal(t, []string{"show-command"}, s.HookCommands(PreExec))
+	assert.False(t, s.RemoveHookCommand(PreCmd, "show-command"))
+	assert.True(t, s.RemoveHookCommand(PreExec, "show-command"))
+	assert.Empty(t, s.HookCommands(PreExec))
+	calls = nil
+	_, err = s.Run(context.Background(), "chdir sub", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"preexec:chdir sub", "precmd"}, calls)
+}
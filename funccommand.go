@@ -0,0 +1,184 @@
+package tish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// IO gives a Go function command access to its streams and the shell.
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	Shell   *Shell
+	Process *Process
+}
+
+// ExitError is an error with exit code. Return it (ExitError{} or &ExitError{}) from a Go function command
+// to set the exit code. If Err is nil, nothing is written to stderr.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Exit returns an error that exits the Go function command with the code silently.
+func Exit(code int) error {
+	return &ExitError{Code: code}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	ioType      = reflect.TypeOf(IO{})
+	argsType    = reflect.TypeOf([]string{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FuncCommand creates a command from a Go function like:
+//
+//	type GreetOpts struct {
+//		Name string `short:"n" long:"name" default:"world" description:"name to greet"`
+//	}
+//
+//	func(ctx context.Context, io tish.IO, opts GreetOpts, args []string) error
+//
+// Arguments are parsed into the options struct (or pointer to struct) with go-flags.
+// "--help" writes the help to stdout and invalid options exit with code 2. Flags are completed from the options.
+//
+// Returned errors are written to stderr as "name: message" and exit with code 1.
+// ExitError sets the code, interruption exits with 130 and limit errors exit with their codes.
+func FuncCommand(name string, fn interface{}) (*Command, error) {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 4 || ft.NumOut() != 1 ||
+		ft.In(0) != contextType || ft.In(1) != ioType || ft.In(3) != argsType || ft.Out(0) != errorType {
+		return nil, fmt.Errorf("%s: function should be func(context.Context, tish.IO, Options, []string) error: %s", name, ft)
+	}
+	optsType := ft.In(2)
+	isPtr := optsType.Kind() == reflect.Ptr
+	structType := optsType
+	if isPtr {
+		structType = optsType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s: options should be struct or pointer to struct: %s", name, optsType)
+	}
+	// check the struct tags at registration
+	if _, err := newFuncParser(name, reflect.New(structType).Interface()); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return &Command{
+		Name: name,
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			opts := reflect.New(structType)
+			parser, _ := newFuncParser(name, opts.Interface())
+			args, err := parser.ParseArgs(p.Args)
+			if err != nil {
+				var flagsErr *flags.Error
+				if errors.As(err, &flagsErr) && flagsErr.Type == flags.ErrHelp {
+					parser.WriteHelp(p.Stdout)
+					return result.SetInternalProcessResult(0)
+				}
+				fmt.Fprintf(p.Stderr, "%s: %v\n", name, err)
+				return result.SetInternalProcessResult(2)
+			}
+			if !isPtr {
+				opts = opts.Elem()
+			}
+			if args == nil {
+				args = []string{}
+			}
+			stdio := IO{
+				Stdin:   p.Stdin,
+				Stdout:  p.Stdout,
+				Stderr:  p.Stderr,
+				Shell:   p.Shell,
+				Process: p,
+			}
+			out := fv.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(stdio), opts, reflect.ValueOf(args)})
+			err, _ = out[0].Interface().(error)
+			return result.SetInternalProcessResult(funcExitCode(ctx, name, err, p.Stderr))
+		},
+		Options: reflect.New(structType).Interface(),
+	}, nil
+}
+
+// RegisterFunc registers a Go function command to all shells. It panics if fn is not a valid function.
+// See FuncCommand for the signature of fn.
+func RegisterFunc(name string, fn interface{}) {
+	cmd, err := FuncCommand(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	RegisterCommand(cmd)
+}
+
+// RegisterFunc registers a Go function command to the shell. See FuncCommand for the signature of fn.
+func (s *Shell) RegisterFunc(name string, fn interface{}) error {
+	cmd, err := FuncCommand(name, fn)
+	if err != nil {
+		return err
+	}
+	s.RegisterCommand(cmd)
+	return nil
+}
+
+func newFuncParser(name string, data interface{}) (*flags.Parser, error) {
+	parser := flags.NewNamedParser(name, flags.HelpFlag|flags.PassDoubleDash)
+	if _, err := parser.AddGroup("Options", "", data); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// funcExitCode maps the returned error to exit code and reports it.
+// A deadline like Option.CommandTimeout is ExitCodeTimeout and cancellation is 130 like Ctrl-C.
+func funcExitCode(ctx context.Context, name string, err error, stderr io.Writer) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := asExitError(err); ok {
+		if exitErr.Err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, exitErr.Err)
+		}
+		return exitErr.Code
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return ExitCodeTimeout
+	}
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return exitCode(nil, interrupted(ctx))
+	}
+	fmt.Fprintf(stderr, "%s: %v\n", name, err)
+	return exitCode(nil, err)
+}
+
+// asExitError finds ExitError in the error chain. Both value and pointer are accepted.
+func asExitError(err error) (*ExitError, bool) {
+	var ptr *ExitError
+	if errors.As(err, &ptr) {
+		return ptr, true
+	}
+	var value ExitError
+	if errors.As(err, &value) {
+		return &value, true
+	}
+	return nil, false
+}
@@ -0,0 +1,182 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type greetOpts struct {
+	Name  string `short:"n" long:"name" default:"world" description:"name to greet"`
+	Upper bool   `short:"u" long:"upper" description:"greet loudly"`
+}
+
+func greet(ctx context.Context, stdio IO, opts greetOpts, args []string) error {
+	msg := fmt.Sprintf("hello %s %s", opts.Name, strings.Join(args, " "))
+	if opts.Upper {
+		msg = strings.ToUpper(msg)
+	}
+	fmt.Fprintln(stdio.Stdout, msg)
+	return nil
+}
+
+func TestShell_RegisterFunc(t *testing.T) {
+	root := CreateTestFolders(t, "func-command")
+
+	s := NewShell(root, []string{"GREETING=hi"})
+	assert.NoError(t, s.RegisterFunc("greet", greet))
+	assert.NoError(t, s.RegisterFunc("upper", func(ctx context.Context, stdio IO, opts *struct{}, args []string) error {
+		input, err := ioutil.ReadAll(stdio.Stdin)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdio.Stdout, strings.ToUpper(string(input))+stdio.Shell.Getenv("GREETING"))
+		return err
+	}))
+	assert.NoError(t, s.RegisterFunc("fail", func(ctx context.Context, stdio IO, opts struct{}, args []string) error {
+		switch args[0] {
+		case "error":
+			return errors.New("something wrong")
+		case "exit":
+			return Exit(3)
+		case "exit-message":
+			return &ExitError{Code: 4, Err: errors.New("bad input")}
+		case "exit-value":
+			return fmt.Errorf("wrapped: %w", ExitError{Code: 5})
+		}
+		return nil
+	}))
+
+	tests := []struct {
+		name       string
+		cmd        string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "default option",
+			cmd:        "greet",
+			wantStdout: "hello world \n",
+		},
+		{
+			name:       "options and args",
+			cmd:        "greet -u --name tish a b",
+			wantStdout: "HELLO TISH A B\n",
+		},
+		{
+			name:       "pointer options and stdin",
+			cmd:        "greet | upper",
+			wantStdout: "HELLO WORLD \nhi",
+		},
+		{
+			name:       "invalid option",
+			cmd:        "greet --unknown",
+			wantCode:   2,
+			wantStderr: "greet: unknown flag `unknown'\n",
+		},
+		{
+			name:       "error",
+			cmd:        "fail error",
+			wantCode:   1,
+			wantStderr: "fail: something wrong\n",
+		},
+		{
+			name:     "exit code",
+			cmd:      "fail exit",
+			wantCode: 3,
+		},
+		{
+			name:       "exit code with message",
+			cmd:        "fail exit-message",
+			wantCode:   4,
+			wantStderr: "fail: bad input\n",
+		},
+		{
+			name:     "exit code by value",
+			cmd:      "fail exit-value",
+			wantCode: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code, err := s.Run(context.Background(), tt.cmd, &stdout, &stderr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+}
+
+func TestFuncCommand_Help(t *testing.T) {
+	root := CreateTestFolders(t, "func-command-help")
+
+	s := NewShell(root, []string{})
+	assert.NoError(t, s.RegisterFunc("greet", greet))
+
+	var stdout bytes.Buffer
+	code, err := s.Run(context.Background(), "greet --help", &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Usage:\n  greet")
+	assert.Contains(t, stdout.String(), "name to greet")
+
+	cmd, err := FuncCommand("greet", greet)
+	assert.NoError(t, err)
+	assert.Equal(t, []Flag{
+		{Short: "n", Long: "name", Description: "name to greet", HasArg: true},
+		{Short: "u", Long: "upper", Description: "greet loudly"},
+		{Short: "h", Long: "help", Description: "Show this help message"},
+	}, cmd.Flags())
+}
+
+func TestFuncCommand_InvalidFunction(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   interface{}
+	}{
+		{name: "not function", fn: "greet"},
+		{name: "no options", fn: func(ctx context.Context, stdio IO, args []string) error { return nil }},
+		{name: "options is not struct", fn: func(ctx context.Context, stdio IO, opts int, args []string) error { return nil }},
+		{name: "no error", fn: func(ctx context.Context, stdio IO, opts struct{}, args []string) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FuncCommand("invalid", tt.fn)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFuncCommand_ExitCodeOfContext(t *testing.T) {
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), 0)
+	defer cancelDeadline()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want int
+	}{
+		{name: "deadline", ctx: deadline, err: deadline.Err(), want: 124},
+		{name: "deadline error", ctx: context.Background(), err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), want: 124},
+		{name: "canceled", ctx: canceled, err: canceled.Err(), want: 130},
+		{name: "interrupted", ctx: context.Background(), err: ErrInterrupted, want: 130},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, funcExitCode(tt.ctx, "cmd", tt.err, io.Discard))
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package gitstatus
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileSig is the stat info to detect changes of a file or directory.
+type fileSig struct {
+	exists bool
+	size   int64
+	mtime  time.Time
+	mode   os.FileMode
+}
+
+func newFileSig(stat os.FileInfo) fileSig {
+	return fileSig{exists: true, size: stat.Size(), mtime: stat.ModTime(), mode: stat.Mode()}
+}
+
+// statSig returns the fileSig of the path. A missing file has the zero value.
+func statSig(path string) fileSig {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fileSig{}
+	}
+	return newFileSig(stat)
+}
+
+// unchanged returns true if all files have the same stat info as recorded.
+func unchanged(files map[string]fileSig) bool {
+	if files == nil {
+		return false
+	}
+	for path, sig := range files {
+		if statSig(path) != sig {
+			return false
+		}
+	}
+	return true
+}
+
+// hashedFile is the object name of a file in the working tree.
+type hashedFile struct {
+	sig  fileSig
+	hash hash
+}
+
+// cacheEntry is the last status of a working tree and files that were read to get it.
+type cacheEntry struct {
+	status Status
+	// refs has HEAD, config, packed-refs and refs of the branch and the upstream.
+	refs     map[string]fileSig
+	head     hash
+	indexSig fileSig
+	index    []indexEntry
+	staged   bool
+	hashes   map[string]hashedFile
+	// watch has directories and ignore files that were read to search untracked files.
+	watch     map[string]fileSig
+	untracked bool
+}
+
+// Cache keeps the status of working trees and reads files only when they are changed.
+//
+// Branch and ahead/behind are recomputed when refs are changed, staged files are recomputed when the index is changed,
+// and untracked files are searched again when the index, directories or ignore files are changed.
+// Files in the working tree are checked every time, but only files whose stat info doesn't match the index are hashed.
+type Cache struct {
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// NewCache creates a Cache.
+func NewCache() *Cache {
+	return &Cache{
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+var defaultCache = NewCache()
+
+// Cached reads the status with the cache shared in the process.
+func Cached(dir string) (*Status, error) {
+	return defaultCache.Read(dir)
+}
+
+// Read reads the status of the repository that has the directory. Results are cached by the top of working tree.
+func (c *Cache) Read(dir string) (*Status, error) {
+	r, err := findRepository(dir)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[r.workTree]
+	if !ok {
+		entry = &cacheEntry{}
+	}
+	if err := entry.update(r); err != nil {
+		delete(c.entries, r.workTree)
+		return nil, err
+	}
+	c.entries[r.workTree] = entry
+	status := entry.status
+	return &status, nil
+}
+
+func (e *cacheEntry) update(r *repository) error {
+	var objects *objectStore
+	defer func() {
+		if objects != nil {
+			objects.close()
+		}
+	}()
+	refsChanged := !unchanged(e.refs)
+	if refsChanged {
+		var status Status
+		head, branchRef, upstreamRef, err := readHead(r, &status)
+		if err != nil {
+			return err
+		}
+		if upstreamRef != "" {
+			upstream, err := r.resolveRef(upstreamRef)
+			if err == nil {
+				objects = newObjectStore(r.commonDir)
+				if status.Ahead, status.Behind, err = objects.aheadBehind(head, upstream); err != nil {
+					return err
+				}
+			} else {
+				// the remote branch is not fetched yet
+				status.Upstream = ""
+			}
+		}
+		e.status = status
+		e.head = head
+		e.refs = make(map[string]fileSig)
+		for _, path := range []string{
+			filepath.Join(r.gitDir, "HEAD"),
+			filepath.Join(r.commonDir, "config"),
+			filepath.Join(r.commonDir, "packed-refs"),
+		} {
+			e.refs[path] = statSig(path)
+		}
+		for _, ref := range []string{branchRef, upstreamRef} {
+			if ref != "" {
+				e.refs[r.refPath(ref)] = statSig(r.refPath(ref))
+			}
+		}
+	}
+
+	indexPath := filepath.Join(r.gitDir, "index")
+	indexSig := statSig(indexPath)
+	indexChanged := e.index == nil || indexSig != e.indexSig
+	if indexChanged {
+		index, err := readIndex(indexPath)
+		if err != nil {
+			return err
+		}
+		if index == nil {
+			index = []indexEntry{}
+		}
+		e.index = index
+		e.indexSig = indexSig
+		e.hashes = make(map[string]hashedFile)
+	}
+	if refsChanged || indexChanged {
+		if objects == nil {
+			objects = newObjectStore(r.commonDir)
+		}
+		isStaged, err := staged(objects, e.head, e.index)
+		if err != nil {
+			return err
+		}
+		e.staged = isStaged
+	}
+	e.status.Staged = e.staged
+
+	e.status.Dirty = dirty(r.workTree, e.index, e.indexSig, e.hashes)
+
+	if indexChanged || !unchanged(e.watch) {
+		e.watch = make(map[string]fileSig)
+		e.untracked = untracked(r, e.index, e.watch)
+	}
+	e.status.Untracked = e.untracked
+	return nil
+}
@@ -0,0 +1,161 @@
+package gitstatus
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a pattern of gitignore.
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored patterns match the path from the directory of .gitignore. Others match the base name.
+	anchored bool
+}
+
+// ignoreList is rules of a .gitignore file. base is the slash separated directory of the file ("" for the top).
+type ignoreList struct {
+	base  string
+	rules []ignoreRule
+}
+
+// parseIgnore parses the content of .gitignore.
+func parseIgnore(base string, content []byte) *ignoreList {
+	list := &ignoreList{base: base}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			list.rules = append(list.rules, rule)
+		}
+	}
+	return list
+}
+
+func readIgnoreFile(base, path string) *ignoreList {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parseIgnore(base, content)
+}
+
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// trailing spaces are ignored unless they are escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	pattern, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// globToRegexp converts gitignore glob to regular expression.
+// "*" and "?" don't match "/". "**/" matches zero or more directories and "/**" matches everything inside.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**":
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// match returns whether the path (slash separated from the top of the working tree) is ignored by the list.
+// decided is false if no rules match.
+func (l *ignoreList) match(path string, isDir bool) (ignored, decided bool) {
+	rel := path
+	if l.base != "" {
+		if !strings.HasPrefix(path, l.base+"/") {
+			return false, false
+		}
+		rel = path[len(l.base)+1:]
+	}
+	name := rel
+	if i := strings.LastIndexByte(rel, '/'); i != -1 {
+		name = rel[i+1:]
+	}
+	// the last matching rule wins
+	for i := len(l.rules) - 1; i >= 0; i-- {
+		r := l.rules[i]
+		if r.dirOnly && !isDir {
+			continue
+		}
+		target := name
+		if r.anchored {
+			target = rel
+		}
+		if r.pattern.MatchString(target) {
+			return !r.negate, true
+		}
+	}
+	return false, false
+}
+
+// ignoreMatcher is a stack of ignore lists. Lists pushed later (deeper directories) take precedence.
+type ignoreMatcher []*ignoreList
+
+func (m ignoreMatcher) ignored(path string, isDir bool) bool {
+	for i := len(m) - 1; i >= 0; i-- {
+		if ignored, decided := m[i].match(path, isDir); decided {
+			return ignored
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package gitstatus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreList_match(t *testing.T) {
+	list := parseIgnore("", []byte(`# comment
+*.log
+!keep.log
+/build
+tmp/
+doc/**/*.pdf
+**/cache
+file?.txt
+[abc].md
+\#hash
+`))
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"error.log", false, true},
+		{"sub/error.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"sub/build", true, false},
+		{"tmp", true, true},
+		{"sub/tmp", true, true},
+		{"tmp", false, false},
+		{"doc/a.pdf", false, true},
+		{"doc/x/y/a.pdf", false, true},
+		{"other/a.pdf", false, false},
+		{"cache", true, true},
+		{"a/b/cache", false, true},
+		{"file1.txt", false, true},
+		{"file10.txt", false, false},
+		{"a.md", false, true},
+		{"d.md", false, false},
+		{"#hash", false, true},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ignored, _ := list.match(tt.path, tt.isDir)
+			assert.Equal(t, tt.ignored, ignored)
+		})
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	matcher := ignoreMatcher{
+		parseIgnore("", []byte("*.txt\n")),
+		parseIgnore("sub", []byte("!readme.txt\n/local\n")),
+	}
+	assert.True(t, matcher.ignored("a.txt", false))
+	assert.True(t, matcher.ignored("sub/a.txt", false))
+	assert.False(t, matcher.ignored("sub/readme.txt", false))
+	assert.True(t, matcher.ignored("readme.txt", false))
+	assert.True(t, matcher.ignored("sub/local", true))
+	assert.False(t, matcher.ignored("local", true))
+}
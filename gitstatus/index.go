@@ -0,0 +1,121 @@
+package gitstatus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// indexEntry is a file in the index (staging area).
+type indexEntry struct {
+	path  string
+	mode  uint32
+	hash  hash
+	size  uint32
+	mtime int64
+	// mtimeNano is nanoseconds of mtime
+	mtimeNano uint32
+	// stage is not zero for conflicted files
+	stage int
+	// skipWorktree and intentToAdd are extended flags
+	skipWorktree bool
+	intentToAdd  bool
+}
+
+// readIndex reads the index file (version 2, 3 and 4). A missing file is an empty index.
+func readIndex(path string) ([]indexEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	errBroken := fmt.Errorf("%s: broken index", path)
+	if len(data) < 12 || !bytes.Equal(data[:4], []byte("DIRC")) {
+		return nil, errBroken
+	}
+	version := binary.BigEndian.Uint32(data[4:])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("%s: unsupported index version %d", path, version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:]))
+	entries := make([]indexEntry, 0, count)
+	pos := 12
+	var lastPath []byte
+	for i := 0; i < count; i++ {
+		start := pos
+		if len(data) < pos+62 {
+			return nil, errBroken
+		}
+		e := indexEntry{
+			mtime:     int64(binary.BigEndian.Uint32(data[pos+8:])),
+			mtimeNano: binary.BigEndian.Uint32(data[pos+12:]),
+			mode:      binary.BigEndian.Uint32(data[pos+24:]),
+			size:      binary.BigEndian.Uint32(data[pos+36:]),
+		}
+		copy(e.hash[:], data[pos+40:pos+60])
+		flags := binary.BigEndian.Uint16(data[pos+60:])
+		e.stage = int(flags>>12) & 3
+		pos += 62
+		if flags&0x4000 != 0 {
+			if version < 3 || len(data) < pos+2 {
+				return nil, errBroken
+			}
+			extended := binary.BigEndian.Uint16(data[pos:])
+			e.skipWorktree = extended&0x4000 != 0
+			e.intentToAdd = extended&0x2000 != 0
+			pos += 2
+		}
+		if version == 4 {
+			// the path is compressed: the length to remove from the previous path and the suffix
+			strip, n := offsetVarint(data[pos:])
+			if n <= 0 || strip > len(lastPath) {
+				return nil, errBroken
+			}
+			pos += n
+			end := bytes.IndexByte(data[pos:], 0)
+			if end == -1 {
+				return nil, errBroken
+			}
+			name := append(append([]byte{}, lastPath[:len(lastPath)-strip]...), data[pos:pos+end]...)
+			pos += end + 1
+			e.path = string(name)
+			lastPath = name
+		} else {
+			end := bytes.IndexByte(data[pos:], 0)
+			if end == -1 {
+				return nil, errBroken
+			}
+			e.path = string(data[pos : pos+end])
+			// entries are padded with 1-8 NUL bytes to a multiple of 8 bytes
+			pos = start + (pos+end-start+8)&^7
+		}
+		entries = append(entries, e)
+	}
+	if pos > len(data) {
+		return nil, errBroken
+	}
+	return entries, nil
+}
+
+// offsetVarint decodes the variable length integer used by index version 4 and ofs-delta.
+// It returns the value and the number of bytes read (0 if the data is too short).
+func offsetVarint(data []byte) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	c := data[0]
+	n := int(c & 0x7f)
+	i := 1
+	for c&0x80 != 0 {
+		if i >= len(data) {
+			return 0, 0
+		}
+		c = data[i]
+		i++
+		n = (n+1)<<7 | int(c&0x7f)
+	}
+	return n, i
+}
@@ -0,0 +1,460 @@
+package gitstatus
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hash is SHA-1 object name.
+type hash [20]byte
+
+func parseHash(s string) (hash, error) {
+	var h hash
+	if len(s) != 40 {
+		return h, fmt.Errorf("invalid object name: %s", s)
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, fmt.Errorf("invalid object name: %s", s)
+	}
+	return h, nil
+}
+
+func (h hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// blobHash returns the object name of the blob that has the content.
+func blobHash(content []byte) hash {
+	d := sha1.New()
+	fmt.Fprintf(d, "blob %d\x00", len(content))
+	d.Write(content)
+	var h hash
+	copy(h[:], d.Sum(nil))
+	return h
+}
+
+type objectType int
+
+const (
+	commitObject   objectType = 1
+	treeObject     objectType = 2
+	blobObject     objectType = 3
+	tagObject      objectType = 4
+	ofsDeltaObject objectType = 6
+	refDeltaObject objectType = 7
+)
+
+var objectTypeNames = map[string]objectType{
+	"commit": commitObject,
+	"tree":   treeObject,
+	"blob":   blobObject,
+	"tag":    tagObject,
+}
+
+var errObjectNotFound = errors.New("object not found")
+
+// objectStore reads loose objects and pack files. Call close after use.
+type objectStore struct {
+	dir   string
+	packs []*pack
+	// loaded is true after pack indexes are loaded
+	loaded bool
+}
+
+type object struct {
+	typ  objectType
+	data []byte
+}
+
+func newObjectStore(commonDir string) *objectStore {
+	return &objectStore{
+		dir: filepath.Join(commonDir, "objects"),
+	}
+}
+
+func (o *objectStore) close() {
+	for _, p := range o.packs {
+		p.file.Close()
+	}
+	o.packs = nil
+	o.loaded = false
+}
+
+// read returns the type and content of the object.
+func (o *objectStore) read(h hash) (objectType, []byte, error) {
+	s := h.String()
+	if compressed, err := os.Open(filepath.Join(o.dir, s[:2], s[2:])); err == nil {
+		defer compressed.Close()
+		return readLooseObject(compressed)
+	}
+	if err := o.loadPacks(); err != nil {
+		return 0, nil, err
+	}
+	for _, p := range o.packs {
+		if offset, ok := p.find(h); ok {
+			obj, err := p.readAt(offset, o)
+			if err != nil {
+				return 0, nil, err
+			}
+			return obj.typ, obj.data, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("%w: %s", errObjectNotFound, s)
+}
+
+func readLooseObject(r io.Reader) (objectType, []byte, error) {
+	z, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer z.Close()
+	content, err := ioutil.ReadAll(z)
+	if err != nil {
+		return 0, nil, err
+	}
+	i := bytes.IndexByte(content, 0)
+	if i == -1 {
+		return 0, nil, errors.New("invalid loose object")
+	}
+	header := strings.SplitN(string(content[:i]), " ", 2)
+	typ, ok := objectTypeNames[header[0]]
+	if !ok || len(header) != 2 {
+		return 0, nil, errors.New("invalid loose object")
+	}
+	size, err := strconv.Atoi(header[1])
+	if err != nil || size != len(content)-i-1 {
+		return 0, nil, errors.New("invalid loose object")
+	}
+	return typ, content[i+1:], nil
+}
+
+func (o *objectStore) loadPacks() error {
+	if o.loaded {
+		return nil
+	}
+	o.loaded = true
+	indexes, err := filepath.Glob(filepath.Join(o.dir, "pack", "*.idx"))
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		p, err := openPack(idx)
+		if err != nil {
+			return err
+		}
+		o.packs = append(o.packs, p)
+	}
+	return nil
+}
+
+// pack is a pack file and its index (version 2).
+type pack struct {
+	file    *os.File
+	names   []byte
+	offsets []byte
+	large   []byte
+	count   int
+	fanout  [256]uint32
+	// cache keeps recently used base objects of deltas
+	cache map[int64]object
+}
+
+func openPack(idxPath string) (*pack, error) {
+	idx, err := ioutil.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:]) != 2 {
+		return nil, fmt.Errorf("%s: unsupported pack index", idxPath)
+	}
+	p := &pack{
+		cache: map[int64]object{},
+	}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+	p.count = int(p.fanout[255])
+	pos := 8 + 256*4
+	if len(idx) < pos+p.count*(20+4+4) {
+		return nil, fmt.Errorf("%s: broken pack index", idxPath)
+	}
+	p.names = idx[pos : pos+p.count*20]
+	pos += p.count * 20
+	// skip CRC32
+	pos += p.count * 4
+	p.offsets = idx[pos : pos+p.count*4]
+	p.large = idx[pos+p.count*4:]
+	p.file, err = os.Open(strings.TrimSuffix(idxPath, ".idx") + ".pack")
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// find returns the offset of the object in the pack file.
+func (p *pack) find(h hash) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(p.fanout[h[0]-1])
+	}
+	hi := int(p.fanout[h[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.names[(lo+i)*20:(lo+i+1)*20], h[:]) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.names[i*20:(i+1)*20], h[:]) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	j := int(offset & 0x7fffffff)
+	if len(p.large) < (j+1)*8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[j*8:])), true
+}
+
+// readAt reads the object at the offset and resolves deltas.
+func (p *pack) readAt(offset int64, store *objectStore) (object, error) {
+	if obj, ok := p.cache[offset]; ok {
+		return obj, nil
+	}
+	r := bufio.NewReader(io.NewSectionReader(p.file, offset, 1<<62))
+	c, err := r.ReadByte()
+	if err != nil {
+		return object{}, err
+	}
+	typ := objectType(c >> 4 & 7)
+	size := int64(c & 15)
+	for shift := uint(4); c&0x80 != 0; shift += 7 {
+		if c, err = r.ReadByte(); err != nil {
+			return object{}, err
+		}
+		size |= int64(c&0x7f) << shift
+	}
+
+	var base object
+	switch typ {
+	case ofsDeltaObject:
+		c, err := r.ReadByte()
+		if err != nil {
+			return object{}, err
+		}
+		distance := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = r.ReadByte(); err != nil {
+				return object{}, err
+			}
+			distance = (distance+1)<<7 | int64(c&0x7f)
+		}
+		if base, err = p.readAt(offset-distance, store); err != nil {
+			return object{}, err
+		}
+	case refDeltaObject:
+		var h hash
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return object{}, err
+		}
+		t, data, err := store.read(h)
+		if err != nil {
+			return object{}, err
+		}
+		base = object{typ: t, data: data}
+	}
+
+	z, err := zlib.NewReader(r)
+	if err != nil {
+		return object{}, err
+	}
+	defer z.Close()
+	data := make([]byte, size)
+	if _, err := io.ReadFull(z, data); err != nil {
+		return object{}, err
+	}
+	obj := object{typ: typ, data: data}
+	if typ == ofsDeltaObject || typ == refDeltaObject {
+		data, err := applyDelta(base.data, data)
+		if err != nil {
+			return object{}, err
+		}
+		obj = object{typ: base.typ, data: data}
+	}
+	if len(p.cache) >= 256 {
+		p.cache = map[int64]object{}
+	}
+	p.cache[offset] = obj
+	return obj, nil
+}
+
+// applyDelta creates the object from the base object and git's delta instructions.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	errBroken := errors.New("broken delta")
+	pos := 0
+	varint := func() int {
+		n, shift := 0, uint(0)
+		for pos < len(delta) {
+			c := delta[pos]
+			pos++
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		return n
+	}
+	if varint() != len(base) {
+		return nil, errBroken
+	}
+	result := make([]byte, 0, varint())
+	for pos < len(delta) {
+		c := delta[pos]
+		pos++
+		switch {
+		case c&0x80 != 0:
+			var offset, size int
+			for i := uint(0); i < 7; i++ {
+				if c&(1<<i) == 0 {
+					continue
+				}
+				if pos >= len(delta) {
+					return nil, errBroken
+				}
+				if i < 4 {
+					offset |= int(delta[pos]) << (8 * i)
+				} else {
+					size |= int(delta[pos]) << (8 * (i - 4))
+				}
+				pos++
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errBroken
+			}
+			result = append(result, base[offset:offset+size]...)
+		case c != 0:
+			if pos+int(c) > len(delta) {
+				return nil, errBroken
+			}
+			result = append(result, delta[pos:pos+int(c)]...)
+			pos += int(c)
+		default:
+			return nil, errBroken
+		}
+	}
+	if len(result) != cap(result) {
+		return nil, errBroken
+	}
+	return result, nil
+}
+
+// commit is the parsed commit object.
+type commit struct {
+	tree    hash
+	parents []hash
+	time    int64
+}
+
+func (o *objectStore) readCommit(h hash) (*commit, error) {
+	typ, data, err := o.read(h)
+	if err != nil {
+		return nil, err
+	}
+	// annotated tags point to commits
+	for typ == tagObject {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			line = data[:i]
+		}
+		target, err := parseHash(strings.TrimPrefix(string(line), "object "))
+		if err != nil {
+			return nil, err
+		}
+		if typ, data, err = o.read(target); err != nil {
+			return nil, err
+		}
+	}
+	if typ != commitObject {
+		return nil, fmt.Errorf("%s is not a commit", h)
+	}
+	c := &commit{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "tree "):
+			c.tree, err = parseHash(line[5:])
+		case strings.HasPrefix(line, "parent "):
+			var parent hash
+			parent, err = parseHash(line[7:])
+			c.parents = append(c.parents, parent)
+		case strings.HasPrefix(line, "committer "):
+			// committer Name <email> 1234567890 +0900
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				c.time, _ = strconv.ParseInt(fields[len(fields)-2], 10, 64)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// treeEntry is a file in the flattened tree.
+type treeEntry struct {
+	mode uint32
+	hash hash
+}
+
+// readTree returns all files in the tree and its subtrees. Keys are slash separated paths.
+func (o *objectStore) readTree(h hash, prefix string, result map[string]treeEntry) error {
+	typ, data, err := o.read(h)
+	if err != nil {
+		return err
+	}
+	if typ != treeObject {
+		return fmt.Errorf("%s is not a tree", h)
+	}
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp == -1 || nul < sp || len(data) < nul+21 {
+			return fmt.Errorf("broken tree: %s", h)
+		}
+		mode, err := strconv.ParseUint(string(data[:sp]), 8, 32)
+		if err != nil {
+			return err
+		}
+		name := prefix + string(data[sp+1:nul])
+		var entry hash
+		copy(entry[:], data[nul+1:nul+21])
+		data = data[nul+21:]
+		if mode == 0o40000 {
+			if err := o.readTree(entry, name+"/", result); err != nil {
+				return err
+			}
+		} else {
+			result[name] = treeEntry{mode: uint32(mode), hash: entry}
+		}
+	}
+	return nil
+}
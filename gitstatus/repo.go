@@ -0,0 +1,195 @@
+// Package gitstatus reads the status of git repositories (branch, ahead/behind and changes)
+// directly from the .git directory without running git.
+package gitstatus
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned when the directory is not in a git repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// repository is the location of a git repository.
+type repository struct {
+	// workTree is the top directory of the working tree.
+	workTree string
+	// gitDir has HEAD and index of the working tree.
+	gitDir string
+	// commonDir has objects, refs and config. It is as same as gitDir except linked worktrees.
+	commonDir string
+}
+
+// findRepository finds the repository that has the directory.
+func findRepository(dir string) (*repository, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if stat, err := os.Stat(dotGit); err == nil {
+			if stat.IsDir() {
+				return newRepository(dir, dotGit)
+			}
+			// linked worktrees and submodules have "gitdir: path" file
+			content, err := ioutil.ReadFile(dotGit)
+			if err != nil {
+				return nil, err
+			}
+			line := strings.TrimSpace(string(content))
+			if !strings.HasPrefix(line, "gitdir:") {
+				return nil, ErrNotRepository
+			}
+			gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return newRepository(dir, gitDir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotRepository
+		}
+		dir = parent
+	}
+}
+
+func newRepository(workTree, gitDir string) (*repository, error) {
+	r := &repository{
+		workTree:  workTree,
+		gitDir:    gitDir,
+		commonDir: gitDir,
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(content))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		r.commonDir = filepath.Clean(commonDir)
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "HEAD")); err != nil {
+		return nil, ErrNotRepository
+	}
+	return r, nil
+}
+
+// refPath returns the path of the loose ref. HEAD and other pseudo refs are in gitDir.
+func (r *repository) refPath(name string) string {
+	if strings.HasPrefix(name, "refs/") {
+		return filepath.Join(r.commonDir, filepath.FromSlash(name))
+	}
+	return filepath.Join(r.gitDir, name)
+}
+
+// readRef returns the content of the ref: "ref: refs/heads/main" for symbolic refs or the object name.
+func (r *repository) readRef(name string) (string, error) {
+	content, err := ioutil.ReadFile(r.refPath(name))
+	if err == nil {
+		return strings.TrimSpace(string(content)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	hash, err := r.packedRef(name)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		return "", os.ErrNotExist
+	}
+	return hash, nil
+}
+
+// packedRef finds the ref in packed-refs. It returns an empty string if it is not found.
+func (r *repository) packedRef(name string) (string, error) {
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		if i := strings.IndexByte(line, ' '); i != -1 && line[i+1:] == name {
+			return line[:i], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// resolveRef follows symbolic refs and returns the object name.
+func (r *repository) resolveRef(name string) (hash, error) {
+	for i := 0; i < 10; i++ {
+		value, err := r.readRef(name)
+		if err != nil {
+			return hash{}, err
+		}
+		if !strings.HasPrefix(value, "ref:") {
+			return parseHash(value)
+		}
+		name = strings.TrimSpace(strings.TrimPrefix(value, "ref:"))
+	}
+	return hash{}, errors.New("too deep symbolic ref")
+}
+
+// upstream returns the upstream ref of the branch from [branch "name"] section of the config.
+// It returns an empty string if the branch doesn't have upstream.
+func (r *repository) upstream(branch string) string {
+	content, err := ioutil.ReadFile(filepath.Join(r.commonDir, "config"))
+	if err != nil {
+		return ""
+	}
+	values := configSection(content, `branch "`+branch+`"`)
+	remote, merge := values["remote"], values["merge"]
+	if remote == "" || !strings.HasPrefix(merge, "refs/heads/") {
+		return ""
+	}
+	if remote == "." {
+		return merge
+	}
+	return "refs/remotes/" + remote + "/" + strings.TrimPrefix(merge, "refs/heads/")
+}
+
+// configSection returns key-values of the section in git config. Keys are lower case.
+func configSection(content []byte, section string) map[string]string {
+	result := map[string]string{}
+	inSection := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			name := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			// section names are case insensitive, but subsection names are not
+			if i := strings.IndexByte(name, ' '); i != -1 {
+				name = strings.ToLower(name[:i]) + name[i:]
+			} else {
+				name = strings.ToLower(name)
+			}
+			inSection = name == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		key, value := line, "true"
+		if i := strings.IndexByte(line, '='); i != -1 {
+			key, value = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+		result[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return result
+}
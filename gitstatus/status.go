@@ -0,0 +1,301 @@
+package gitstatus
+
+import (
+	"container/heap"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Status is the status of the working tree.
+type Status struct {
+	// Branch is the current branch. It is the abbreviated commit name if HEAD is detached.
+	Branch   string
+	Detached bool
+	// Upstream is the upstream branch like "origin/main". It is empty if the branch doesn't track any branch.
+	Upstream string
+	// Ahead and Behind are the numbers of commits that are only in the branch or only in the upstream.
+	Ahead  int
+	Behind int
+	// Staged is true if the index differs from HEAD.
+	Staged bool
+	// Dirty is true if tracked files in the working tree differ from the index.
+	Dirty bool
+	// Untracked is true if the working tree has files that are neither tracked nor ignored.
+	Untracked bool
+}
+
+// Read reads the status of the repository that has the directory.
+// It returns ErrNotRepository if the directory is not in a git repository.
+func Read(dir string) (*Status, error) {
+	return NewCache().Read(dir)
+}
+
+// readHead reads HEAD and the upstream. head is zero if the branch doesn't have any commits yet.
+func readHead(r *repository, status *Status) (head hash, branchRef, upstreamRef string, err error) {
+	value, err := r.readRef("HEAD")
+	if err != nil {
+		return hash{}, "", "", err
+	}
+	if !strings.HasPrefix(value, "ref:") {
+		head, err = parseHash(value)
+		if err != nil {
+			return hash{}, "", "", err
+		}
+		status.Detached = true
+		status.Branch = head.String()[:7]
+		return head, "", "", nil
+	}
+	branchRef = strings.TrimSpace(strings.TrimPrefix(value, "ref:"))
+	status.Branch = strings.TrimPrefix(branchRef, "refs/heads/")
+	head, err = r.resolveRef(branchRef)
+	if errors.Is(err, os.ErrNotExist) {
+		// unborn branch just after git init
+		return hash{}, branchRef, "", nil
+	} else if err != nil {
+		return hash{}, "", "", err
+	}
+	upstreamRef = r.upstream(status.Branch)
+	if upstreamRef != "" {
+		status.Upstream = strings.TrimPrefix(strings.TrimPrefix(upstreamRef, "refs/remotes/"), "refs/heads/")
+	}
+	return head, branchRef, upstreamRef, nil
+}
+
+// aheadBehind counts commits that are reachable from only one of local and upstream.
+//
+// It walks the history from both commits in the commit date order and paints each commit by where it is reachable from.
+// The walk stops when all commits in the queue are reachable from both, because their ancestors are common.
+// Like git, it can miscount when commit dates are skewed.
+func (o *objectStore) aheadBehind(local, upstream hash) (ahead, behind int, err error) {
+	const (
+		fromLocal    = 1
+		fromUpstream = 2
+		fromBoth     = fromLocal | fromUpstream
+	)
+	flags := map[hash]int{}
+	queue := &commitQueue{}
+	push := func(h hash, flag int) error {
+		if flags[h]&flag == flag {
+			return nil
+		}
+		flags[h] |= flag
+		c, err := o.readCommit(h)
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, queuedCommit{hash: h, commit: c})
+		return nil
+	}
+	if err := push(local, fromLocal); err != nil {
+		return 0, 0, err
+	}
+	if err := push(upstream, fromUpstream); err != nil {
+		return 0, 0, err
+	}
+	for queue.Len() > 0 {
+		stale := true
+		for _, q := range *queue {
+			if flags[q.hash] != fromBoth {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			break
+		}
+		q := heap.Pop(queue).(queuedCommit)
+		for _, parent := range q.commit.parents {
+			if err := push(parent, flags[q.hash]); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	for _, flag := range flags {
+		switch flag {
+		case fromLocal:
+			ahead++
+		case fromUpstream:
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+type queuedCommit struct {
+	hash   hash
+	commit *commit
+}
+
+// commitQueue is a priority queue that pops the newest commit first.
+type commitQueue []queuedCommit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].commit.time > q[j].commit.time }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(queuedCommit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// staged compares the index with the tree of HEAD.
+func staged(o *objectStore, head hash, index []indexEntry) (bool, error) {
+	tree := map[string]treeEntry{}
+	if head != (hash{}) {
+		c, err := o.readCommit(head)
+		if err != nil {
+			return false, err
+		}
+		if err := o.readTree(c.tree, "", tree); err != nil {
+			return false, err
+		}
+	}
+	count := 0
+	for _, e := range index {
+		if e.stage != 0 {
+			// conflicted
+			return true, nil
+		}
+		if e.intentToAdd {
+			continue
+		}
+		t, ok := tree[e.path]
+		if !ok || t.hash != e.hash || t.mode != e.mode {
+			return true, nil
+		}
+		count++
+	}
+	return count != len(tree), nil
+}
+
+const (
+	modeSymlink = 0o120000
+	modeGitlink = 0o160000
+)
+
+// dirty compares files in the working tree with the index.
+// Files whose stat info doesn't match the index are hashed and the hashes are kept in hashes.
+func dirty(workTree string, index []indexEntry, indexStat fileSig, hashes map[string]hashedFile) bool {
+	for _, e := range index {
+		if e.stage != 0 || e.intentToAdd {
+			return true
+		}
+		if e.skipWorktree || e.mode == modeGitlink {
+			continue
+		}
+		path := filepath.Join(workTree, filepath.FromSlash(e.path))
+		stat, err := os.Lstat(path)
+		if err != nil {
+			return true
+		}
+		isSymlink := stat.Mode()&os.ModeSymlink != 0
+		if isSymlink != (e.mode == modeSymlink) || (!isSymlink && !stat.Mode().IsRegular()) {
+			return true
+		}
+		if !isSymlink && runtime.GOOS != "windows" && (stat.Mode()&0o111 != 0) != (e.mode&0o111 != 0) {
+			return true
+		}
+		if uint32(stat.Size()) != e.size {
+			return true
+		}
+		mtime := stat.ModTime()
+		sameTime := mtime.Unix() == e.mtime && (e.mtimeNano == 0 || uint32(mtime.Nanosecond()) == e.mtimeNano)
+		// files modified just before the index was written may change without changing stat ("racy git")
+		racy := !mtime.Before(indexStat.mtime)
+		if sameTime && !racy {
+			continue
+		}
+		sig := newFileSig(stat)
+		h, ok := hashes[e.path]
+		if !ok || h.sig != sig {
+			var content []byte
+			if isSymlink {
+				var target string
+				target, err = os.Readlink(path)
+				content = []byte(target)
+			} else {
+				content, err = ioutil.ReadFile(path)
+			}
+			if err != nil {
+				return true
+			}
+			h = hashedFile{sig: sig, hash: blobHash(content)}
+			hashes[e.path] = h
+		}
+		if h.hash != e.hash {
+			return true
+		}
+	}
+	return false
+}
+
+// untracked walks the working tree and finds a file that is neither tracked nor ignored.
+// Directories and ignore files it reads are recorded to watch so that the result can be cached.
+func untracked(r *repository, index []indexEntry, watch map[string]fileSig) bool {
+	tracked := make(map[string]bool, len(index))
+	for _, e := range index {
+		tracked[e.path] = true
+	}
+	var matcher ignoreMatcher
+	for _, path := range globalIgnoreFiles(r) {
+		watch[path] = statSig(path)
+		if list := readIgnoreFile("", path); list != nil {
+			matcher = append(matcher, list)
+		}
+	}
+	var walk func(dir string, matcher ignoreMatcher) bool
+	walk = func(dir string, matcher ignoreMatcher) bool {
+		fullPath := filepath.Join(r.workTree, filepath.FromSlash(dir))
+		watch[fullPath] = statSig(fullPath)
+		entries, err := os.ReadDir(fullPath)
+		if err != nil {
+			return false
+		}
+		ignorePath := filepath.Join(fullPath, ".gitignore")
+		watch[ignorePath] = statSig(ignorePath)
+		if list := readIgnoreFile(dir, ignorePath); list != nil {
+			matcher = append(matcher[:len(matcher):len(matcher)], list)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if name == ".git" {
+				continue
+			}
+			path := name
+			if dir != "" {
+				path = dir + "/" + name
+			}
+			if tracked[path] {
+				continue
+			}
+			if matcher.ignored(path, entry.IsDir()) {
+				continue
+			}
+			if !entry.IsDir() {
+				return true
+			}
+			if walk(path, matcher) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk("", matcher)
+}
+
+// globalIgnoreFiles returns the user wide ignore file and info/exclude. Later ones have higher priority.
+func globalIgnoreFiles(r *repository) []string {
+	var result []string
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		result = append(result, filepath.Join(xdg, "git", "ignore"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		result = append(result, filepath.Join(home, ".config", "git", "ignore"))
+	}
+	return append(result, filepath.Join(r.commonDir, "info", "exclude"))
+}
@@ -0,0 +1,166 @@
+package gitstatus
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// git runs git command in the directory to prepare test repositories.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"HOME="+dir,
+		"XDG_CONFIG_HOME="+filepath.Join(dir, ".config"),
+		"GIT_AUTHOR_NAME=tish", "GIT_AUTHOR_EMAIL=tish@example.com",
+		"GIT_COMMITTER_NAME=tish", "GIT_COMMITTER_EMAIL=tish@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0o644))
+}
+
+func initRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(dir, "README.md"), "# test\n")
+	writeFile(t, filepath.Join(dir, "src", "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func TestRead_NotRepository(t *testing.T) {
+	_, err := Read(t.TempDir())
+	assert.Equal(t, ErrNotRepository, err)
+}
+
+func TestRead_Clean(t *testing.T) {
+	dir := initRepository(t)
+	status, err := Read(filepath.Join(dir, "src"))
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main"}, status)
+}
+
+func TestRead_Unborn(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "develop")
+	status, err := Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "develop"}, status)
+
+	writeFile(t, filepath.Join(dir, "a.txt"), "a")
+	git(t, dir, "add", "a.txt")
+	status, err = Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "develop", Staged: true}, status)
+}
+
+func TestRead_Changes(t *testing.T) {
+	dir := initRepository(t)
+	cache := NewCache()
+
+	writeFile(t, filepath.Join(dir, "debug.log"), "ignored")
+	status, err := cache.Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main"}, status)
+
+	writeFile(t, filepath.Join(dir, "src", "new.go"), "package main\n")
+	status, err = cache.Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main", Untracked: true}, status)
+
+	git(t, dir, "add", "src/new.go")
+	status, err = cache.Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main", Staged: true}, status)
+
+	writeFile(t, filepath.Join(dir, "README.md"), "# changed\n")
+	status, err = cache.Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main", Staged: true, Dirty: true}, status)
+
+	git(t, dir, "commit", "-q", "-am", "second")
+	status, err = cache.Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main"}, status)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "src", "main.go")))
+	status, err = cache.Read(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Branch: "main", Dirty: true}, status)
+}
+
+func TestRead_SameSize(t *testing.T) {
+	dir := initRepository(t)
+	cache := NewCache()
+	_, err := cache.Read(dir)
+	assert.NoError(t, err)
+
+	// same size, different content: the cached hash must not be reused
+	writeFile(t, filepath.Join(dir, "README.md"), "# TEST\n")
+	status, err := cache.Read(dir)
+	assert.NoError(t, err)
+	assert.True(t, status.Dirty)
+}
+
+func TestRead_Detached(t *testing.T) {
+	dir := initRepository(t)
+	git(t, dir, "checkout", "-q", "--detach")
+	status, err := Read(dir)
+	assert.NoError(t, err)
+	assert.True(t, status.Detached)
+	assert.Len(t, status.Branch, 7)
+}
+
+func TestRead_AheadBehind(t *testing.T) {
+	for _, gc := range []bool{false, true} {
+		name := "loose"
+		if gc {
+			name = "packed"
+		}
+		t.Run(name, func(t *testing.T) {
+			remote := initRepository(t)
+			dir := t.TempDir()
+			git(t, dir, "clone", "-q", remote, ".")
+			for _, file := range []string{"a", "b", "c"} {
+				writeFile(t, filepath.Join(remote, file), file)
+				git(t, remote, "add", file)
+				git(t, remote, "commit", "-q", "-m", file)
+			}
+			git(t, dir, "fetch", "-q")
+			for _, file := range []string{"d", "e"} {
+				writeFile(t, filepath.Join(dir, file), file)
+				git(t, dir, "add", file)
+				git(t, dir, "commit", "-q", "-m", file)
+			}
+			if gc {
+				git(t, dir, "gc", "-q")
+			}
+			status, err := Read(dir)
+			assert.NoError(t, err)
+			assert.Equal(t, &Status{Branch: "main", Upstream: "origin/main", Ahead: 2, Behind: 3}, status)
+		})
+	}
+}
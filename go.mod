@@ -1,17 +1,25 @@
 module github.com/shibukawa/tish
 
-go 1.16
+go 1.21
 
 require (
 	github.com/fatih/color v1.13.0
-	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
 	github.com/gookit/color v1.4.2
 	github.com/jessevdk/go-flags v1.5.0
+	github.com/mattn/go-runewidth v0.0.9
+	github.com/stretchr/testify v1.7.0
+	github.com/tetratelabs/wazero v1.8.2
+	golang.org/x/sys v0.0.0-20210902050250-f475640dd07b
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/kr/pretty v0.2.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.9 // indirect
-	github.com/peterh/liner v1.2.1
-	github.com/stretchr/testify v1.7.0
-	golang.org/x/sys v0.0.0-20210902050250-f475640dd07b // indirect
+	github.com/mattn/go-colorable v0.1.9 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
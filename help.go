@@ -0,0 +1,194 @@
+package tish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Flag is a flag of the command generated from Command.Options.
+type Flag struct {
+	Short       string
+	Long        string
+	Description string
+	HasArg      bool
+}
+
+// Candidate is a completion candidate. Description is available for flags.
+type Candidate struct {
+	Text        string
+	Description string
+}
+
+// newParser creates go-flags parser for a new value of Options type. It returns nil if the command doesn't have Options.
+func (c *Command) newParser() *flags.Parser {
+	if c.Options == nil {
+		return nil
+	}
+	t := reflect.TypeOf(c.Options)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	data := reflect.New(t).Interface()
+	parser := flags.NewNamedParser(c.Name, flags.None)
+	parser.LongDescription = c.Description
+	if _, err := parser.AddGroup("Options", "", data); err != nil {
+		return nil
+	}
+	return parser
+}
+
+// Flags returns flags of the command generated from Options. "--help" and "-h" are included unless Options uses them
+// or NoHelp is set.
+func (c *Command) Flags() []Flag {
+	result := c.optionFlags()
+	if result == nil {
+		return nil
+	}
+	if long, short := c.helpFlags(result); long != "" {
+		result = append(result, Flag{Short: strings.TrimPrefix(short, "-"), Long: "help", Description: "Show this help message"})
+	}
+	return result
+}
+
+// optionFlags returns flags defined in Options.
+func (c *Command) optionFlags() []Flag {
+	parser := c.newParser()
+	if parser == nil {
+		return nil
+	}
+	result := []Flag{}
+	var walk func(groups []*flags.Group)
+	walk = func(groups []*flags.Group) {
+		for _, g := range groups {
+			for _, o := range g.Options() {
+				if o.Hidden {
+					continue
+				}
+				f := Flag{
+					Long:        o.LongNameWithNamespace(),
+					Description: o.Description,
+					HasArg:      o.Field().Type.Kind() != reflect.Bool,
+				}
+				if o.ShortName != 0 {
+					f.Short = string(o.ShortName)
+				}
+				result = append(result, f)
+			}
+			walk(g.Groups())
+		}
+	}
+	walk(parser.Groups())
+	return result
+}
+
+// helpFlags returns "--help" and "-h" unless the flags use them. Both are empty if NoHelp is set.
+func (c *Command) helpFlags(flags []Flag) (long, short string) {
+	if c.NoHelp {
+		return "", ""
+	}
+	long, short = "--help", "-h"
+	for _, f := range flags {
+		if f.Long == "help" {
+			long = ""
+		}
+		if f.Short == "h" {
+			short = ""
+		}
+	}
+	if long == "" {
+		short = ""
+	}
+	return
+}
+
+// FlagCandidates returns flags that begin with the input.
+func (c *Command) FlagCandidates(input string) []Candidate {
+	var result []Candidate
+	for _, f := range c.Flags() {
+		if f.Short != "" && strings.HasPrefix("-"+f.Short, input) {
+			result = append(result, Candidate{Text: "-" + f.Short, Description: f.Description})
+		}
+		if f.Long != "" && strings.HasPrefix("--"+f.Long, input) {
+			result = append(result, Candidate{Text: "--" + f.Long, Description: f.Description})
+		}
+	}
+	return result
+}
+
+// WriteHelp writes usage of the command. Flags are generated from Options.
+func (c *Command) WriteHelp(w io.Writer) {
+	parser := c.newParser()
+	if parser == nil {
+		fmt.Fprintf(w, "Usage:\n  %s\n", c.Name)
+		if c.Description != "" {
+			fmt.Fprintf(w, "\n%s\n", c.Description)
+		}
+		return
+	}
+	parser.Usage = "[OPTIONS]"
+	switch long, short := c.helpFlags(c.optionFlags()); {
+	case short != "":
+		parser.AddGroup("Help Options", "", &struct {
+			Help bool `short:"h" long:"help" description:"Show this help message"`
+		}{})
+	case long != "":
+		parser.AddGroup("Help Options", "", &struct {
+			Help bool `long:"help" description:"Show this help message"`
+		}{})
+	}
+	parser.WriteHelp(w)
+}
+
+// wantsHelp returns true if the leading flags have "--help" (or "-h" if Options doesn't use it).
+// Arguments after the first operand or "--" are not checked, because they can be text like "alias h='help --help'".
+func (c *Command) wantsHelp(args []string) bool {
+	optionFlags := c.optionFlags()
+	if optionFlags == nil {
+		return false
+	}
+	long, short := c.helpFlags(optionFlags)
+	if long == "" {
+		return false
+	}
+	hasArg := map[string]bool{}
+	for _, f := range optionFlags {
+		if f.HasArg && f.Short != "" {
+			hasArg["-"+f.Short] = true
+		}
+		if f.HasArg && f.Long != "" {
+			hasArg["--"+f.Long] = true
+		}
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == long || short != "" && arg == short:
+			return true
+		case arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-"):
+			return false
+		case hasArg[arg]:
+			// skip the value of the flag like "--name --help"
+			i++
+		}
+	}
+	return false
+}
+
+// helpExecutor writes help instead of running the command if it gets "--help".
+func helpExecutor(cmd *Command, next Executor) Executor {
+	return func(ctx context.Context, result *ExecResult, p *Process) error {
+		if cmd.wantsHelp(p.Args) {
+			cmd.WriteHelp(p.Stdout)
+			return result.SetInternalProcessResult(0)
+		}
+		return next(ctx, result, p)
+	}
+}
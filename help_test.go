@@ -0,0 +1,147 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Help(t *testing.T) {
+	root := CreateTestFolders(t, "command-help")
+
+	s := NewShell(root, []string{})
+	me := &mockExecutor{Stdout: "ran\n"}
+	s.RegisterCommand(&Command{
+		Name:        "show",
+		Description: "Show things",
+		Options: struct {
+			Human bool   `short:"h" description:"human readable"`
+			Name  string `long:"name" description:"name to show"`
+		}{},
+		Executor: me.Executor,
+	})
+	s.RegisterCommand(&Command{
+		Name:        "greet",
+		Description: "Say hello",
+		Options: &struct {
+			Upper bool `short:"u" long:"upper" description:"greet loudly"`
+		}{},
+		Executor: (&mockExecutor{Stdout: "hello\n"}).Executor,
+	})
+	s.RegisterCommand(&Command{
+		Name:        "say",
+		Description: "Say text",
+		Options: struct {
+			Loud bool `short:"l" description:"say loudly"`
+		}{},
+		NoHelp:   true,
+		Executor: (&mockExecutor{Stdout: "said\n"}).Executor,
+	})
+
+	tests := []struct {
+		name       string
+		cmd        string
+		wantStdout string
+	}{
+		{
+			name:       "long help",
+			cmd:        "greet --help",
+			wantStdout: "Usage:\n  greet [OPTIONS]\n\nSay hello\n\nOptions:\n  -u, --upper  greet loudly\n\nHelp Options:\n  -h, --help   Show this help message\n",
+		},
+		{
+			name:       "short help",
+			cmd:        "greet -u -h",
+			wantStdout: "Usage:\n  greet [OPTIONS]\n\nSay hello\n\nOptions:\n  -u, --upper  greet loudly\n\nHelp Options:\n  -h, --help   Show this help message\n",
+		},
+		{
+			name:       "after double dash",
+			cmd:        "greet -- --help",
+			wantStdout: "hello\n",
+		},
+		{
+			name:       "after operand",
+			cmd:        "greet world --help",
+			wantStdout: "hello\n",
+		},
+		{
+			name:       "value of flag",
+			cmd:        "show --name --help",
+			wantStdout: "ran\n",
+		},
+		{
+			name:       "no help",
+			cmd:        "say -l --help",
+			wantStdout: "said\n",
+		},
+		{
+			name:       "short flag is used by options",
+			cmd:        "show -h",
+			wantStdout: "ran\n",
+		},
+		{
+			name:       "anonymous struct",
+			cmd:        "show --help",
+			wantStdout: "Usage:\n  show [OPTIONS]\n\nShow things\n\nOptions:\n  -h          human readable\n      --name= name to show\n\nHelp Options:\n      --help  Show this help message\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			code, err := s.Run(context.Background(), tt.cmd, &stdout, io.Discard)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, code)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+		})
+	}
+}
+
+func TestCommand_Flags(t *testing.T) {
+	cmd := &Command{
+		Name: "show",
+		Options: struct {
+			Human bool   `short:"h" description:"human readable"`
+			Name  string `short:"n" long:"name" description:"name to show"`
+		}{},
+	}
+	assert.Equal(t, []Flag{
+		{Short: "h", Description: "human readable"},
+		{Short: "n", Long: "name", Description: "name to show", HasArg: true},
+		{Long: "help", Description: "Show this help message"},
+	}, cmd.Flags())
+	assert.Nil(t, (&Command{Name: "noop"}).Flags())
+}
+
+func TestShell_CandidatesOfFlags(t *testing.T) {
+	root := CreateTestFolders(t, "complete-flags", map[string]string{
+		"-file": "",
+	})
+
+	s := NewShell(root, []string{})
+	s.RegisterCommand(&Command{
+		Name: "greet",
+		Options: &struct {
+			Name  string `short:"n" long:"name" description:"name to greet"`
+			Upper bool   `short:"u" long:"upper" description:"greet loudly"`
+		}{},
+		Executor: (&mockExecutor{}).Executor,
+	})
+	registerMockCommand(t, s, "mock")
+
+	head, got, tail := s.Candidates("greet --", 8)
+	assert.Equal(t, "greet ", head)
+	assert.Equal(t, []Candidate{
+		{Text: "--help", Description: "Show this help message"},
+		{Text: "--name", Description: "name to greet"},
+		{Text: "--upper", Description: "greet loudly"},
+	}, got)
+	assert.Equal(t, "", tail)
+
+	_, completions, _ := s.Complete("greet -", 7)
+	assert.Equal(t, []string{"--help", "--name", "--upper", "-h", "-n", "-u"}, completions)
+
+	_, completions, _ = s.Complete("mock -", 6)
+	assert.Equal(t, []string{"-file"}, completions)
+}
@@ -0,0 +1,162 @@
+package tish
+
+import (
+	"strings"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// CommandKind is how the command name resolves when it runs.
+type CommandKind int
+
+const (
+	// CommandNotFound is a command that doesn't resolve.
+	CommandNotFound CommandKind = iota
+	// BuiltinCommand is a command registered to the shell (applets, plugins and Go functions).
+	BuiltinCommand
+	// AliasCommand is an alias. Aliases are functions of tish.
+	AliasCommand
+	// ExternalCommand is an executable file in PATH.
+	ExternalCommand
+)
+
+// LookupCommandKind returns how the command name resolves. Aliases are checked first like Run.
+func (s *Shell) LookupCommandKind(name string) CommandKind {
+	if _, ok := s.Alias(name); ok {
+		return AliasCommand
+	}
+	if s.lookupCommand(name) == nil {
+		return CommandNotFound
+	}
+	if s.HasCommand(name) {
+		return BuiltinCommand
+	}
+	return ExternalCommand
+}
+
+// Highlight styles. They are the names of Theme.Colors.
+const (
+	HighlightBuiltin      = "builtin"
+	HighlightAlias        = "alias"
+	HighlightExternal     = "external"
+	HighlightNotFound     = "notFound"
+	HighlightString       = "string"
+	HighlightEscape       = "escape"
+	HighlightVariable     = "variable"
+	HighlightSubstitution = "substitution"
+	HighlightRedirect     = "redirect"
+	HighlightOperator     = "operator"
+	HighlightComment      = "comment"
+	// HighlightError marks unclosed quotes and back quotes.
+	HighlightError = "error"
+)
+
+// defaultHighlightColors are used when the theme doesn't have the colors of highlight styles.
+var defaultHighlightColors = map[string]string{
+	HighlightBuiltin:      "bright-blue",
+	HighlightAlias:        "cyan",
+	HighlightExternal:     "blue",
+	HighlightNotFound:     "bright-red",
+	HighlightString:       "yellow",
+	HighlightEscape:       "bright-magenta",
+	HighlightVariable:     "magenta",
+	HighlightSubstitution: "bright-yellow",
+	HighlightRedirect:     "bright-cyan",
+	HighlightOperator:     "bright-cyan",
+	HighlightComment:      "bright-black",
+	HighlightError:        "white,red",
+}
+
+var commandStyles = map[CommandKind]string{
+	CommandNotFound: HighlightNotFound,
+	BuiltinCommand:  HighlightBuiltin,
+	AliasCommand:    HighlightAlias,
+	ExternalCommand: HighlightExternal,
+}
+
+// Highlight is a styled range of the command line. Start and End are byte offsets.
+type Highlight struct {
+	Start int
+	End   int
+	Style string
+}
+
+// Highlight returns styled ranges of the command line for syntax highlighting.
+//
+// Command names are styled by LookupCommandKind. Unclosed quotes and back quotes are marked by HighlightError.
+// Ranges are sorted and they don't overlap. Unstyled parts are not included.
+func (s *Shell) Highlight(line string) []Highlight {
+	tokens, _ := parser.Tokenize(line)
+	var result []Highlight
+	for _, t := range tokens {
+		switch t.Kind {
+		case parser.OperatorToken:
+			result = append(result, Highlight{Start: t.Start, End: t.End, Style: HighlightOperator})
+		case parser.RedirectToken:
+			result = append(result, Highlight{Start: t.Start, End: t.End, Style: HighlightRedirect})
+		case parser.CommentToken:
+			result = append(result, Highlight{Start: t.Start, End: t.End, Style: HighlightComment})
+		case parser.WordToken:
+			if t.Command && isStaticWord(t) {
+				result = append(result, Highlight{Start: t.Start, End: t.End, Style: commandStyles[s.LookupCommandKind(t.Word.Text)]})
+				continue
+			}
+			for _, p := range t.Parts {
+				if style := partStyle(p); style != "" {
+					result = append(result, Highlight{Start: p.Start, End: p.End, Style: style})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// isStaticWord returns true if the word is complete and it doesn't have variables and command substitutions.
+func isStaticWord(t parser.Token) bool {
+	for _, p := range t.Parts {
+		if p.Unclosed || p.Kind == parser.VariablePart || p.Kind == parser.BackquotePart {
+			return false
+		}
+	}
+	return true
+}
+
+func partStyle(p parser.Part) string {
+	if p.Unclosed {
+		return HighlightError
+	}
+	switch p.Kind {
+	case parser.SingleQuotedPart, parser.DoubleQuotedPart:
+		return HighlightString
+	case parser.EscapedPart:
+		return HighlightEscape
+	case parser.VariablePart:
+		return HighlightVariable
+	case parser.BackquotePart:
+		return HighlightSubstitution
+	}
+	return ""
+}
+
+// HighlightLine colors the ranges of the line by the colors of the theme. Plain returns the line as is.
+func (t *Theme) HighlightLine(line string, highlights []Highlight, plain bool) string {
+	if plain || len(highlights) == 0 {
+		return line
+	}
+	if t == nil {
+		t = DefaultTheme
+	}
+	var b strings.Builder
+	pos := 0
+	for _, h := range highlights {
+		b.WriteString(line[pos:h.Start])
+		spec, ok := t.Colors[h.Style]
+		if !ok {
+			spec = defaultHighlightColors[h.Style]
+		}
+		b.WriteString(colorize(false, spec, line[h.Start:h.End]))
+		pos = h.End
+	}
+	b.WriteString(line[pos:])
+	return b.String()
+}
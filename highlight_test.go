@@ -0,0 +1,88 @@
+package tish
+
+import (
+	"os/exec"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_LookupCommandKind(t *testing.T) {
+	s := NewShell(".", nil)
+	registerMockCommand(t, s, "mock1")
+	s.SetAlias("m", "mock1 -x")
+	assert.Equal(t, BuiltinCommand, s.LookupCommandKind("mock1"))
+	assert.Equal(t, AliasCommand, s.LookupCommandKind("m"))
+	assert.Equal(t, CommandNotFound, s.LookupCommandKind("no-such-command"))
+	if _, err := exec.LookPath("sh"); err == nil {
+		assert.Equal(t, ExternalCommand, s.LookupCommandKind("sh"))
+		safe := NewShell(".", nil, Option{SafeMode: true})
+		assert.Equal(t, CommandNotFound, safe.LookupCommandKind("sh"))
+	}
+}
+
+func TestShell_Highlight(t *testing.T) {
+	s := NewShell(".", nil)
+	registerMockCommand(t, s, "mock1")
+	tests := []struct {
+		name string
+		line string
+		want []Highlight
+	}{
+		{
+			name: "command and operator",
+			line: "mock1 a | unknown > out # note",
+			want: []Highlight{
+				{0, 5, HighlightBuiltin},
+				{8, 9, HighlightOperator},
+				{10, 17, HighlightNotFound},
+				{18, 19, HighlightRedirect},
+				{24, 30, HighlightComment},
+			},
+		},
+		{
+			name: "strings and variables",
+			line: `mock1 "a $B" \c` + " `d`",
+			want: []Highlight{
+				{0, 5, HighlightBuiltin},
+				{6, 9, HighlightString},
+				{9, 11, HighlightVariable},
+				{11, 12, HighlightString},
+				{13, 15, HighlightEscape},
+				{16, 19, HighlightSubstitution},
+			},
+		},
+		{
+			name: "unclosed",
+			line: "mock1 'abc `d",
+			want: []Highlight{
+				{0, 5, HighlightBuiltin},
+				{6, 11, HighlightError},
+				{11, 13, HighlightError},
+			},
+		},
+		{
+			name: "variable command",
+			line: "$EDITOR",
+			want: []Highlight{
+				{0, 7, HighlightVariable},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Highlight(tt.line))
+		})
+	}
+}
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestTheme_HighlightLine(t *testing.T) {
+	highlights := []Highlight{{0, 2, HighlightBuiltin}, {3, 5, HighlightString}}
+	assert.Equal(t, "ls 'a'", DefaultTheme.HighlightLine("ls 'a'", highlights, true))
+	theme := &Theme{Colors: map[string]string{HighlightBuiltin: "1"}}
+	got := theme.HighlightLine("ls 'a'", highlights, false)
+	assert.Equal(t, "ls 'a'", ansiEscape.ReplaceAllString(got, ""))
+}
@@ -0,0 +1,523 @@
+package tish
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// DefaultHistorySize is the number of history entries kept when HISTSIZE is not set.
+const DefaultHistorySize = 1000
+
+// HistoryEntry is an entry of the command history.
+type HistoryEntry struct {
+	// Number is the history number used by "!n". It is not saved.
+	Number     int       `json:"-"`
+	Command    string    `json:"cmd"`
+	Time       time.Time `json:"time"`
+	ExitCode   int       `json:"exit"`
+	WorkingDir string    `json:"dir,omitempty"`
+	// Paths are absolute paths of arguments that existed when the entry was added by Shell.AddHistory.
+	// Shell.Suggest skips the entry if they are removed.
+	Paths []string `json:"paths,omitempty"`
+}
+
+// HistoryOption configures History.
+type HistoryOption struct {
+	// File is a path of the history file. The history is not saved if it is empty.
+	File string
+	// Size is the max number of entries. DefaultHistorySize is used if it is zero.
+	Size int
+	// IgnoreDups doesn't record the command that is as same as the previous one.
+	IgnoreDups bool
+	// IgnoreSpace doesn't record the command that begins with space.
+	IgnoreSpace bool
+}
+
+// HistoryOptionFromEnv creates HistoryOption from HISTFILE, HISTSIZE and HISTCONTROL
+// (colon separated "ignoredups", "ignorespace" and "ignoreboth" like bash).
+// defaultFile is used if HISTFILE is not set.
+func HistoryOptionFromEnv(env map[string]string, defaultFile string) HistoryOption {
+	opt := HistoryOption{
+		File: defaultFile,
+	}
+	if file, ok := env["HISTFILE"]; ok {
+		opt.File = file
+	}
+	if size, err := strconv.Atoi(env["HISTSIZE"]); err == nil && size > 0 {
+		opt.Size = size
+	}
+	for _, c := range strings.Split(env["HISTCONTROL"], ":") {
+		switch c {
+		case "ignoredups":
+			opt.IgnoreDups = true
+		case "ignorespace":
+			opt.IgnoreSpace = true
+		case "ignoreboth":
+			opt.IgnoreDups = true
+			opt.IgnoreSpace = true
+		}
+	}
+	return opt
+}
+
+// ErrHistoryExpansion is returned when the history reference like "!foo" can't be expanded.
+type ErrHistoryExpansion struct {
+	Event  string
+	Reason string
+}
+
+func (e ErrHistoryExpansion) Error() string {
+	return fmt.Sprintf("%s: %s", e.Event, e.Reason)
+}
+
+// History is a command history that is shared by the shell and its forks.
+//
+// Entries are appended to the history file one line (JSON) per entry,
+// so several sessions can share the same file. Each session keeps its own entries in memory like bash.
+// The file is locked by "<file>.lock" while writing, so rewriting (trimming, Delete and Clear) doesn't lose
+// entries that other sessions append.
+type History struct {
+	lock    sync.Mutex
+	option  HistoryOption
+	entries []HistoryEntry
+	// base is the number of the first entry
+	base int
+}
+
+// NewHistory creates a history in memory.
+func NewHistory(opt HistoryOption) *History {
+	if opt.Size <= 0 {
+		opt.Size = DefaultHistorySize
+	}
+	return &History{
+		option: opt,
+		base:   1,
+	}
+}
+
+// OpenHistory creates a history and loads the history file. A missing file is not an error.
+//
+// If the file has more entries than Size, old entries are removed from the file.
+func OpenHistory(opt HistoryOption) (*History, error) {
+	h := NewHistory(opt)
+	if h.option.File == "" {
+		return h, nil
+	}
+	entries, err := readHistoryFile(h.option.File)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > h.option.Size {
+		size := h.option.Size
+		err := rewriteHistoryFile(h.option.File, func(entries []HistoryEntry) []HistoryEntry {
+			if len(entries) > size {
+				return entries[len(entries)-size:]
+			}
+			return entries
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = entries[len(entries)-size:]
+	}
+	h.entries = entries
+	return h, nil
+}
+
+// Option returns the option of the history.
+func (h *History) Option() HistoryOption {
+	return h.option
+}
+
+// Add records the entry. Time is set to now if it is zero.
+// It returns false if the entry is ignored by IgnoreDups or IgnoreSpace.
+func (h *History) Add(entry HistoryEntry) (bool, error) {
+	if strings.TrimSpace(entry.Command) == "" {
+		return false, nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.option.IgnoreSpace && strings.HasPrefix(entry.Command, " ") {
+		return false, nil
+	}
+	if h.option.IgnoreDups && len(h.entries) > 0 && h.entries[len(h.entries)-1].Command == entry.Command {
+		return false, nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Number = 0
+	h.entries = append(h.entries, entry)
+	if over := len(h.entries) - h.option.Size; over > 0 {
+		h.entries = append([]HistoryEntry{}, h.entries[over:]...)
+		h.base += over
+	}
+	if h.option.File == "" {
+		return true, nil
+	}
+	return true, appendHistoryFile(h.option.File, entry)
+}
+
+// Entries returns all entries with their numbers.
+func (h *History) Entries() []HistoryEntry {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	result := make([]HistoryEntry, len(h.entries))
+	for i, e := range h.entries {
+		e.Number = h.base + i
+		result[i] = e
+	}
+	return result
+}
+
+// Entry returns the entry of the number.
+func (h *History) Entry(number int) (HistoryEntry, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	i := number - h.base
+	if i < 0 || i >= len(h.entries) {
+		return HistoryEntry{}, false
+	}
+	e := h.entries[i]
+	e.Number = number
+	return e, true
+}
+
+// Delete removes the entry of the number from the history and the history file.
+func (h *History) Delete(number int) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	i := number - h.base
+	if i < 0 || i >= len(h.entries) {
+		return fmt.Errorf("%d: history position out of range", number)
+	}
+	removed := h.entries[i]
+	h.entries = append(h.entries[:i:i], h.entries[i+1:]...)
+	if h.option.File == "" {
+		return nil
+	}
+	// other sessions may have appended entries after loading
+	return rewriteHistoryFile(h.option.File, func(entries []HistoryEntry) []HistoryEntry {
+		for j := len(entries) - 1; j >= 0; j-- {
+			if entries[j].Command == removed.Command && entries[j].Time.Equal(removed.Time) {
+				return append(entries[:j:j], entries[j+1:]...)
+			}
+		}
+		return entries
+	})
+}
+
+// Clear removes all entries from the history and the history file.
+func (h *History) Clear() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.base += len(h.entries)
+	h.entries = nil
+	if h.option.File == "" {
+		return nil
+	}
+	return rewriteHistoryFile(h.option.File, func(entries []HistoryEntry) []HistoryEntry {
+		return nil
+	})
+}
+
+// Expand expands history references in the line like bash:
+//
+//	!!        previous command
+//	!n        command of history number n
+//	!-n       n-th previous command
+//	!prefix   latest command that begins with prefix
+//	!$        last word of the previous command
+//	^old^new  previous command that old is replaced with new
+//
+// "!" in single quotes, escaped by back slash or followed by space, "=", "(" or an operator like ";" is not expanded.
+func (h *History) Expand(line string) (string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if strings.HasPrefix(line, "^") {
+		return h.substitute(line)
+	}
+	if !strings.Contains(line, "!") {
+		return line, nil
+	}
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && quote != '\'' && i+1 < len(line):
+			b.WriteString(line[i : i+2])
+			i++
+			continue
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == c:
+			quote = 0
+		case c == '!' && quote != '\'':
+			text, n, err := h.event(line[i+1:])
+			if err != nil {
+				return "", err
+			}
+			if n > 0 {
+				b.WriteString(text)
+				i += n
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// event returns the text of the event designator after "!" and the length of the designator.
+func (h *History) event(src string) (string, int, error) {
+	if src == "" || strings.IndexByte(" \t\r\n=(\"'", src[0]) != -1 {
+		return "", 0, nil
+	}
+	switch src[0] {
+	case '!':
+		e, ok := h.previous(1)
+		if !ok {
+			return "", 0, ErrHistoryExpansion{Event: "!!", Reason: "event not found"}
+		}
+		return e.Command, 1, nil
+	case '$':
+		e, ok := h.previous(1)
+		if !ok {
+			return "", 0, ErrHistoryExpansion{Event: "!$", Reason: "event not found"}
+		}
+		return lastWord(e.Command), 1, nil
+	}
+	end := 0
+	if src[0] == '-' {
+		end = 1
+	}
+	for end < len(src) && '0' <= src[end] && src[end] <= '9' {
+		end++
+	}
+	if end > 0 && src[end-1] != '-' {
+		n, _ := strconv.Atoi(src[:end])
+		var e HistoryEntry
+		var ok bool
+		if n < 0 {
+			e, ok = h.previous(-n)
+		} else if i := n - h.base; i >= 0 && i < len(h.entries) {
+			e, ok = h.entries[i], true
+		}
+		if !ok {
+			return "", 0, ErrHistoryExpansion{Event: "!" + src[:end], Reason: "event not found"}
+		}
+		return e.Command, end, nil
+	}
+	end = 0
+	for end < len(src) && strings.IndexByte(" \t\r\n;|&<>()'\"", src[end]) == -1 {
+		end++
+	}
+	if end == 0 {
+		// "!" followed by a character that can't start a word like "hi!;" is not an event designator
+		return "", 0, nil
+	}
+	prefix := src[:end]
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if strings.HasPrefix(h.entries[i].Command, prefix) {
+			return h.entries[i].Command, end, nil
+		}
+	}
+	return "", 0, ErrHistoryExpansion{Event: "!" + prefix, Reason: "event not found"}
+}
+
+// substitute expands "^old^new^" with the previous command.
+func (h *History) substitute(line string) (string, error) {
+	parts := strings.SplitN(line[1:], "^", 3)
+	if len(parts) < 2 {
+		parts = append(parts, "")
+	}
+	old, replacement := parts[0], parts[1]
+	e, ok := h.previous(1)
+	if !ok {
+		return "", ErrHistoryExpansion{Event: line, Reason: "event not found"}
+	}
+	if old == "" || !strings.Contains(e.Command, old) {
+		return "", ErrHistoryExpansion{Event: line, Reason: "substitution failed"}
+	}
+	result := strings.Replace(e.Command, old, replacement, 1)
+	if len(parts) == 3 {
+		result += parts[2]
+	}
+	return result, nil
+}
+
+// previous returns the n-th previous entry.
+func (h *History) previous(n int) (HistoryEntry, bool) {
+	if n <= 0 || n > len(h.entries) {
+		return HistoryEntry{}, false
+	}
+	return h.entries[len(h.entries)-n], true
+}
+
+// lastWord returns the last word of the command line as it is written.
+func lastWord(line string) string {
+	words, _ := parser.SplitWords(line)
+	if len(words) == 0 {
+		return ""
+	}
+	w := words[len(words)-1]
+	return line[w.Start:w.End]
+}
+
+// History returns the command history. It is shared with forked shells.
+func (s *Shell) History() *History {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.history
+}
+
+// AddHistory adds the entry to the history with paths of its arguments that exist in the WorkingDir of the entry.
+// It should be called after running the command, so files removed by the command like "rm a.txt" are not recorded.
+func (s *Shell) AddHistory(entry HistoryEntry) (bool, error) {
+	if entry.Paths == nil {
+		entry.Paths = s.existingPaths(entry.Command, entry.WorkingDir)
+	}
+	return s.History().Add(entry)
+}
+
+// SetHistory replaces the command history.
+func (s *Shell) SetHistory(h *History) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.history = h
+}
+
+// LoadHistory opens the history file configured by HISTFILE, HISTSIZE and HISTCONTROL of the shell.
+// defaultFile is used if HISTFILE is not set.
+func (s *Shell) LoadHistory(defaultFile string) error {
+	h, err := OpenHistory(HistoryOptionFromEnv(s.Environ(), defaultFile))
+	if err != nil {
+		return err
+	}
+	s.SetHistory(h)
+	return nil
+}
+
+// readHistoryFile reads entries. Lines that are not JSON are read as commands to import other shells' history.
+func readHistoryFile(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e HistoryEntry
+		if line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			e = HistoryEntry{Command: string(line)}
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// lockHistoryFile locks "<path>.lock" that guards appending and replacing the history file.
+// The history file itself can't be locked because it is replaced by renaming.
+func lockHistoryFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// appendHistoryFile appends the entry by one write call, so concurrent sessions don't break lines.
+func appendHistoryFile(path string, entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockHistoryFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewriteHistoryFile reads the file, updates the entries and writes them back under the lock,
+// so entries appended by other sessions after loading are kept.
+func rewriteHistoryFile(path string, update func(entries []HistoryEntry) []HistoryEntry) error {
+	unlock, err := lockHistoryFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := readHistoryFile(path)
+	if err != nil {
+		return err
+	}
+	return writeHistoryFile(path, update(entries))
+}
+
+// writeHistoryFile replaces the file by renaming a temporary file, so readers never see a partial file.
+// The caller should hold the lock of lockHistoryFile.
+func writeHistoryFile(path string, entries []HistoryEntry) error {
+	var buffer bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buffer.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,197 @@
+package tish
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func commandsOf(entries []HistoryEntry) []string {
+	var result []string
+	for _, e := range entries {
+		result = append(result, e.Command)
+	}
+	return result
+}
+
+func TestHistoryOptionFromEnv(t *testing.T) {
+	opt := HistoryOptionFromEnv(map[string]string{
+		"HISTSIZE":    "20",
+		"HISTCONTROL": "ignorespace:ignoredups",
+	}, "/home/tish/.tish_history")
+	assert.Equal(t, HistoryOption{File: "/home/tish/.tish_history", Size: 20, IgnoreDups: true, IgnoreSpace: true}, opt)
+
+	opt = HistoryOptionFromEnv(map[string]string{
+		"HISTFILE":    "",
+		"HISTSIZE":    "invalid",
+		"HISTCONTROL": "ignoreboth",
+	}, "/home/tish/.tish_history")
+	assert.Equal(t, HistoryOption{IgnoreDups: true, IgnoreSpace: true}, opt)
+}
+
+func TestHistory_Add(t *testing.T) {
+	h := NewHistory(HistoryOption{Size: 3, IgnoreDups: true, IgnoreSpace: true})
+	for _, cmd := range []string{"ls", "ls", " secret", "", "pwd", "cd /", "ls"} {
+		h.Add(HistoryEntry{Command: cmd, WorkingDir: "/home"})
+	}
+	entries := h.Entries()
+	assert.Equal(t, []string{"pwd", "cd /", "ls"}, commandsOf(entries))
+	assert.Equal(t, 2, entries[0].Number)
+	assert.Equal(t, "/home", entries[0].WorkingDir)
+	assert.False(t, entries[0].Time.IsZero())
+
+	e, ok := h.Entry(3)
+	assert.True(t, ok)
+	assert.Equal(t, "cd /", e.Command)
+	_, ok = h.Entry(1)
+	assert.False(t, ok)
+}
+
+func TestHistory_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	opt := HistoryOption{File: file, Size: 5}
+
+	// two sessions append to the same file
+	h1, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	h2, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for _, h := range []*History{h1, h2} {
+		wg.Add(1)
+		go func(h *History) {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				_, err := h.Add(HistoryEntry{Command: "echo", ExitCode: i, WorkingDir: "/tmp"})
+				assert.NoError(t, err)
+			}
+		}(h)
+	}
+	wg.Wait()
+	assert.Len(t, h1.Entries(), 3)
+
+	// old entries are removed when the file is loaded
+	h3, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	entries := h3.Entries()
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "/tmp", entries[0].WorkingDir)
+	h4, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	assert.Len(t, h4.Entries(), 5)
+
+	// delete keeps entries of other sessions
+	_, err = h3.Add(HistoryEntry{Command: "ls", Time: time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC)})
+	assert.NoError(t, err)
+	_, err = h4.Add(HistoryEntry{Command: "pwd"})
+	assert.NoError(t, err)
+	first := h3.Entries()[0]
+	assert.Equal(t, 2, first.Number)
+	assert.NoError(t, h3.Delete(first.Number))
+	assert.Error(t, h3.Delete(1))
+	h5, err := OpenHistory(HistoryOption{File: file})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo", "echo", "echo", "echo", "ls", "pwd"}, commandsOf(h5.Entries()))
+	assert.Equal(t, time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC), h5.Entries()[4].Time.UTC())
+
+	assert.NoError(t, h5.Clear())
+	assert.Empty(t, h5.Entries())
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestHistory_ConcurrentRewrite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	opt := HistoryOption{File: file, Size: 1000}
+	h1, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	h2, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		_, err := h1.Add(HistoryEntry{Command: "ls"})
+		assert.NoError(t, err)
+	}
+
+	// entries appended while the other session rewrites the file are kept
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_, err := h2.Add(HistoryEntry{Command: "pwd"})
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, h1.Delete(h1.Entries()[0].Number))
+	}
+	wg.Wait()
+	h3, err := OpenHistory(opt)
+	assert.NoError(t, err)
+	commands := commandsOf(h3.Entries())
+	assert.Len(t, commands, 50)
+	assert.NotContains(t, commands, "ls")
+}
+
+func TestHistory_ImportPlainText(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("ls -l\n\ncd /tmp\n"), 0600))
+	h, err := OpenHistory(HistoryOption{File: file})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ls -l", "cd /tmp"}, commandsOf(h.Entries()))
+}
+
+func TestHistory_Expand(t *testing.T) {
+	h := NewHistory(HistoryOption{})
+	for _, cmd := range []string{"ls -l /tmp", "cat 'my file.txt'", "echo hello world"} {
+		h.Add(HistoryEntry{Command: cmd})
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		want    string
+		wantErr string
+	}{
+		{name: "no reference", line: "ls", want: "ls"},
+		{name: "previous", line: "!! | wc", want: "echo hello world | wc"},
+		{name: "number", line: "!1", want: "ls -l /tmp"},
+		{name: "relative", line: "!-2", want: "cat 'my file.txt'"},
+		{name: "prefix", line: "!ca && !l", want: "cat 'my file.txt' && ls -l /tmp"},
+		{name: "last word", line: "rm !$", want: "rm world"},
+		{name: "quick substitution", line: "^hello^bye", want: "echo bye world"},
+		{name: "quick substitution with suffix", line: "^world^tish^ | wc", want: "echo hello tish | wc"},
+		{name: "single quote", line: "echo '!!'", want: "echo '!!'"},
+		{name: "double quote", line: `echo "!!"`, want: `echo "echo hello world"`},
+		{name: "escaped", line: `echo \!!`, want: `echo \!!`},
+		{name: "followed by space", line: "echo hi ! there!", want: "echo hi ! there!"},
+		{name: "followed by operator", line: "echo hi!; echo (hey!) | cat!>out", want: "echo hi!; echo (hey!) | cat!>out"},
+		{name: "not found", line: "!unknown", wantErr: "!unknown: event not found"},
+		{name: "out of range", line: "!10", wantErr: "!10: event not found"},
+		{name: "substitution failed", line: "^foo^bar", wantErr: "^foo^bar: substitution failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Expand(tt.line)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShell_HistoryIsShared(t *testing.T) {
+	s := NewShell("/", []string{"HISTSIZE=2", "HISTFILE="})
+	assert.NoError(t, s.LoadHistory("/never/used"))
+	assert.Equal(t, HistoryOption{Size: 2}, s.History().Option())
+	s.Fork().History().Add(HistoryEntry{Command: "ls"})
+	assert.Equal(t, []string{"ls"}, commandsOf(s.History().Entries()))
+}
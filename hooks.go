@@ -0,0 +1,160 @@
+package tish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// Hook is a name of user hook like zsh.
+type Hook string
+
+const (
+	// PreExec hooks run before the command line is executed. The command line is passed as an argument.
+	PreExec Hook = "preexec"
+	// PreCmd hooks run after the command line is executed (before the next prompt).
+	PreCmd Hook = "precmd"
+	// ChPwd hooks run after the pipeline that changed the working directory.
+	ChPwd Hook = "chpwd"
+)
+
+// HookFunc is a Go callback of hook.
+type HookFunc func(ctx context.Context, s *Shell, args []string, stdout, stderr io.Writer) error
+
+// Hooks are the names of all hooks.
+var Hooks = []Hook{PreExec, PreCmd, ChPwd}
+
+type hookEntry struct {
+	f HookFunc
+	// command is the command line of AddHookCommand
+	command string
+}
+
+type inHookKey struct{}
+
+// AddHook registers the Go callback to the hook. It returns a function to remove it.
+func (s *Shell) AddHook(hook Hook, f HookFunc) (remove func()) {
+	return s.addHook(hook, &hookEntry{f: f})
+}
+
+func (s *Shell) addHook(hook Hook, entry *hookEntry) (remove func()) {
+	b := s.events
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.hooks[hook] = append(b.hooks[hook], entry)
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		entries := b.hooks[hook]
+		for i, e := range entries {
+			if e == entry {
+				b.hooks[hook] = append(entries[:i:i], entries[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// AddHookCommand registers the command line to the hook like preexec_functions of zsh.
+// Arguments of the hook are quoted and appended to the command line, so an alias can be used like a shell function.
+// It returns a function to remove it.
+func (s *Shell) AddHookCommand(hook Hook, cmdLine string) (remove func()) {
+	return s.addHook(hook, &hookEntry{
+		f: func(ctx context.Context, s *Shell, args []string, stdout, stderr io.Writer) error {
+			line := cmdLine
+			if len(args) > 0 {
+				line += " " + quoteWords(args)
+			}
+			_, err := s.runLine(ctx, line, nil, stdout, stderr)
+			return err
+		},
+		command: cmdLine,
+	})
+}
+
+// HookCommands returns the command lines registered to the hook by AddHookCommand in registered order.
+func (s *Shell) HookCommands(hook Hook) []string {
+	b := s.events
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	var result []string
+	for _, e := range b.hooks[hook] {
+		if e.command != "" {
+			result = append(result, e.command)
+		}
+	}
+	return result
+}
+
+// RemoveHookCommand removes the command lines registered to the hook by AddHookCommand.
+// It returns false if the command line is not registered.
+func (s *Shell) RemoveHookCommand(hook Hook, cmdLine string) bool {
+	b := s.events
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	entries := b.hooks[hook]
+	rest := make([]*hookEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.command == "" || e.command != cmdLine {
+			rest = append(rest, e)
+		}
+	}
+	b.hooks[hook] = rest
+	return len(rest) != len(entries)
+}
+
+// RunHooks runs the hooks in registered order. Errors of hooks are written to stderr and don't stop others.
+//
+// Run calls PreExec, PreCmd and ChPwd hooks automatically. Hooks don't run while running other hooks.
+func (s *Shell) RunHooks(ctx context.Context, hook Hook, stdout, stderr io.Writer, args ...string) {
+	if ctx.Value(inHookKey{}) != nil {
+		return
+	}
+	b := s.events
+	b.lock.RLock()
+	entries := append([]*hookEntry{}, b.hooks[hook]...)
+	b.lock.RUnlock()
+	if len(entries) == 0 {
+		return
+	}
+	ctx = context.WithValue(ctx, inHookKey{}, hook)
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := e.f(ctx, s, args, stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", hook, err)
+		}
+	}
+}
+
+// runLine parses and runs the command line.
+func (s *Shell) runLine(ctx context.Context, cmdStr string, stdin io.Reader, stdout, stderr io.Writer) (*ExecResult, error) {
+	sessionGroups, err := parser.ParseCommandStr(s.expandAlias(cmdStr))
+	if err != nil {
+		return nil, err
+	}
+	s.emit(CommandParsed{
+		Command:       cmdStr,
+		SessionGroups: sessionGroups,
+	})
+	return s.runSessionGroups(ctx, sessionGroups, stdin, stdout, stderr)
+}
+
+// quoteWords joins the words that can be parsed again.
+func quoteWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w == "" {
+			quoted[i] = "''"
+		} else if strings.ContainsAny(w, " \t\n'\"\\$`|;&<>*?[]#~") {
+			quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+		} else {
+			quoted[i] = w
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,81 @@
+package tish
+
+import (
+	"strings"
+)
+
+// keyNames are the names of special keys.
+var keyNames = map[string]bool{
+	"up": true, "down": true, "left": true, "right": true,
+	"home": true, "end": true, "pgup": true, "pgdown": true,
+	"delete": true, "backspace": true, "tab": true, "enter": true, "esc": true,
+}
+
+// NormalizeKey converts the key like "C-a", `\C-a`, "M-f" and "Ctrl-A" to "ctrl-a" and "alt-f".
+// It returns false if the key is not valid.
+func NormalizeKey(key string) (string, bool) {
+	key = strings.ToLower(strings.TrimPrefix(key, `\`))
+	var ctrl, alt bool
+	for {
+		switch {
+		case strings.HasPrefix(key, "ctrl-"):
+			ctrl, key = true, key[5:]
+		case strings.HasPrefix(key, "c-"):
+			ctrl, key = true, key[2:]
+		case strings.HasPrefix(key, "alt-"):
+			alt, key = true, key[4:]
+		case strings.HasPrefix(key, "meta-"):
+			alt, key = true, key[5:]
+		case strings.HasPrefix(key, "m-"):
+			alt, key = true, key[2:]
+		case strings.HasPrefix(key, `\`) && (ctrl || alt):
+			key = key[1:]
+		default:
+			if len([]rune(key)) != 1 && !keyNames[key] {
+				return "", false
+			}
+			if !ctrl && !alt && !keyNames[key] {
+				// printable characters insert themselves
+				return "", false
+			}
+			if alt {
+				key = "alt-" + key
+			}
+			if ctrl {
+				key = "ctrl-" + key
+			}
+			return key, true
+		}
+	}
+}
+
+// BindKey binds the key to the action of the line editor like "bind" of bash. It returns false if the key is not valid.
+func (s *Shell) BindKey(key, action string) bool {
+	key, ok := NormalizeKey(key)
+	if !ok {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.bindings[key] = action
+	return true
+}
+
+// UnbindKey removes the key binding. It returns false if the key is not bound.
+func (s *Shell) UnbindKey(key string) bool {
+	key, _ = NormalizeKey(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.bindings[key]; !ok {
+		return false
+	}
+	delete(s.bindings, key)
+	return true
+}
+
+// KeyBindings returns a copy of key bindings. Keys are normalized by NormalizeKey.
+func (s *Shell) KeyBindings() map[string]string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return copyMap(s.bindings)
+}
@@ -0,0 +1,46 @@
+package tish
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"ctrl-a", "ctrl-a", true},
+		{"C-a", "ctrl-a", true},
+		{`\C-r`, "ctrl-r", true},
+		{"M-f", "alt-f", true},
+		{`\M-\C-x`, "ctrl-alt-x", true},
+		{"Meta-B", "alt-b", true},
+		{"up", "up", true},
+		{"ctrl-left", "ctrl-left", true},
+		{"a", "", false},
+		{"ctrl-", "", false},
+		{"ctrl-abc", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := NormalizeKey(tt.key)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestShell_BindKey(t *testing.T) {
+	s := NewShell(".", nil)
+	assert.True(t, s.BindKey("C-r", "history-search"))
+	assert.False(t, s.BindKey("x", "forward-char"))
+	assert.Equal(t, map[string]string{"ctrl-r": "history-search"}, s.KeyBindings())
+
+	child := s.Fork()
+	assert.True(t, child.UnbindKey("ctrl-r"))
+	assert.False(t, child.UnbindKey("ctrl-r"))
+	assert.Equal(t, map[string]string{"ctrl-r": "history-search"}, s.KeyBindings())
+}
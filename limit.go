@@ -0,0 +1,217 @@
+package tish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limit is a kind of resource limit in Option.
+type Limit int
+
+const (
+	// CommandTimeoutLimit is Option.CommandTimeout.
+	CommandTimeoutLimit Limit = iota + 1
+	// RunTimeoutLimit is Option.RunTimeout.
+	RunTimeoutLimit
+	// OutputLimit is Option.MaxOutputBytes.
+	OutputLimit
+	// ProcessLimit is Option.MaxProcesses.
+	ProcessLimit
+	// SubstitutionDepthLimit is Option.MaxSubstitutionDepth.
+	SubstitutionDepthLimit
+)
+
+// Exit codes of Shell.Run when the limit is hit.
+const (
+	ExitCodeTimeout           = 124 // same as timeout command of coreutils
+	ExitCodeOutputLimit       = 125
+	ExitCodeProcessLimit      = 122
+	ExitCodeSubstitutionDepth = 123
+)
+
+func (l Limit) String() string {
+	switch l {
+	case CommandTimeoutLimit:
+		return "command timeout"
+	case RunTimeoutLimit:
+		return "run timeout"
+	case OutputLimit:
+		return "output size"
+	case ProcessLimit:
+		return "process count"
+	case SubstitutionDepthLimit:
+		return "command substitution depth"
+	}
+	return "unknown limit"
+}
+
+// OutputLimitBehavior is a behavior when output exceeds Option.MaxOutputBytes.
+type OutputLimitBehavior int
+
+const (
+	// TruncateOutput discards the rest of output and lets the command continue.
+	TruncateOutput OutputLimitBehavior = iota
+	// FailOutput makes further writes fail and stops the commands.
+	FailOutput
+)
+
+// ErrLimitExceeded is returned when the command hits resource limits in Option.
+type ErrLimitExceeded struct {
+	Limit   Limit
+	Command string
+	// Value is the limit value (time.Duration for timeouts, int64 for output and int for others).
+	Value interface{}
+}
+
+func (e ErrLimitExceeded) Error() string {
+	if e.Command != "" {
+		return fmt.Sprintf("%s limit exceeded: %s (%v)", e.Limit, e.Command, e.Value)
+	}
+	return fmt.Sprintf("%s limit exceeded (%v)", e.Limit, e.Value)
+}
+
+// ExitCode returns the exit code that Shell.Run returns for this error.
+func (e ErrLimitExceeded) ExitCode() int {
+	switch e.Limit {
+	case CommandTimeoutLimit, RunTimeoutLimit:
+		return ExitCodeTimeout
+	case OutputLimit:
+		return ExitCodeOutputLimit
+	case ProcessLimit:
+		return ExitCodeProcessLimit
+	case SubstitutionDepthLimit:
+		return ExitCodeSubstitutionDepth
+	}
+	return 1
+}
+
+// limitWriter counts written bytes and stops writing after the limit.
+type limitWriter struct {
+	lock     *sync.Mutex
+	w        io.Writer
+	limit    int64
+	rest     int64
+	behavior OutputLimitBehavior
+	cancel   context.CancelFunc
+	err      *ErrLimitExceeded
+}
+
+func (s *Shell) newLimitWriter(w io.Writer, cancel context.CancelFunc) *limitWriter {
+	opt := s.Option()
+	return &limitWriter{
+		lock:     &sync.Mutex{},
+		w:        w,
+		limit:    opt.MaxOutputBytes,
+		rest:     opt.MaxOutputBytes,
+		behavior: opt.OutputLimit,
+		cancel:   cancel,
+	}
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if int64(len(p)) <= l.rest {
+		n, err := l.w.Write(p)
+		l.rest -= int64(n)
+		return n, err
+	}
+	if l.err == nil {
+		l.err = &ErrLimitExceeded{
+			Limit: OutputLimit,
+			Value: l.limit,
+		}
+	}
+	n, err := l.w.Write(p[:l.rest])
+	l.rest -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	if l.behavior == FailOutput {
+		l.cancel()
+		return n, l.err
+	}
+	return len(p), nil
+}
+
+// exceeded returns an error if the output exceeded the limit.
+func (l *limitWriter) exceeded() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.err == nil {
+		return nil
+	}
+	return l.err
+}
+
+// processCounter counts running processes of the shell and its forks.
+type processCounter struct {
+	lock    *sync.Mutex
+	running int
+}
+
+func newProcessCounter() *processCounter {
+	return &processCounter{
+		lock: &sync.Mutex{},
+	}
+}
+
+// acquireProcess reserves a slot of Option.MaxProcesses. The slots are shared with forks.
+func (s *Shell) acquireProcess(cmd string) error {
+	max := s.Option().MaxProcesses
+	c := s.processes
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if max > 0 && c.running >= max {
+		return &ErrLimitExceeded{
+			Limit:   ProcessLimit,
+			Command: cmd,
+			Value:   max,
+		}
+	}
+	c.running++
+	return nil
+}
+
+func (s *Shell) releaseProcess() {
+	c := s.processes
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.running--
+}
+
+type substitutionDepthKey struct{}
+
+func substitutionDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(substitutionDepthKey{}).(int)
+	return depth
+}
+
+// enterSubstitution returns the context for command substitution.
+func (s *Shell) enterSubstitution(ctx context.Context, cmd string) (context.Context, error) {
+	depth := substitutionDepth(ctx) + 1
+	if limit := s.Option().MaxSubstitutionDepth; limit > 0 && depth > limit {
+		return nil, &ErrLimitExceeded{
+			Limit:   SubstitutionDepthLimit,
+			Command: cmd,
+			Value:   limit,
+		}
+	}
+	return context.WithValue(ctx, substitutionDepthKey{}, depth), nil
+}
+
+// withTimeout returns the context with timeout if the timeout is set.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timedOut returns true if the ctx is expired by its own deadline, not by the parent.
+func timedOut(parent, ctx context.Context) bool {
+	return parent.Err() == nil && ctx.Err() == context.DeadlineExceeded
+}
@@ -0,0 +1,170 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func registerBlockCommand(s *Shell) {
+	s.RegisterCommand(&Command{
+		Name: "block",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+}
+
+func TestShell_Limit_Timeout(t *testing.T) {
+	root := CreateTestFolders(t, "limit-timeout")
+
+	tests := []struct {
+		name   string
+		option Option
+		cmd    string
+		limit  Limit
+	}{
+		{
+			name:   "command timeout",
+			option: Option{CommandTimeout: 50 * time.Millisecond},
+			cmd:    "mock1 | block",
+			limit:  CommandTimeoutLimit,
+		},
+		{
+			name:   "run timeout",
+			option: Option{RunTimeout: 50 * time.Millisecond},
+			cmd:    "mock1 ; block",
+			limit:  RunTimeoutLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(root, []string{}, tt.option)
+			registerMockCommand(t, s, "mock1")
+			registerBlockCommand(s)
+
+			start := time.Now()
+			code, err := s.Run(context.Background(), tt.cmd, io.Discard, io.Discard)
+			var limitErr *ErrLimitExceeded
+			if assert.ErrorAs(t, err, &limitErr) {
+				assert.Equal(t, tt.limit, limitErr.Limit)
+			}
+			assert.Equal(t, ExitCodeTimeout, code)
+			assert.True(t, time.Since(start) < time.Second)
+		})
+	}
+}
+
+func TestShell_Limit_Output(t *testing.T) {
+	root := CreateTestFolders(t, "limit-output")
+
+	tests := []struct {
+		name     string
+		behavior OutputLimitBehavior
+		want     string
+	}{
+		{
+			name:     "truncate",
+			behavior: TruncateOutput,
+			want:     "hellohello",
+		},
+		{
+			name:     "fail",
+			behavior: FailOutput,
+			want:     "hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(root, []string{}, Option{MaxOutputBytes: 10, OutputLimit: tt.behavior})
+			mock1 := registerMockCommand(t, s, "mock1")
+			mock1.Stdout = "hello"
+
+			var stdout bytes.Buffer
+			code, err := s.Run(context.Background(), "mock1 ; mock1 ; mock1 ; mock1", &stdout, io.Discard)
+			var limitErr *ErrLimitExceeded
+			if assert.ErrorAs(t, err, &limitErr) {
+				assert.Equal(t, OutputLimit, limitErr.Limit)
+			}
+			assert.Equal(t, ExitCodeOutputLimit, code)
+			if tt.behavior == TruncateOutput {
+				assert.Equal(t, tt.want, stdout.String())
+			} else {
+				assert.True(t, strings.HasPrefix(stdout.String(), tt.want))
+				assert.True(t, stdout.Len() <= 10)
+			}
+		})
+	}
+}
+
+func TestShell_Limit_Processes(t *testing.T) {
+	root := CreateTestFolders(t, "limit-processes")
+
+	s := NewShell(root, []string{}, Option{MaxProcesses: 2})
+	registerMockCommand(t, s, "mock1")
+	registerMockCommand(t, s, "mock2")
+	registerMockCommand(t, s, "mock3")
+
+	code, err := s.Run(context.Background(), "mock1 | mock2", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	code, err = s.Run(context.Background(), "mock1 | mock2 | mock3", io.Discard, io.Discard)
+	var limitErr *ErrLimitExceeded
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, ProcessLimit, limitErr.Limit)
+	}
+	assert.Equal(t, ExitCodeProcessLimit, code)
+
+	// slots are released
+	code, err = s.Run(context.Background(), "mock1 | mock2", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	// forks share slots
+	assert.NoError(t, s.acquireProcess("running"))
+	assert.NoError(t, s.acquireProcess("running"))
+	code, err = s.Fork().Run(context.Background(), "mock1", io.Discard, io.Discard)
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, ExitCodeProcessLimit, code)
+	s.releaseProcess()
+	s.releaseProcess()
+	code, err = s.Fork().Run(context.Background(), "mock1", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+}
+
+func TestShell_Limit_SubstitutionDepth(t *testing.T) {
+	root := CreateTestFolders(t, "limit-substitution")
+
+	s := NewShell(root, []string{}, Option{MaxSubstitutionDepth: 1})
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock2 := registerMockCommand(t, s, "mock2")
+	mock2.Stdout = "world\n"
+	s.RegisterCommand(&Command{
+		Name: "nested",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			code, err := p.Shell.Run(ctx, "mock1 `mock2`", p.Stdout, p.Stderr)
+			result.SetInternalProcessResult(code)
+			return err
+		},
+	})
+
+	code, err := s.Run(context.Background(), "mock1 hello-`mock2`!", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, []string{"hello-world!"}, mock1.Args)
+
+	code, err = s.Run(context.Background(), "mock1 `nested`", io.Discard, io.Discard)
+	var limitErr *ErrLimitExceeded
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, SubstitutionDepthLimit, limitErr.Limit)
+	}
+	assert.Equal(t, ExitCodeSubstitutionDepth, code)
+}
@@ -0,0 +1,292 @@
+package lineedit
+
+import (
+	"sort"
+	"unicode"
+)
+
+// action is an editing command bound to keys.
+type action func(e *Editor)
+
+// actions are the editing commands. Names are from GNU readline.
+var actions = map[string]action{
+	"beginning-of-line":      func(e *Editor) { e.pos = 0 },
+	"end-of-line":            (*Editor).endOfLine,
+	"backward-char":          (*Editor).backwardChar,
+	"forward-char":           (*Editor).forwardChar,
+	"backward-word":          func(e *Editor) { e.pos = e.wordStart() },
+	"forward-word":           (*Editor).forwardWord,
+	"backward-delete-char":   (*Editor).backwardDeleteChar,
+	"delete-char":            (*Editor).deleteChar,
+	"delete-char-or-eof":     (*Editor).deleteCharOrEOF,
+	"kill-line":              func(e *Editor) { e.kill(e.pos, len(e.buf)) },
+	"unix-line-discard":      func(e *Editor) { e.kill(0, e.pos) },
+	"kill-whole-line":        func(e *Editor) { e.kill(0, len(e.buf)) },
+	"kill-word":              func(e *Editor) { e.kill(e.pos, e.wordEnd()) },
+	"backward-kill-word":     func(e *Editor) { e.kill(e.wordStart(), e.pos) },
+	"unix-word-rubout":       func(e *Editor) { e.kill(e.spaceStart(), e.pos) },
+	"yank":                   (*Editor).yank,
+	"transpose-chars":        (*Editor).transposeChars,
+	"previous-history":       func(e *Editor) { e.moveHistory(-1) },
+	"next-history":           func(e *Editor) { e.moveHistory(1) },
+	"reverse-search-history": (*Editor).startSearch,
+	"complete":               (*Editor).complete,
+	"accept-line":            func(e *Editor) { e.done = true },
+	"abort":                  func(e *Editor) { e.done, e.err = true, ErrPromptAborted },
+	"clear-screen":           (*Editor).clearScreen,
+	"beginning-of-history":   func(e *Editor) { e.moveHistory(-len(e.history)) },
+	"end-of-history":         func(e *Editor) { e.moveHistory(len(e.history)) },
+	"capitalize-word":        func(e *Editor) { e.convertWord(true, unicode.ToUpper) },
+	"upcase-word":            func(e *Editor) { e.convertWord(false, unicode.ToUpper) },
+	"downcase-word":          func(e *Editor) { e.convertWord(false, unicode.ToLower) },
+	"accept-suggestion":      (*Editor).acceptSuggestion,
+}
+
+// defaultBindings are emacs style key bindings like bash.
+var defaultBindings = map[string]string{
+	"ctrl-a":        "beginning-of-line",
+	"home":          "beginning-of-line",
+	"ctrl-e":        "end-of-line",
+	"end":           "end-of-line",
+	"ctrl-b":        "backward-char",
+	"left":          "backward-char",
+	"ctrl-f":        "forward-char",
+	"right":         "forward-char",
+	"alt-b":         "backward-word",
+	"ctrl-left":     "backward-word",
+	"alt-f":         "forward-word",
+	"ctrl-right":    "forward-word",
+	"backspace":     "backward-delete-char",
+	"ctrl-h":        "backward-delete-char",
+	"delete":        "delete-char",
+	"ctrl-d":        "delete-char-or-eof",
+	"ctrl-k":        "kill-line",
+	"ctrl-u":        "unix-line-discard",
+	"alt-d":         "kill-word",
+	"alt-backspace": "backward-kill-word",
+	"ctrl-w":        "unix-word-rubout",
+	"ctrl-y":        "yank",
+	"ctrl-t":        "transpose-chars",
+	"up":            "previous-history",
+	"ctrl-p":        "previous-history",
+	"down":          "next-history",
+	"ctrl-n":        "next-history",
+	"alt-<":         "beginning-of-history",
+	"alt->":         "end-of-history",
+	"ctrl-r":        "reverse-search-history",
+	"tab":           "complete",
+	"enter":         "accept-line",
+	"ctrl-c":        "abort",
+	"ctrl-l":        "clear-screen",
+	"alt-c":         "capitalize-word",
+	"alt-u":         "upcase-word",
+	"alt-l":         "downcase-word",
+}
+
+// Actions returns the names of editing commands that can be bound to keys.
+func Actions() []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Editor) backwardChar() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+// forwardChar moves the cursor or accepts the suggestion at the end of line like fish.
+func (e *Editor) forwardChar() {
+	if e.pos < len(e.buf) {
+		e.pos++
+	} else {
+		e.acceptSuggestion()
+	}
+}
+
+func (e *Editor) endOfLine() {
+	if e.pos == len(e.buf) {
+		e.acceptSuggestion()
+	}
+	e.pos = len(e.buf)
+}
+
+// forwardWord moves the cursor or accepts the next word of the suggestion at the end of line.
+func (e *Editor) forwardWord() {
+	if e.pos < len(e.buf) || e.suggestion == "" {
+		e.pos = e.wordEnd()
+		return
+	}
+	rest := []rune(e.suggestion)
+	i := 0
+	for i < len(rest) && !isWordRune(rest[i]) {
+		i++
+	}
+	for i < len(rest) && isWordRune(rest[i]) {
+		i++
+	}
+	e.insert(rest[:i]...)
+}
+
+func (e *Editor) acceptSuggestion() {
+	e.insert([]rune(e.suggestion)...)
+	e.suggestion = ""
+}
+
+func (e *Editor) backwardDeleteChar() {
+	if e.pos > 0 {
+		e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+		e.pos--
+	}
+}
+
+func (e *Editor) deleteChar() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+// deleteCharOrEOF ends the input at the empty line like Ctrl-D of bash.
+func (e *Editor) deleteCharOrEOF() {
+	if len(e.buf) == 0 {
+		e.done, e.err = true, errEOF
+		return
+	}
+	e.deleteChar()
+}
+
+// kill removes buf[start:end] and keeps it for yank.
+func (e *Editor) kill(start, end int) {
+	if start >= end {
+		return
+	}
+	e.killed = append([]rune{}, e.buf[start:end]...)
+	e.buf = append(e.buf[:start], e.buf[end:]...)
+	e.pos = start
+}
+
+func (e *Editor) yank() {
+	e.insert(e.killed...)
+}
+
+func (e *Editor) transposeChars() {
+	if len(e.buf) < 2 || e.pos == 0 {
+		return
+	}
+	i := e.pos
+	if i == len(e.buf) {
+		i--
+	}
+	e.buf[i-1], e.buf[i] = e.buf[i], e.buf[i-1]
+	e.pos = i + 1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordStart returns the beginning of the word before the cursor.
+func (e *Editor) wordStart() int {
+	i := e.pos
+	for i > 0 && !isWordRune(e.buf[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(e.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor.
+func (e *Editor) wordEnd() int {
+	i := e.pos
+	for i < len(e.buf) && !isWordRune(e.buf[i]) {
+		i++
+	}
+	for i < len(e.buf) && isWordRune(e.buf[i]) {
+		i++
+	}
+	return i
+}
+
+// spaceStart returns the beginning of the space separated word before the cursor (for Ctrl-W).
+func (e *Editor) spaceStart() int {
+	i := e.pos
+	for i > 0 && unicode.IsSpace(e.buf[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(e.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// convertWord converts the word after the cursor. If first is true, only the first letter is converted
+// and the others are lowered.
+func (e *Editor) convertWord(first bool, convert func(rune) rune) {
+	end := e.wordEnd()
+	converted := false
+	for i := e.pos; i < end; i++ {
+		if !isWordRune(e.buf[i]) {
+			continue
+		}
+		if !first || !converted {
+			e.buf[i] = convert(e.buf[i])
+			converted = true
+		} else {
+			e.buf[i] = unicode.ToLower(e.buf[i])
+		}
+	}
+	e.pos = end
+}
+
+// moveHistory moves in the history. Only lines that start with the text typed before moving are shown.
+func (e *Editor) moveHistory(delta int) {
+	if e.histIndex == len(e.history) {
+		e.saved = append([]rune{}, e.buf...)
+	}
+	prefix := string(e.saved)
+	step := 1
+	if delta < 0 {
+		step = -1
+		delta = -delta
+	}
+	i := e.histIndex
+	for ; delta > 0; delta-- {
+		next := i + step
+		for next >= 0 && next < len(e.history) && !hasPrefix(e.history[next], prefix) {
+			next += step
+		}
+		if next < 0 {
+			break
+		}
+		i = next
+		if i >= len(e.history) {
+			i = len(e.history)
+			break
+		}
+	}
+	if i == e.histIndex {
+		return
+	}
+	e.histIndex = i
+	if i == len(e.history) {
+		e.buf = append([]rune{}, e.saved...)
+	} else {
+		e.buf = []rune(e.history[i])
+	}
+	e.pos = len(e.buf)
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+func (e *Editor) clearScreen() {
+	e.write("\x1b[H\x1b[2J")
+	e.cursorRow = 0
+}
@@ -0,0 +1,110 @@
+package lineedit
+
+import (
+	"bufio"
+	"strings"
+	"unicode/utf8"
+)
+
+// key is a key press. Special keys have names like "ctrl-a", "alt-f", "up" and "enter" (same as tish.NormalizeKey).
+// Printable characters have an empty name and the rune.
+type key struct {
+	name string
+	r    rune
+}
+
+var controlNames = map[byte]string{
+	0x09: "tab",
+	0x0a: "enter",
+	0x0d: "enter",
+	0x1b: "esc",
+	0x7f: "backspace",
+}
+
+// csiNames are names of "ESC [ x" and "ESC O x" sequences.
+var csiNames = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left", "H": "home", "F": "end",
+	"1~": "home", "7~": "home", "4~": "end", "8~": "end",
+	"2~": "insert", "3~": "delete", "5~": "pgup", "6~": "pgdown",
+}
+
+// modifiers of "ESC [ 1 ; m x" sequences.
+var modifierNames = map[byte]string{
+	'2': "shift-",
+	'3': "alt-",
+	'5': "ctrl-",
+	'7': "ctrl-alt-",
+}
+
+// readKey reads a key press from the terminal input.
+//
+// ESC is a key itself and the beginning of escape sequences. It is a sequence if more bytes are already buffered,
+// because terminals send the whole sequence at once.
+func readKey(r *bufio.Reader) (key, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+	switch {
+	case c == 0x1b && r.Buffered() > 0:
+		return readEscape(r)
+	case controlNames[c] != "":
+		return key{name: controlNames[c]}, nil
+	case c < 0x20:
+		return key{name: "ctrl-" + string(rune('a'+c-1))}, nil
+	case c < utf8.RuneSelf:
+		return key{r: rune(c)}, nil
+	}
+	r.UnreadByte()
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	return key{r: ch}, nil
+}
+
+func readEscape(r *bufio.Reader) (key, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+	if c != '[' && c != 'O' {
+		// ESC + key is Alt + key
+		r.UnreadByte()
+		k, err := readKey(r)
+		if err != nil {
+			return key{}, err
+		}
+		if strings.HasPrefix(k.name, "ctrl-") {
+			return key{name: "ctrl-alt-" + k.name[5:]}, nil
+		} else if k.name != "" {
+			return key{name: "alt-" + k.name}, nil
+		}
+		return key{name: "alt-" + string(k.r)}, nil
+	}
+	// parameters and the final byte (0x40-0x7e)
+	var seq []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		seq = append(seq, b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+	s := string(seq)
+	if name, ok := csiNames[s]; ok {
+		return key{name: name}, nil
+	}
+	// "1;5C" is Ctrl + Right
+	if len(seq) == 4 && seq[0] == '1' && seq[1] == ';' {
+		if mod, ok := modifierNames[seq[2]]; ok {
+			if name, ok := csiNames[s[3:]]; ok {
+				return key{name: mod + name}, nil
+			}
+		}
+	}
+	return key{name: "unknown"}, nil
+}
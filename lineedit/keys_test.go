@@ -0,0 +1,42 @@
+package lineedit
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  key
+	}{
+		{name: "ascii", input: "a", want: key{r: 'a'}},
+		{name: "multibyte", input: "あ", want: key{r: 'あ'}},
+		{name: "enter", input: "\r", want: key{name: "enter"}},
+		{name: "tab", input: "\t", want: key{name: "tab"}},
+		{name: "backspace", input: "\x7f", want: key{name: "backspace"}},
+		{name: "ctrl", input: "\x01", want: key{name: "ctrl-a"}},
+		{name: "esc", input: "\x1b", want: key{name: "esc"}},
+		{name: "alt", input: "\x1bf", want: key{name: "alt-f"}},
+		{name: "ctrl alt", input: "\x1b\x01", want: key{name: "ctrl-alt-a"}},
+		{name: "alt backspace", input: "\x1b\x7f", want: key{name: "alt-backspace"}},
+		{name: "arrow", input: "\x1b[A", want: key{name: "up"}},
+		{name: "ss3 arrow", input: "\x1bOD", want: key{name: "left"}},
+		{name: "delete", input: "\x1b[3~", want: key{name: "delete"}},
+		{name: "ctrl arrow", input: "\x1b[1;5C", want: key{name: "ctrl-right"}},
+		{name: "unknown", input: "\x1b[15~", want: key{name: "unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			r.Peek(1)
+			got, err := readKey(r)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
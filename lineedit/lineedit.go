@@ -0,0 +1,250 @@
+// Package lineedit is a line editor for terminals with syntax highlighting, completion, autosuggestions, history
+// and key bindings.
+//
+// It replaces liner in the interactive shell, because liner can't color the input line.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ErrPromptAborted is returned by Prompt when Ctrl-C is pressed.
+var ErrPromptAborted = errors.New("prompt aborted")
+
+// errEOF is returned by Prompt when Ctrl-D is pressed at the empty line.
+var errEOF = io.EOF
+
+// Completer returns candidates of the word at pos (byte offset of the cursor). The new line is head + candidate + tail.
+// Shell.Complete has this signature.
+type Completer func(line string, pos int) (head string, completions []string, tail string)
+
+// Highlighter returns the line with ANSI escape sequences. It must not change the visible text.
+type Highlighter func(line string) string
+
+// Suggester returns the line suggested for the typed line, or "" if there is no suggestion.
+// Shell.Suggest has this signature.
+type Suggester func(line string) string
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+// Editor reads lines from the terminal.
+type Editor struct {
+	in          *os.File
+	out         io.Writer
+	reader      *bufio.Reader
+	history     []string
+	completer   Completer
+	highlighter Highlighter
+	suggester   Suggester
+	bindings    map[string]string
+	// width returns the width of the terminal
+	width func() int
+
+	// state of the current prompt
+	prompt    string
+	buf       []rune
+	pos       int
+	histIndex int
+	saved     []rune
+	killed    []rune
+	// suggestion is the rest of the suggested line shown after the cursor
+	suggestion string
+	lastAction string
+	done       bool
+	err        error
+	// cursorRow and endRow are rows of the cursor and the end of line from the first line of the prompt
+	cursorRow int
+	endRow    int
+	search    *search
+}
+
+// New creates Editor that reads keys from in (usually os.Stdin) and writes the line to out.
+// out can be io.MultiWriter to record the session, because the editor doesn't write to the terminal directly.
+func New(in *os.File, out io.Writer) *Editor {
+	e := newEditor(out)
+	e.in = in
+	e.reader = bufio.NewReader(in)
+	e.width = func() int {
+		if width, ok := terminalWidth(in); ok {
+			return width
+		}
+		return 80
+	}
+	return e
+}
+
+func newEditor(out io.Writer) *Editor {
+	bindings := make(map[string]string, len(defaultBindings))
+	for k, a := range defaultBindings {
+		bindings[k] = a
+	}
+	return &Editor{
+		out:      out,
+		bindings: bindings,
+		width:    func() int { return 80 },
+	}
+}
+
+// SetCompleter sets the completer called by Tab.
+func (e *Editor) SetCompleter(completer Completer) {
+	e.completer = completer
+}
+
+// SetHighlighter sets the function that colors the input line.
+func (e *Editor) SetHighlighter(highlighter Highlighter) {
+	e.highlighter = highlighter
+}
+
+// SetSuggester sets the function that suggests the line while typing. The suggestion is shown as dimmed text
+// and Right (or End) at the end of line accepts it.
+func (e *Editor) SetSuggester(suggester Suggester) {
+	e.suggester = suggester
+}
+
+// AppendHistory adds the line to the history. Empty lines and the same line as the last one are skipped.
+func (e *Editor) AppendHistory(line string) {
+	if line == "" || len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// Bind binds the key (normalized by tish.NormalizeKey like "ctrl-x") to the action. See Actions for action names.
+func (e *Editor) Bind(key, action string) error {
+	if _, ok := actions[action]; !ok {
+		return fmt.Errorf("unknown action: %s", action)
+	}
+	e.bindings[key] = action
+	return nil
+}
+
+// Unbind reverts the key to the default binding, or removes the binding if the key has no default.
+func (e *Editor) Unbind(key string) {
+	if action, ok := defaultBindings[key]; ok {
+		e.bindings[key] = action
+	} else {
+		delete(e.bindings, key)
+	}
+}
+
+// Prompt prints the prompt and reads a line. The prompt can have colors but it must not have new lines.
+//
+// It returns ErrPromptAborted for Ctrl-C and io.EOF for Ctrl-D at the empty line.
+// If the input is not a terminal, it reads a line without editing.
+func (e *Editor) Prompt(prompt string) (string, error) {
+	restore, err := MakeRaw(e.in)
+	if err != nil {
+		return e.readPlainLine(prompt)
+	}
+	defer restore()
+	return e.readLine(e.reader, prompt)
+}
+
+func (e *Editor) readPlainLine(prompt string) (string, error) {
+	e.write(prompt)
+	line, err := e.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readLine is the main loop of the editor. The terminal must be in raw mode.
+func (e *Editor) readLine(r *bufio.Reader, prompt string) (string, error) {
+	e.prompt = prompt
+	e.buf, e.pos = nil, 0
+	e.histIndex, e.saved = len(e.history), nil
+	e.lastAction, e.done, e.err = "", false, nil
+	e.cursorRow, e.endRow = 0, 0
+	e.search = nil
+	e.suggestion = ""
+	e.refresh()
+	for !e.done {
+		k, err := readKey(r)
+		if err != nil {
+			e.write("\r\n")
+			return "", err
+		}
+		e.handle(k)
+		if e.done {
+			break
+		}
+		e.updateSuggestion()
+		e.refresh()
+	}
+	if e.search != nil {
+		e.acceptSearch()
+	}
+	e.suggestion = ""
+	if e.err == ErrPromptAborted {
+		// erase the suggestion
+		e.refresh()
+		e.write("^C\r\n")
+		return "", e.err
+	}
+	// move the cursor to the end and the next line
+	e.pos = len(e.buf)
+	e.refresh()
+	e.write("\r\n")
+	return string(e.buf), e.err
+}
+
+// handle runs the action bound to the key or inserts the character.
+func (e *Editor) handle(k key) {
+	if e.search != nil && e.handleSearch(k) {
+		return
+	}
+	if k.name == "" {
+		e.insert(k.r)
+		e.lastAction = ""
+		return
+	}
+	name, ok := e.bindings[k.name]
+	if !ok {
+		e.beep()
+		return
+	}
+	actions[name](e)
+	e.lastAction = name
+}
+
+// updateSuggestion asks the suggester when the cursor is at the end of line.
+func (e *Editor) updateSuggestion() {
+	e.suggestion = ""
+	if e.suggester == nil || e.search != nil || len(e.buf) == 0 || e.pos != len(e.buf) {
+		return
+	}
+	line := string(e.buf)
+	if s := e.suggester(line); len(s) > len(line) && strings.HasPrefix(s, line) {
+		e.suggestion = s[len(line):]
+	}
+}
+
+func (e *Editor) insert(r ...rune) {
+	buf := make([]rune, 0, len(e.buf)+len(r))
+	buf = append(buf, e.buf[:e.pos]...)
+	buf = append(buf, r...)
+	e.buf = append(buf, e.buf[e.pos:]...)
+	e.pos += len(r)
+}
+
+func (e *Editor) write(s string) {
+	io.WriteString(e.out, s)
+}
+
+func (e *Editor) beep() {
+	e.write("\a")
+}
+
+// textWidth returns the width of the text on the terminal without escape sequences.
+func textWidth(s string) int {
+	return runewidth.StringWidth(ansiPattern.ReplaceAllString(s, ""))
+}
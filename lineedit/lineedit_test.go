@@ -0,0 +1,197 @@
+package lineedit
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// typeKeys feeds the keys to the editor and returns the line.
+func typeKeys(e *Editor, keys string) (string, error) {
+	return e.readLine(bufio.NewReader(strings.NewReader(keys)), "$ ")
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []string
+		keys    string
+		want    string
+		wantErr error
+	}{
+		{name: "type", keys: "echo hello\r", want: "echo hello"},
+		{name: "multibyte", keys: "echo こんにちは\r", want: "echo こんにちは"},
+		{name: "backspace", keys: "echoo\x7f hi\r", want: "echo hi"},
+		{name: "insert at the beginning", keys: "cho\x01e\r", want: "echo"},
+		{name: "arrow keys", keys: "eho\x1b[D\x1b[Dc\x1b[C\x1b[Cx\r", want: "echox"},
+		{name: "home and end", keys: "b\x1b[Ha\x1b[Fc\r", want: "abc"},
+		{name: "word move", keys: "echo hello world\x1bbX\x1bb\x1bbY\r", want: "echo Yhello Xworld"},
+		{name: "ctrl word move", keys: "ab cd\x1b[1;5DX\r", want: "ab Xcd"},
+		{name: "kill line and yank", keys: "echo hello\x1bb\x0b\x01\x19 \r", want: "hello echo "},
+		{name: "backward kill line", keys: "echo hello\x15ls\r", want: "ls"},
+		{name: "unix word rubout", keys: "echo foo/bar\x17baz\r", want: "echo baz"},
+		{name: "backward kill word", keys: "echo foo/bar\x1b\x7fbaz\r", want: "echo foo/baz"},
+		{name: "kill word", keys: "echo hello world\x01\x1bd\r", want: " hello world"},
+		{name: "transpose", keys: "ehco\x1b[D\x1b[D\x14\r", want: "echo"},
+		{name: "delete", keys: "echo\x01\x1b[3~\x04\r", want: "ho"},
+		{name: "upcase word", keys: "echo\x01\x1bu\r", want: "ECHO"},
+		{name: "capitalize word", keys: "echo\x01\x1bc\r", want: "Echo"},
+		{name: "ctrl-d at empty line", keys: "\x04", wantErr: io.EOF},
+		{name: "ctrl-c", keys: "echo\x03", wantErr: ErrPromptAborted},
+		{name: "end of input", keys: "echo", wantErr: io.EOF},
+		{name: "previous history", history: []string{"ls", "pwd"}, keys: "\x1b[A\x1b[A\r", want: "ls"},
+		{name: "next history", history: []string{"ls", "pwd"}, keys: "x\x1b[A\x1b[A\x1b[B\x1b[B\r", want: "x"},
+		{name: "history prefix", history: []string{"echo a", "ls", "echo b", "pwd"}, keys: "ec\x10\x10\r", want: "echo a"},
+		{name: "history stops at the oldest", history: []string{"ls"}, keys: "\x10\x10\x10\r", want: "ls"},
+		{name: "reverse search", history: []string{"echo a", "ls -l", "echo b"}, keys: "\x12ech\r", want: "echo b"},
+		{name: "reverse search again", history: []string{"echo a", "ls -l", "echo b"}, keys: "\x12ech\x12\r", want: "echo a"},
+		{name: "reverse search then edit", history: []string{"ls -l"}, keys: "\x12-l\x05a\r", want: "ls -la"},
+		{name: "reverse search cancel", history: []string{"ls -l"}, keys: "pwd\x12ls\x07\r", want: "pwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEditor(&bytes.Buffer{})
+			for _, h := range tt.history {
+				e.AppendHistory(h)
+			}
+			got, err := typeKeys(e, tt.keys)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEditor_Complete(t *testing.T) {
+	completer := func(line string, pos int) (string, []string, string) {
+		start := strings.LastIndexByte(line[:pos], ' ') + 1
+		var result []string
+		for _, c := range []string{"echo", "exit", "export", "ls"} {
+			if strings.HasPrefix(c, line[start:pos]) {
+				result = append(result, c)
+			}
+		}
+		return line[:start], result, line[pos:]
+	}
+	tests := []struct {
+		name     string
+		keys     string
+		want     string
+		wantList bool
+	}{
+		{name: "single candidate", keys: "l\t -a\r", want: "ls -a"},
+		{name: "common prefix", keys: "exp\t\r", want: "export"},
+		{name: "list candidates", keys: "e\t\t\r", want: "e", wantList: true},
+		{name: "insert common prefix", keys: "ex\t\r", want: "ex"},
+		{name: "middle of line", keys: "ec x\x01\x06\x06\t\r", want: "echo x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			e := newEditor(out)
+			e.SetCompleter(completer)
+			got, err := typeKeys(e, tt.keys)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantList, strings.Contains(out.String(), "echo    exit    export"))
+		})
+	}
+}
+
+func TestEditor_Bind(t *testing.T) {
+	e := newEditor(&bytes.Buffer{})
+	assert.NoError(t, e.Bind("ctrl-x", "beginning-of-line"))
+	assert.Error(t, e.Bind("ctrl-y", "no-such-action"))
+	got, err := typeKeys(e, "bc\x18a\r")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", got)
+}
+
+func TestEditor_Unbind(t *testing.T) {
+	e := newEditor(&bytes.Buffer{})
+	assert.NoError(t, e.Bind("ctrl-x", "beginning-of-line"))
+	assert.NoError(t, e.Bind("ctrl-a", "end-of-line"))
+	e.Unbind("ctrl-x")
+	e.Unbind("ctrl-a")
+	// ctrl-x beeps and ctrl-a moves to the beginning again
+	got, err := typeKeys(e, "bc\x18\x01a\r")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", got)
+}
+
+func TestEditor_Highlight(t *testing.T) {
+	out := &bytes.Buffer{}
+	e := newEditor(out)
+	e.SetHighlighter(func(line string) string {
+		return strings.Replace(line, "echo", "\x1b[34mecho\x1b[0m", 1)
+	})
+	got, err := typeKeys(e, "echo hi\r")
+	assert.NoError(t, err)
+	assert.Equal(t, "echo hi", got)
+	assert.Contains(t, out.String(), "$ \x1b[34mecho\x1b[0m hi")
+}
+
+func TestEditor_Wrap(t *testing.T) {
+	out := &bytes.Buffer{}
+	e := newEditor(out)
+	e.width = func() int { return 10 }
+	_, err := typeKeys(e, "12345678\x01")
+	assert.Equal(t, io.EOF, err)
+	// the line fills the first row, so the cursor at the beginning is one row up from the end
+	assert.True(t, strings.HasSuffix(out.String(), "$ 12345678\r\n\x1b[1A\r\x1b[2C\r\n"), "%q", out.String())
+}
+
+func TestAppendHistory(t *testing.T) {
+	e := newEditor(&bytes.Buffer{})
+	e.AppendHistory("ls")
+	e.AppendHistory("ls")
+	e.AppendHistory("")
+	e.AppendHistory("pwd")
+	assert.Equal(t, []string{"ls", "pwd"}, e.history)
+}
+
+func TestEditor_Suggest(t *testing.T) {
+	suggester := func(line string) string {
+		for _, s := range []string{"git commit -m", "ls -la"} {
+			if strings.HasPrefix(s, line) {
+				return s
+			}
+		}
+		return ""
+	}
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{name: "not accepted", keys: "gi\r", want: "gi"},
+		{name: "right", keys: "gi\x1b[C\r", want: "git commit -m"},
+		{name: "end", keys: "l\x05\r", want: "ls -la"},
+		{name: "forward word", keys: "g\x1bf\x1bf\r", want: "git commit"},
+		{name: "right in the middle of line", keys: "gi\x02\x1b[C\r", want: "gi"},
+		{name: "type after accepting", keys: "ls\x1b[Ch\r", want: "ls -lah"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEditor(&bytes.Buffer{})
+			e.SetSuggester(suggester)
+			got, err := typeKeys(e, tt.keys)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	out := &bytes.Buffer{}
+	e := newEditor(out)
+	e.SetSuggester(suggester)
+	_, err := typeKeys(e, "gi")
+	assert.Equal(t, io.EOF, err)
+	assert.Contains(t, out.String(), "$ gi\x1b[2mt commit -m\x1b[0m\r\x1b[4C", "suggestion is dimmed and the cursor stays")
+}
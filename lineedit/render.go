@@ -0,0 +1,134 @@
+package lineedit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// refresh redraws the prompt and the line, and moves the cursor.
+//
+// The line can wrap, so it goes back to the first row of the prompt, clears below and writes everything again.
+func (e *Editor) refresh() {
+	prompt, line, pos := e.prompt, string(e.buf), e.pos
+	if e.search != nil {
+		prompt, line, pos = e.searchPrompt()
+	}
+	var b strings.Builder
+	if e.cursorRow > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", e.cursorRow)
+	}
+	b.WriteString("\r\x1b[J")
+	b.WriteString(prompt)
+	if e.highlighter != nil {
+		b.WriteString(e.highlighter(line))
+	} else {
+		b.WriteString(line)
+	}
+	if e.suggestion != "" {
+		b.WriteString("\x1b[2m" + e.suggestion + "\x1b[0m")
+	}
+	width := e.width()
+	promptWidth := textWidth(prompt)
+	total := promptWidth + runewidth.StringWidth(line) + runewidth.StringWidth(e.suggestion)
+	if total > 0 && total%width == 0 {
+		// the terminal keeps the cursor at the last column, so move it to the next line explicitly
+		b.WriteString("\r\n")
+	}
+	cursor := promptWidth + runewidth.StringWidth(string([]rune(line)[:pos]))
+	e.endRow = total / width
+	e.cursorRow = cursor / width
+	if up := e.endRow - e.cursorRow; up > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", up)
+	}
+	b.WriteString("\r")
+	if col := cursor % width; col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	e.write(b.String())
+}
+
+// printBelow prints lines under the input line. The prompt is written again by the next refresh.
+func (e *Editor) printBelow(lines []string) {
+	var b strings.Builder
+	if down := e.endRow - e.cursorRow; down > 0 {
+		fmt.Fprintf(&b, "\x1b[%dB", down)
+	}
+	b.WriteString("\r\n")
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\r\n")
+	}
+	e.write(b.String())
+	e.cursorRow, e.endRow = 0, 0
+}
+
+// columns lays out words in columns like ls.
+func columns(words []string, width int) []string {
+	maxWidth := 0
+	for _, w := range words {
+		if n := runewidth.StringWidth(w); n > maxWidth {
+			maxWidth = n
+		}
+	}
+	cols := width / (maxWidth + 2)
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (len(words) + cols - 1) / cols
+	lines := make([]string, rows)
+	for i, w := range words {
+		row := i % rows
+		if i+rows < len(words) {
+			w = runewidth.FillRight(w, maxWidth+2)
+		}
+		lines[row] += w
+	}
+	return lines
+}
+
+// complete completes the word at the cursor.
+//
+// A single candidate or the common prefix of candidates is inserted. If nothing can be inserted, Tab beeps
+// and the second Tab prints candidates.
+func (e *Editor) complete() {
+	if e.completer == nil {
+		e.beep()
+		return
+	}
+	line := string(e.buf)
+	bytePos := len(string(e.buf[:e.pos]))
+	head, completions, tail := e.completer(line, bytePos)
+	if len(completions) == 0 {
+		e.beep()
+		return
+	}
+	prefix := completions[0]
+	for _, c := range completions[1:] {
+		prefix = commonPrefix(prefix, c)
+	}
+	if len(completions) == 1 || len(head)+len(prefix) > bytePos {
+		e.buf = []rune(head + prefix + tail)
+		e.pos = len([]rune(head + prefix))
+		return
+	}
+	if e.lastAction != "complete" {
+		e.beep()
+		return
+	}
+	sorted := append([]string{}, completions...)
+	sort.Strings(sorted)
+	e.printBelow(columns(sorted, e.width()))
+}
+
+// commonPrefix returns the common prefix of a and b without breaking runes.
+func commonPrefix(a, b string) string {
+	ra, rb := []rune(a), []rune(b)
+	i := 0
+	for i < len(ra) && i < len(rb) && ra[i] == rb[i] {
+		i++
+	}
+	return string(ra[:i])
+}
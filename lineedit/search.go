@@ -0,0 +1,84 @@
+package lineedit
+
+import (
+	"strings"
+)
+
+// search is the state of reverse-search-history (Ctrl-R).
+type search struct {
+	query []rune
+	// index is the history index of the match. It is -1 if nothing matches.
+	index int
+	// original line to restore when the search is canceled
+	buf []rune
+	pos int
+}
+
+func (e *Editor) startSearch() {
+	e.search = &search{index: len(e.history), buf: e.buf, pos: e.pos}
+}
+
+// find finds the newest history entry that has the query from the index.
+func (e *Editor) find(from int) {
+	query := string(e.search.query)
+	for i := from; i >= 0; i-- {
+		if i < len(e.history) && strings.Contains(e.history[i], query) {
+			e.search.index = i
+			return
+		}
+	}
+	e.search.index = -1
+	e.beep()
+}
+
+// handleSearch handles keys while searching like bash. It returns false if the key ends the search
+// and it should be handled as usual.
+func (e *Editor) handleSearch(k key) bool {
+	s := e.search
+	switch {
+	case k.name == "":
+		s.query = append(s.query, k.r)
+		e.find(s.index)
+	case k.name == "backspace" || k.name == "ctrl-h":
+		if len(s.query) > 0 {
+			s.query = s.query[:len(s.query)-1]
+			e.find(len(e.history) - 1)
+		}
+	case e.bindings[k.name] == "reverse-search-history":
+		if s.index > 0 {
+			e.find(s.index - 1)
+		}
+	case k.name == "ctrl-g" || k.name == "esc" || k.name == "ctrl-c":
+		e.buf, e.pos = s.buf, s.pos
+		e.search = nil
+	default:
+		e.acceptSearch()
+		return false
+	}
+	return true
+}
+
+// acceptSearch puts the match to the line.
+func (e *Editor) acceptSearch() {
+	if s := e.search; s.index >= 0 && s.index < len(e.history) {
+		e.buf = []rune(e.history[s.index])
+		e.pos = len(e.buf)
+		e.histIndex = s.index
+	}
+	e.search = nil
+}
+
+// searchPrompt returns the prompt, the line and the cursor position while searching.
+func (e *Editor) searchPrompt() (string, string, int) {
+	s := e.search
+	prompt := "(reverse-i-search)`" + string(s.query) + "': "
+	if s.index < 0 {
+		prompt = "(failed reverse-i-search)`" + string(s.query) + "': "
+	}
+	if s.index < 0 || s.index >= len(e.history) {
+		return prompt, string(s.buf), s.pos
+	}
+	match := e.history[s.index]
+	pos := len([]rune(match[:strings.Index(match, string(s.query))]))
+	return prompt, match, pos
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// MakeRaw returns an error because raw mode is not supported, so Prompt reads lines without editing.
+func MakeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
+
+func terminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,46 @@
+//go:build windows
+// +build windows
+
+package lineedit
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// MakeRaw puts the console into raw mode and returns the function to restore it.
+// It disables line input and echo, and enables VT sequences of input and output,
+// so keys are read as same escape sequences as Unix terminals.
+func MakeRaw(f *os.File) (func(), error) {
+	in := windows.Handle(f.Fd())
+	var inMode uint32
+	if err := windows.GetConsoleMode(in, &inMode); err != nil {
+		return nil, err
+	}
+	raw := inMode &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT)
+	if err := windows.SetConsoleMode(in, raw|windows.ENABLE_VIRTUAL_TERMINAL_INPUT); err != nil {
+		return nil, err
+	}
+	out := windows.Handle(os.Stdout.Fd())
+	var outMode uint32
+	outOK := windows.GetConsoleMode(out, &outMode) == nil
+	if outOK {
+		windows.SetConsoleMode(out, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+	return func() {
+		windows.SetConsoleMode(in, inMode)
+		if outOK {
+			windows.SetConsoleMode(out, outMode)
+		}
+	}, nil
+}
+
+// terminalWidth returns the width of the console. The screen buffer belongs to the output, not f.
+func terminalWidth(f *os.File) (int, bool) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(os.Stdout.Fd()), &info); err != nil {
+		return 0, false
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, true
+}
@@ -2,7 +2,11 @@ package tish
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"io"
+	"os"
+	"sync"
 	"time"
 )
 
@@ -11,11 +15,16 @@ type LogRoot struct {
 	Option Option `json:"option"`
 }
 
+// LogLine is a line of stream. Epoch is milliseconds since Unix epoch when the line is written.
 type LogLine struct {
 	Epoch int64  `json:"epoch"`
 	Line  string `json:"line"`
 }
 
+// Log is a record of an executed process.
+//
+// PreTasks are command substitutions that run before the process,
+// ChildTasks are processes that the process runs (like time command).
 type Log struct {
 	Epoch int64 `json:"start_at"`
 
@@ -31,19 +40,213 @@ type Log struct {
 	Stdout []LogLine `json:"stdout"`
 	Stderr []LogLine `json:"stderr"`
 
-	Command string `json:"command"`
+	Command    string `json:"command"`
+	WorkingDir string `json:"working_dir"`
 
 	PreTasks   []Log `json:"pre_tasks"`
 	ChildTasks []Log `json:"child_tasks"`
 }
 
-func DumpLog(w io.Writer) error {
-	return nil
+func epoch(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
 }
 
-func DumpLogToString() (string, error) {
+// logRecorder keeps logs of the shell. All nodes are guarded by the lock.
+type logRecorder struct {
+	lock *sync.Mutex
+	logs []*logNode
+}
+
+func newLogRecorder() *logRecorder {
+	return &logRecorder{
+		lock: &sync.Mutex{},
+	}
+}
+
+type logNode struct {
+	recorder *logRecorder
+	parent   *logNode
+	preTask  bool
+	log      Log
+	pre      []*logNode
+	children []*logNode
+
+	stdin, stdout, stderr *lineRecorder
+}
+
+type logKey struct{}
+
+// newLog creates a log of the process. It returns nil if logging is disabled.
+//
+// If the ctx is the one of running process, the log becomes its child task.
+func (s *Shell) newLog(ctx context.Context) *logNode {
+	if !s.Option().Log {
+		return nil
+	}
+	parent, _ := ctx.Value(logKey{}).(*logNode)
+	return &logNode{
+		recorder: s.logs,
+		parent:   parent,
+	}
+}
+
+// newPreLog creates a log of command substitution of the parent.
+func (s *Shell) newPreLog(parent *logNode) *logNode {
+	if parent == nil {
+		return nil
+	}
+	node := &logNode{
+		recorder: parent.recorder,
+		preTask:  true,
+	}
+	parent.recorder.lock.Lock()
+	defer parent.recorder.lock.Unlock()
+	parent.pre = append(parent.pre, node)
+	return node
+}
+
+// start registers the log and tees the streams of the process.
+func (n *logNode) start(ctx context.Context, p *Process) context.Context {
+	n.stdout = newLineRecorder()
+	n.stderr = newLineRecorder()
+	if p.Stdout != nil {
+		p.Stdout = io.MultiWriter(p.Stdout, n.stdout)
+	}
+	if p.Stderr != nil {
+		p.Stderr = io.MultiWriter(p.Stderr, n.stderr)
+	}
+	// reading terminal can't be stopped, so it is not recorded
+	if p.Stdin != nil && p.Stdin != os.Stdin {
+		n.stdin = newLineRecorder()
+		p.Stdin = io.TeeReader(p.Stdin, n.stdin)
+	}
+
+	n.recorder.lock.Lock()
+	defer n.recorder.lock.Unlock()
+	n.log.Epoch = epoch(time.Now())
+	n.log.Command = quoteCommand(p.Cmd, p.Args)
+	n.log.WorkingDir = p.Shell.WorkingDir()
+	n.log.Env = p.Env()
+	// pre tasks are already registered by newPreLog
+	if !n.preTask {
+		if n.parent != nil {
+			n.parent.children = append(n.parent.children, n)
+		} else {
+			n.recorder.logs = append(n.recorder.logs, n)
+		}
+	}
+	return context.WithValue(ctx, logKey{}, n)
+}
+
+// finish stores the result of the process.
+func (n *logNode) finish(r *ExecResult, exitCode int) {
+	n.recorder.lock.Lock()
+	defer n.recorder.lock.Unlock()
+	n.log.UserTime = r.UserTime()
+	n.log.SystemTime = r.SystemTime()
+	n.log.WallTime = r.WallTime()
+	if r.WallTime() > 0 {
+		n.log.CPU = float64(r.UserTime()+r.SystemTime()) * 100 / float64(r.WallTime())
+	}
+	n.log.ExitCode = exitCode
+	n.log.Stdout = n.stdout.flush()
+	n.log.Stderr = n.stderr.flush()
+	if n.stdin != nil {
+		n.log.Stdin = n.stdin.flush()
+	}
+}
+
+// export converts the node to Log. The caller should hold the lock.
+func (n *logNode) export() Log {
+	l := n.log
+	l.PreTasks = nil
+	for _, p := range n.pre {
+		l.PreTasks = append(l.PreTasks, p.export())
+	}
+	l.ChildTasks = nil
+	for _, c := range n.children {
+		l.ChildTasks = append(l.ChildTasks, c.export())
+	}
+	return l
+}
+
+// quoteCommand returns command line that can be parsed again. Arguments that have special characters are quoted.
+func quoteCommand(cmd string, args []string) string {
+	return quoteWords(append([]string{cmd}, args...))
+}
+
+// lineRecorder splits written bytes into lines with timestamps.
+type lineRecorder struct {
+	lock  *sync.Mutex
+	buf   []byte
+	lines []LogLine
+}
+
+func newLineRecorder() *lineRecorder {
+	return &lineRecorder{
+		lock: &sync.Mutex{},
+	}
+}
+
+func (l *lineRecorder) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := epoch(time.Now())
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i == -1 {
+			break
+		}
+		l.lines = append(l.lines, LogLine{Epoch: now, Line: string(l.buf[:i])})
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush returns recorded lines including the last line without new line.
+func (l *lineRecorder) flush() []LogLine {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.buf) > 0 {
+		l.lines = append(l.lines, LogLine{Epoch: epoch(time.Now()), Line: string(l.buf)})
+		l.buf = nil
+	}
+	return l.lines
+}
+
+// Log returns the execution log. Option.Log should be true to record the log.
+func (s *Shell) Log() LogRoot {
+	opt := s.Option()
+	s.logs.lock.Lock()
+	defer s.logs.lock.Unlock()
+	root := LogRoot{
+		Logs:   []Log{},
+		Option: opt,
+	}
+	for _, n := range s.logs.logs {
+		root.Logs = append(root.Logs, n.export())
+	}
+	return root
+}
+
+// ClearLog removes recorded logs.
+func (s *Shell) ClearLog() {
+	s.logs.lock.Lock()
+	defer s.logs.lock.Unlock()
+	s.logs.logs = nil
+}
+
+// DumpLog writes the execution log in JSON.
+func (s *Shell) DumpLog(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(s.Log())
+}
+
+func (s *Shell) DumpLogToString() (string, error) {
 	var buffer bytes.Buffer
-	err := DumpLog(&buffer)
+	err := s.DumpLog(&buffer)
 	if err != nil {
 		return "", err
 	}
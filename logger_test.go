@@ -0,0 +1,85 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_Log(t *testing.T) {
+	root := CreateTestFolders(t, "log")
+
+	s := NewShell(root, []string{"KEY=value"}, Option{Log: true})
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock1.Stdout = "hello\nworld"
+	mock2 := registerMockCommand(t, s, "mock2")
+	mock2.Stdout = "sub"
+	mock3 := registerMockCommand(t, s, "mock3")
+	mock3.Stderr = "error\n"
+	s.RegisterCommand(&Command{
+		Name: "parent",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			res, err := p.Shell.RunChildProcess(ctx, p, "mock1", nil)
+			result.SetInternalProcessResult(res.ExitCode())
+			return err
+		},
+	})
+
+	_, err := s.Run(context.Background(), "mock1 `mock2` | mock3 ; parent", io.Discard, io.Discard)
+	assert.NoError(t, err)
+
+	log := s.Log()
+	assert.True(t, log.Option.Log)
+	if assert.Len(t, log.Logs, 3) {
+		first := log.Logs[0]
+		assert.Equal(t, "mock1 sub", first.Command)
+		assert.Equal(t, "value", first.Env["KEY"])
+		assert.Equal(t, 0, first.ExitCode)
+		assert.NotZero(t, first.Epoch)
+		if assert.Len(t, first.Stdout, 2) {
+			assert.Equal(t, "hello", first.Stdout[0].Line)
+			assert.Equal(t, "world", first.Stdout[1].Line)
+		}
+		if assert.Len(t, first.PreTasks, 1) {
+			assert.Equal(t, "mock2", first.PreTasks[0].Command)
+			assert.Equal(t, []LogLine{{Epoch: first.PreTasks[0].Stdout[0].Epoch, Line: "sub"}}, first.PreTasks[0].Stdout)
+		}
+
+		second := log.Logs[1]
+		assert.Equal(t, "mock3", second.Command)
+		assert.Len(t, second.Stdin, 2)
+		if assert.Len(t, second.Stderr, 1) {
+			assert.Equal(t, "error", second.Stderr[0].Line)
+		}
+
+		third := log.Logs[2]
+		assert.Equal(t, "parent", third.Command)
+		if assert.Len(t, third.ChildTasks, 1) {
+			assert.Equal(t, "mock1", third.ChildTasks[0].Command)
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, s.DumpLog(&buf))
+	var loaded LogRoot
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &loaded))
+	assert.Equal(t, log, loaded)
+
+	s.ClearLog()
+	assert.Empty(t, s.Log().Logs)
+}
+
+func TestShell_Log_Disabled(t *testing.T) {
+	root := CreateTestFolders(t, "log-disabled")
+
+	s := NewShell(root, []string{})
+	registerMockCommand(t, s, "mock1")
+
+	_, err := s.Run(context.Background(), "mock1", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Empty(t, s.Log().Logs)
+}
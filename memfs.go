@@ -0,0 +1,550 @@
+package tish
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const maxSymlinkHops = 40
+
+// MemoryFileSystem is an in-memory FileSystem.
+//
+// It is useful for running applets in virtual environment or testing.
+// Symbolic links are supported, but ".." in path is resolved lexically.
+type MemoryFileSystem struct {
+	lock  sync.Mutex
+	nodes map[string]*memNode
+}
+
+var _ FileSystem = &MemoryFileSystem{}
+
+type memNode struct {
+	mode    os.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+}
+
+// NewMemoryFileSystem creates empty MemoryFileSystem that has only root directory.
+func NewMemoryFileSystem() *MemoryFileSystem {
+	return &MemoryFileSystem{
+		nodes: map[string]*memNode{},
+	}
+}
+
+// WriteFile creates a file with parent directories. It is a helper to prepare contents.
+func (m *MemoryFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := m.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ReadFile returns the content of the file. It is a helper to check results.
+func (m *MemoryFileSystem) ReadFile(name string) ([]byte, error) {
+	f, err := m.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (m *MemoryFileSystem) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemoryFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := m.lookup(p)
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, pathError("open", name, os.ErrNotExist)
+	case !ok:
+		if err := m.checkParent("open", name, p); err != nil {
+			return nil, err
+		}
+		node = &memNode{
+			mode:    perm & os.ModePerm,
+			modTime: time.Now(),
+		}
+		m.nodes[p] = node
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, pathError("open", name, os.ErrExist)
+	case node.mode.IsDir() && writable:
+		return nil, pathError("open", name, syscall.EISDIR)
+	case flag&os.O_TRUNC != 0 && writable:
+		node.data = nil
+		node.modTime = time.Now()
+	}
+	return &memFile{
+		fs:       m,
+		name:     name,
+		node:     node,
+		append:   flag&os.O_APPEND != 0,
+		readable: flag&os.O_WRONLY == 0,
+		writable: writable,
+	}, nil
+}
+
+func (m *MemoryFileSystem) Stat(name string) (os.FileInfo, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stat("stat", name, true)
+}
+
+func (m *MemoryFileSystem) Lstat(name string) (os.FileInfo, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stat("lstat", name, false)
+}
+
+func (m *MemoryFileSystem) stat(op, name string, follow bool) (os.FileInfo, error) {
+	p, err := m.resolve(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := m.lookup(p)
+	if !ok {
+		return nil, pathError(op, name, os.ErrNotExist)
+	}
+	return node.info(filepath.Base(p)), nil
+}
+
+func (m *MemoryFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := m.lookup(p)
+	if !ok {
+		return nil, pathError("readdir", name, os.ErrNotExist)
+	} else if !node.mode.IsDir() {
+		return nil, pathError("readdir", name, syscall.ENOTDIR)
+	}
+	var result []os.DirEntry
+	for _, child := range m.children(p) {
+		result = append(result, memDirEntry{info: m.nodes[child].info(filepath.Base(child))})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+	return result, nil
+}
+
+func (m *MemoryFileSystem) Mkdir(name string, perm os.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.lookup(p); ok {
+		return pathError("mkdir", name, os.ErrExist)
+	}
+	if err := m.checkParent("mkdir", name, p); err != nil {
+		return err
+	}
+	m.nodes[p] = &memNode{
+		mode:    os.ModeDir | perm&os.ModePerm,
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+func (m *MemoryFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var dirs []string
+	for current := cleanMemPath(path); !isRoot(current); current = filepath.Dir(current) {
+		dirs = append(dirs, current)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir, err := m.resolve("mkdir", dirs[i], true)
+		if err != nil {
+			return err
+		}
+		if node, ok := m.nodes[dir]; ok {
+			if !node.mode.IsDir() {
+				return pathError("mkdir", dirs[i], syscall.ENOTDIR)
+			}
+			continue
+		}
+		m.nodes[dir] = &memNode{
+			mode:    os.ModeDir | perm&os.ModePerm,
+			modTime: time.Now(),
+		}
+	}
+	return nil
+}
+
+func (m *MemoryFileSystem) Rename(oldpath, newpath string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	oldP, err := m.resolve("rename", oldpath, false)
+	if err != nil {
+		return err
+	}
+	newP, err := m.resolve("rename", newpath, false)
+	if err != nil {
+		return err
+	}
+	node, ok := m.lookup(oldP)
+	if !ok || isRoot(oldP) {
+		return pathError("rename", oldpath, os.ErrNotExist)
+	}
+	if oldP == newP {
+		return nil
+	}
+	if isSubPath(oldP, newP) {
+		return pathError("rename", newpath, syscall.EINVAL)
+	}
+	if dest, ok := m.lookup(newP); ok {
+		if dest.mode.IsDir() && (!node.mode.IsDir() || len(m.children(newP)) > 0) {
+			return pathError("rename", newpath, syscall.EEXIST)
+		}
+		if !dest.mode.IsDir() && node.mode.IsDir() {
+			return pathError("rename", newpath, syscall.ENOTDIR)
+		}
+	}
+	if err := m.checkParent("rename", newpath, newP); err != nil {
+		return err
+	}
+	prefix := oldP + string(filepath.Separator)
+	for p, n := range m.nodes {
+		if strings.HasPrefix(p, prefix) {
+			delete(m.nodes, p)
+			m.nodes[newP+string(filepath.Separator)+strings.TrimPrefix(p, prefix)] = n
+		}
+	}
+	delete(m.nodes, oldP)
+	m.nodes[newP] = node
+	return nil
+}
+
+func (m *MemoryFileSystem) Remove(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	node, ok := m.lookup(p)
+	if !ok {
+		return pathError("remove", name, os.ErrNotExist)
+	}
+	if node.mode.IsDir() && len(m.children(p)) > 0 {
+		return pathError("remove", name, syscall.ENOTEMPTY)
+	}
+	delete(m.nodes, p)
+	return nil
+}
+
+func (m *MemoryFileSystem) RemoveAll(path string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("removeall", path, false)
+	if err != nil {
+		return nil
+	}
+	prefix := p + string(filepath.Separator)
+	for key := range m.nodes {
+		if key == p || strings.HasPrefix(key, prefix) {
+			delete(m.nodes, key)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryFileSystem) Chmod(name string, mode os.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	node, ok := m.lookup(p)
+	if !ok {
+		return pathError("chmod", name, os.ErrNotExist)
+	}
+	node.mode = node.mode&^os.ModePerm | mode&os.ModePerm
+	return nil
+}
+
+func (m *MemoryFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	node, ok := m.lookup(p)
+	if !ok {
+		return pathError("chtimes", name, os.ErrNotExist)
+	}
+	node.modTime = mtime
+	return nil
+}
+
+func (m *MemoryFileSystem) Symlink(oldname, newname string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("symlink", newname, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.lookup(p); ok {
+		return pathError("symlink", newname, os.ErrExist)
+	}
+	if err := m.checkParent("symlink", newname, p); err != nil {
+		return err
+	}
+	m.nodes[p] = &memNode{
+		mode:    os.ModeSymlink | 0o777,
+		target:  oldname,
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+func (m *MemoryFileSystem) Readlink(name string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	node, ok := m.lookup(p)
+	if !ok {
+		return "", pathError("readlink", name, os.ErrNotExist)
+	} else if node.mode&os.ModeSymlink == 0 {
+		return "", pathError("readlink", name, syscall.EINVAL)
+	}
+	return node.target, nil
+}
+
+func (m *MemoryFileSystem) EvalSymlinks(path string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, err := m.resolve("lstat", path, true)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := m.lookup(p); !ok {
+		return "", pathError("lstat", path, os.ErrNotExist)
+	}
+	return p, nil
+}
+
+// resolve returns the real path of name by following symbolic links in parent directories.
+// If follow is true, the last element is also followed.
+func (m *MemoryFileSystem) resolve(op, name string, follow bool) (string, error) {
+	p := cleanMemPath(name)
+	hops := 0
+	for {
+		root := filepath.VolumeName(p) + string(filepath.Separator)
+		parts := strings.Split(strings.TrimPrefix(p, root), string(filepath.Separator))
+		current := root
+		restarted := false
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			next := filepath.Join(current, part)
+			node, ok := m.nodes[next]
+			last := i == len(parts)-1
+			if ok && node.mode&os.ModeSymlink != 0 && (follow || !last) {
+				hops++
+				if hops > maxSymlinkHops {
+					return "", pathError(op, name, syscall.ELOOP)
+				}
+				target := node.target
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(current, target)
+				}
+				p = cleanMemPath(filepath.Join(append([]string{target}, parts[i+1:]...)...))
+				restarted = true
+				break
+			} else if !ok && !last {
+				return "", pathError(op, name, os.ErrNotExist)
+			} else if ok && !last && !node.mode.IsDir() {
+				return "", pathError(op, name, syscall.ENOTDIR)
+			}
+			current = next
+		}
+		if !restarted {
+			return current, nil
+		}
+	}
+}
+
+func (m *MemoryFileSystem) lookup(p string) (*memNode, bool) {
+	if isRoot(p) {
+		if _, ok := m.nodes[p]; !ok {
+			m.nodes[p] = &memNode{mode: os.ModeDir | 0o755}
+		}
+	}
+	node, ok := m.nodes[p]
+	return node, ok
+}
+
+func (m *MemoryFileSystem) checkParent(op, name, p string) error {
+	parent, ok := m.lookup(filepath.Dir(p))
+	if !ok {
+		return pathError(op, name, os.ErrNotExist)
+	} else if !parent.mode.IsDir() {
+		return pathError(op, name, syscall.ENOTDIR)
+	}
+	return nil
+}
+
+func (m *MemoryFileSystem) children(p string) []string {
+	var result []string
+	for key := range m.nodes {
+		if key != p && filepath.Dir(key) == p {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func cleanMemPath(name string) string {
+	if !filepath.IsAbs(name) {
+		name = string(filepath.Separator) + name
+	}
+	return filepath.Clean(name)
+}
+
+func isRoot(p string) bool {
+	return filepath.Dir(p) == p
+}
+
+func pathError(op, path string, err error) error {
+	return &os.PathError{Op: op, Path: path, Err: err}
+}
+
+func (n *memNode) info(name string) os.FileInfo {
+	size := int64(len(n.data))
+	if n.mode&os.ModeSymlink != 0 {
+		size = int64(len(n.target))
+	}
+	return &memFileInfo{
+		name:    name,
+		size:    size,
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+type memFile struct {
+	fs       *MemoryFileSystem
+	name     string
+	node     *memNode
+	offset   int
+	append   bool
+	readable bool
+	writable bool
+	closed   bool
+}
+
+func (f *memFile) Read(b []byte) (int, error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+	switch {
+	case f.closed:
+		return 0, pathError("read", f.name, os.ErrClosed)
+	case f.node.mode.IsDir():
+		return 0, pathError("read", f.name, syscall.EISDIR)
+	case !f.readable:
+		return 0, pathError("read", f.name, os.ErrPermission)
+	case f.offset >= len(f.node.data):
+		return 0, io.EOF
+	}
+	n := copy(b, f.node.data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *memFile) Write(b []byte) (int, error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+	if f.closed {
+		return 0, pathError("write", f.name, os.ErrClosed)
+	} else if !f.writable {
+		return 0, pathError("write", f.name, os.ErrPermission)
+	}
+	if f.append {
+		f.offset = len(f.node.data)
+	}
+	if end := f.offset + len(b); end > len(f.node.data) {
+		f.node.data = append(f.node.data, make([]byte, end-len(f.node.data))...)
+	}
+	copy(f.node.data[f.offset:], b)
+	f.offset += len(b)
+	f.node.modTime = time.Now()
+	return len(b), nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+	if f.closed {
+		return pathError("close", f.name, os.ErrClosed)
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.lock.Lock()
+	defer f.fs.lock.Unlock()
+	return f.node.info(filepath.Base(f.name)), nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() interface{}   { return nil }
+
+type memDirEntry struct {
+	info os.FileInfo
+}
+
+func (e memDirEntry) Name() string               { return e.info.Name() }
+func (e memDirEntry) IsDir() bool                { return e.info.IsDir() }
+func (e memDirEntry) Type() os.FileMode          { return e.info.Mode().Type() }
+func (e memDirEntry) Info() (os.FileInfo, error) { return e.info, nil }
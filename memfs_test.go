@@ -0,0 +1,108 @@
+package tish
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem(t *testing.T) {
+	fsys := NewMemoryFileSystem()
+
+	t.Run("write and read", func(t *testing.T) {
+		assert.NoError(t, fsys.WriteFile("/work/test.txt", []byte("hello"), 0o644))
+		content, err := fsys.ReadFile("/work/test.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(content))
+
+		fi, err := fsys.Stat("/work/test.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), fi.Size())
+		assert.Equal(t, os.FileMode(0o644), fi.Mode())
+	})
+	t.Run("append", func(t *testing.T) {
+		f, err := fsys.OpenFile("/work/test.txt", os.O_WRONLY|os.O_APPEND, 0)
+		assert.NoError(t, err)
+		io.WriteString(f, " world")
+		f.Close()
+		content, _ := fsys.ReadFile("/work/test.txt")
+		assert.Equal(t, "hello world", string(content))
+	})
+	t.Run("not exist", func(t *testing.T) {
+		_, err := fsys.Open("/work/missing.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = fsys.OpenFile("/missing/new.txt", os.O_WRONLY|os.O_CREATE, 0o644)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("read dir", func(t *testing.T) {
+		assert.NoError(t, fsys.MkdirAll("/work/sub/deep", 0o755))
+		assert.NoError(t, fsys.WriteFile("/work/a.txt", nil, 0o644))
+		entries, err := fsys.ReadDir("/work")
+		assert.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.Equal(t, []string{"a.txt", "sub", "test.txt"}, names)
+	})
+	t.Run("symlink", func(t *testing.T) {
+		assert.NoError(t, fsys.Symlink("/work/sub", "/link"))
+		assert.NoError(t, fsys.WriteFile("/link/via-link.txt", []byte("link"), 0o644))
+		content, err := fsys.ReadFile("/work/sub/via-link.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "link", string(content))
+
+		fi, err := fsys.Lstat("/link")
+		assert.NoError(t, err)
+		assert.True(t, fi.Mode()&os.ModeSymlink != 0)
+		target, err := fsys.Readlink("/link")
+		assert.NoError(t, err)
+		assert.Equal(t, "/work/sub", target)
+		real, err := fsys.EvalSymlinks("/link/deep")
+		assert.NoError(t, err)
+		assert.Equal(t, "/work/sub/deep", real)
+	})
+	t.Run("rename directory", func(t *testing.T) {
+		assert.NoError(t, fsys.Rename("/work/sub", "/moved"))
+		assert.True(t, IsDir(fsys, "/moved/deep"))
+		assert.True(t, FileExists(fsys, "/moved/via-link.txt"))
+		assert.False(t, FileExists(fsys, "/work/sub"))
+	})
+	t.Run("remove", func(t *testing.T) {
+		assert.Error(t, fsys.Remove("/moved"))
+		assert.NoError(t, fsys.RemoveAll("/moved"))
+		assert.False(t, FileExists(fsys, "/moved/deep"))
+	})
+	t.Run("chmod", func(t *testing.T) {
+		assert.NoError(t, fsys.Chmod("/work/a.txt", 0o600))
+		fi, _ := fsys.Stat("/work/a.txt")
+		assert.Equal(t, os.FileMode(0o600), fi.Mode())
+	})
+}
+
+func TestShell_MemoryFileSystem(t *testing.T) {
+	root, fsys := CreateTestFileSystem(t, "shell-memfs", map[string]string{
+		"a.go":  "",
+		"b.go":  "",
+		"dir/":  "",
+		"input": "hello",
+	})
+	s := NewShell(root, nil)
+	s.SetFileSystem(fsys)
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock1.Stdout = "output"
+
+	_, err := s.Run(context.Background(), "mock1 *.go < input > output.txt", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "b.go"}, mock1.Args)
+	assert.Equal(t, "hello", mock1.Stdin)
+	content, err := fsys.ReadFile(root + "/output.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "output", string(content))
+
+	assert.NoError(t, s.SetWorkingDir("cd", "dir", nil))
+	assert.Equal(t, root+"/dir", s.WorkingDir())
+}
@@ -0,0 +1,60 @@
+package tish
+
+// Middleware wraps Executor to add cross-cutting behavior like logging, timing, policy checks, retries or
+// argument rewriting. It should call next to run the wrapped command.
+//
+//	func logging(next tish.Executor) tish.Executor {
+//		return func(ctx context.Context, result *tish.ExecResult, p *tish.Process) error {
+//			log.Println(p.Cmd, p.Args)
+//			return next(ctx, result, p)
+//		}
+//	}
+type Middleware func(next Executor) Executor
+
+// Use adds middlewares that wrap all commands including external commands.
+//
+// Middlewares registered earlier run outer. Middlewares for all commands run outer than the ones for a command name.
+func (s *Shell) Use(middlewares ...Middleware) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// UseFor adds middlewares that wrap the command of the name.
+func (s *Shell) UseFor(cmdName string, middlewares ...Middleware) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.commandMiddlewares == nil {
+		s.commandMiddlewares = map[string][]Middleware{}
+	}
+	s.commandMiddlewares[cmdName] = append(s.commandMiddlewares[cmdName], middlewares...)
+}
+
+// wrapCommand returns the copy of command whose executor is wrapped by middlewares.
+// Commands that have Options are wrapped to handle "--help" inside of middlewares.
+func (s *Shell) wrapCommand(cmdName string, cmd *Command) *Command {
+	s.lock.RLock()
+	chain := append(append([]Middleware{}, s.middlewares...), s.commandMiddlewares[cmdName]...)
+	s.lock.RUnlock()
+	if len(chain) == 0 && cmd.Options == nil {
+		return cmd
+	}
+	executor := cmd.Executor
+	if cmd.Options != nil {
+		executor = helpExecutor(cmd, executor)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		executor = chain[i](executor)
+	}
+	wrapped := *cmd
+	wrapped.Executor = executor
+	return &wrapped
+}
+
+func copyMiddlewares(src map[string][]Middleware) map[string][]Middleware {
+	result := make(map[string][]Middleware, len(src))
+	for k, v := range src {
+		result[k] = append([]Middleware{}, v...)
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recordMiddleware(name string, calls *[]string) Middleware {
+	return func(next Executor) Executor {
+		return func(ctx context.Context, result *ExecResult, p *Process) error {
+			*calls = append(*calls, name+":"+p.Cmd)
+			return next(ctx, result, p)
+		}
+	}
+}
+
+func TestShell_Use(t *testing.T) {
+	root := CreateTestFolders(t, "middleware")
+
+	s := NewShell(root, []string{})
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock2 := registerMockCommand(t, s, "mock2")
+
+	var calls []string
+	s.Use(recordMiddleware("outer", &calls), recordMiddleware("inner", &calls))
+	s.UseFor("mock2", recordMiddleware("mock2-only", &calls))
+	s.UseFor("mock2", func(next Executor) Executor {
+		return func(ctx context.Context, result *ExecResult, p *Process) error {
+			p.Args = append([]string{"--rewritten"}, p.Args...)
+			return next(ctx, result, p)
+		}
+	})
+
+	_, err := s.Run(context.Background(), "mock1 a ; mock2 b", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"outer:mock1", "inner:mock1",
+		"outer:mock2", "inner:mock2", "mock2-only:mock2",
+	}, calls)
+	assert.Equal(t, []string{"a"}, mock1.Args)
+	assert.Equal(t, []string{"--rewritten", "b"}, mock2.Args)
+
+	// forked shell copies middlewares
+	calls = nil
+	child := s.Fork()
+	child.UseFor("mock1", recordMiddleware("child", &calls))
+	_, err = s.Run(context.Background(), "mock1", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer:mock1", "inner:mock1"}, calls)
+}
+
+func TestShell_Use_PolicyCheck(t *testing.T) {
+	root := CreateTestFolders(t, "middleware-policy")
+
+	s := NewShell(root, []string{})
+	mock := registerMockCommand(t, s, "mock")
+	s.UseFor("mock", func(next Executor) Executor {
+		return func(ctx context.Context, result *ExecResult, p *Process) error {
+			if len(p.Args) > 0 && p.Args[0] == "--force" {
+				io.WriteString(p.Stderr, "mock: --force is not allowed\n")
+				return result.SetInternalProcessResult(1)
+			}
+			return next(ctx, result, p)
+		}
+	})
+
+	var stderr bytes.Buffer
+	code, err := s.Run(context.Background(), "mock --force", io.Discard, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, code)
+	assert.Nil(t, mock.Args)
+	assert.Equal(t, "mock: --force is not allowed\n", stderr.String())
+}
+
+func TestShell_Use_ExternalCommand(t *testing.T) {
+	if _, err := exec.LookPath("true"); err != nil {
+		t.Skip("true command is not found")
+	}
+	root := CreateTestFolders(t, "middleware-external")
+
+	s := NewShell(root, []string{})
+	var calls []string
+	s.Use(recordMiddleware("all", &calls))
+	s.UseFor("true", recordMiddleware("true", &calls))
+
+	code, err := s.Run(context.Background(), "true", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, []string{"all:true", "true:true"}, calls)
+}
@@ -0,0 +1,129 @@
+package tish
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrOption is returned when the option can't be set.
+type ErrOption struct {
+	Name   string
+	Reason string
+}
+
+func (e ErrOption) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Reason)
+}
+
+// readOnlyOptions are the sandbox settings. They can be set only by NewShell, so scripts can't escape from the sandbox.
+var readOnlyOptions = map[string]bool{
+	"safe_mode":  true,
+	"allow_path": true,
+	"commands":   true,
+}
+
+// limitOptions are the resource limits. They are read-only in SafeMode, so untrusted scripts can't remove them.
+var limitOptions = map[string]bool{
+	"kill_timeout":           true,
+	"command_timeout":        true,
+	"run_timeout":            true,
+	"max_output_bytes":       true,
+	"output_limit":           true,
+	"max_processes":          true,
+	"max_substitution_depth": true,
+}
+
+// Option returns the option of the shell.
+func (s *Shell) Option() Option {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.option
+}
+
+// optionField returns the field of Option that has the JSON name.
+func optionField(opt *Option, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(opt).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// SetOption sets the option by the name of the JSON tag (like "globstar" and "kill_timeout").
+//
+// The value is JSON ("true", "10", `["a", "b"]`). Strings and durations ("5s") can be written with or without quotes.
+// safe_mode, allow_path and commands can't be changed. Limits like command_timeout and max_processes can't be changed
+// in SafeMode.
+func (s *Shell) SetOption(name, value string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if readOnlyOptions[name] || s.option.SafeMode && limitOptions[name] {
+		return ErrOption{Name: name, Reason: "read-only option"}
+	}
+	field, ok := optionField(&s.option, name)
+	if !ok {
+		return ErrOption{Name: name, Reason: "unknown option"}
+	}
+	ptr := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		switch field.Interface().(type) {
+		case time.Duration:
+			var str string
+			if json.Unmarshal([]byte(value), &str) == nil {
+				value = str
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return ErrOption{Name: name, Reason: err.Error()}
+			}
+			ptr.Elem().SetInt(int64(d))
+		case string:
+			ptr.Elem().SetString(value)
+		default:
+			return ErrOption{Name: name, Reason: fmt.Sprintf("invalid value %q", value)}
+		}
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
+
+// OptionValues returns options of the shell as JSON names and values. Durations are formatted like "5s".
+func (s *Shell) OptionValues() map[string]string {
+	opt := s.Option()
+	result := make(map[string]string)
+	v := reflect.ValueOf(opt)
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		switch value := v.Field(i).Interface().(type) {
+		case time.Duration:
+			result[name] = value.String()
+		default:
+			b, _ := json.Marshal(value)
+			result[name] = string(b)
+		}
+	}
+	return result
+}
+
+// OptionNames returns JSON names of options in alphabetical order.
+func OptionNames() []string {
+	var names []string
+	t := reflect.TypeOf(Option{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,77 @@
+package tish
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_SetOption(t *testing.T) {
+	s := NewShell(".", nil)
+	assert.NoError(t, s.SetOption("globstar", "true"))
+	assert.NoError(t, s.SetOption("kill_timeout", "5s"))
+	assert.NoError(t, s.SetOption("command_timeout", "1000000000"))
+	assert.NoError(t, s.SetOption("no_match", "2"))
+	opt := s.Option()
+	assert.True(t, opt.GlobStar)
+	assert.Equal(t, 5*time.Second, opt.KillTimeout)
+	assert.Equal(t, time.Second, opt.CommandTimeout)
+	assert.Equal(t, FailGlob, opt.NoMatch)
+
+	assert.Equal(t, ErrOption{Name: "safe_mode", Reason: "read-only option"}, s.SetOption("safe_mode", "true"))
+	assert.Equal(t, ErrOption{Name: "unknown", Reason: "unknown option"}, s.SetOption("unknown", "1"))
+	assert.Error(t, s.SetOption("globstar", "yes"))
+	assert.False(t, s.Option().SafeMode)
+}
+
+func TestShell_SetOption_SafeMode(t *testing.T) {
+	s := NewShell(".", nil, Option{SafeMode: true, CommandTimeout: 200 * time.Millisecond, MaxProcesses: 4})
+	assert.NoError(t, s.SetOption("globstar", "true"))
+	for _, name := range []string{"safe_mode", "allow_path", "commands", "kill_timeout", "command_timeout", "run_timeout",
+		"max_output_bytes", "output_limit", "max_processes", "max_substitution_depth"} {
+		assert.Equal(t, ErrOption{Name: name, Reason: "read-only option"}, s.SetOption(name, "0"), name)
+	}
+	opt := s.Option()
+	assert.True(t, opt.SafeMode)
+	assert.Equal(t, 200*time.Millisecond, opt.CommandTimeout)
+	assert.Equal(t, 4, opt.MaxProcesses)
+}
+
+func TestShell_SetOption_WhileRunning(t *testing.T) {
+	s := NewShell(".", nil)
+	s.RegisterCommand(&Command{
+		Name: "noop",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) error {
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, s.SetOption("xtrace", "false"))
+			assert.NoError(t, s.SetOption("command_timeout", "1s"))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		_, err := s.Run(context.Background(), "noop *.go | noop", io.Discard, io.Discard)
+		assert.NoError(t, err)
+	}
+	wg.Wait()
+}
+
+func TestShell_OptionValues(t *testing.T) {
+	s := NewShell(".", nil, Option{DotGlob: true, KillTimeout: time.Second})
+	values := s.OptionValues()
+	assert.Equal(t, "true", values["dotglob"])
+	assert.Equal(t, "1s", values["kill_timeout"])
+	assert.Equal(t, "null", values["allow_path"])
+	assert.Len(t, values, len(OptionNames()))
+	assert.Contains(t, OptionNames(), "xtrace")
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrQuoteNotClosed = errors.New("quote not closed")
+)
+
+// Word represents a word of command line string.
+//
+// It is split by same rule as shlex (spaces, single/double quote, back slash escape and # comment),
+// but it keeps the position in source string and information about quoting.
+//
+//	ls "my dir"/*.go
+//
+//	Text:    `my dir/*.go`
+//	Pattern: ``              (same as Text)
+//	Quoted:  true
+type Word struct {
+	// Text is a word after removing quotes and escapes.
+	Text string
+	// Pattern is a glob pattern of the word. Wildcard characters in quotes are escaped by back slash.
+	// It is empty if it is as same as Text.
+	Pattern string
+	// Quoted is true if any part of the word is quoted or escaped.
+	Quoted bool
+	// Start and End are byte offsets of the word in source string.
+	Start int
+	End   int
+}
+
+const (
+	wordSpaces   = " \t\r\n"
+	wildcardRune = "*?[]\\"
+)
+
+// SplitWords splits command line string into words.
+//
+// If the last word has unclosed quote, it returns all words including unclosed one with ErrQuoteNotClosed.
+func SplitWords(src string) ([]Word, error) {
+	var result []Word
+	var text, pattern strings.Builder
+	var quote byte // 0, '\'' or '"'
+	inWord := false
+	quoted := false
+	start := 0
+
+	flush := func(end int) {
+		w := Word{
+			Text:   text.String(),
+			Quoted: quoted,
+			Start:  start,
+			End:    end,
+		}
+		if p := pattern.String(); p != w.Text {
+			w.Pattern = p
+		}
+		result = append(result, w)
+		text.Reset()
+		pattern.Reset()
+		inWord = false
+		quoted = false
+	}
+	writeLiteral := func(c byte) {
+		text.WriteByte(c)
+		if strings.IndexByte(wildcardRune, c) != -1 {
+			pattern.WriteByte('\\')
+		}
+		pattern.WriteByte(c)
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch quote {
+		case '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				writeLiteral(c)
+			}
+			continue
+		case '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && i+1 < len(src):
+				i++
+				writeLiteral(src[i])
+			default:
+				writeLiteral(c)
+			}
+			continue
+		}
+		if !inWord {
+			if strings.IndexByte(wordSpaces, c) != -1 {
+				continue
+			}
+			if c == '#' {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+				continue
+			}
+			inWord = true
+			start = i
+		}
+		switch {
+		case strings.IndexByte(wordSpaces, c) != -1:
+			flush(i)
+		case c == '\'' || c == '"':
+			quote = c
+			quoted = true
+		case c == '\\':
+			quoted = true
+			if i+1 < len(src) {
+				i++
+				writeLiteral(src[i])
+			}
+		default:
+			text.WriteByte(c)
+			pattern.WriteByte(c)
+		}
+	}
+	if inWord {
+		flush(len(src))
+	}
+	if quote != 0 {
+		return result, ErrQuoteNotClosed
+	}
+	return result, nil
+}
+
+// HasWildcard returns true if the pattern has unescaped wildcard characters.
+func HasWildcard(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
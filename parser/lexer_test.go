@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []Word
+		wantErr bool
+	}{
+		{
+			name: "simple words",
+			src:  `ls -l`,
+			want: []Word{
+				{Text: "ls", Start: 0, End: 2},
+				{Text: "-l", Start: 3, End: 5},
+			},
+		},
+		{
+			name: "quoted words",
+			src:  `echo "hello world" 'single'`,
+			want: []Word{
+				{Text: "echo", Start: 0, End: 4},
+				{Text: "hello world", Quoted: true, Start: 5, End: 18},
+				{Text: "single", Quoted: true, Start: 19, End: 27},
+			},
+		},
+		{
+			name: "wildcard",
+			src:  `ls *.go`,
+			want: []Word{
+				{Text: "ls", Start: 0, End: 2},
+				{Text: "*.go", Start: 3, End: 7},
+			},
+		},
+		{
+			name: "quoted wildcard",
+			src:  `ls "*".go \?.txt`,
+			want: []Word{
+				{Text: "ls", Start: 0, End: 2},
+				{Text: "*.go", Pattern: `\*.go`, Quoted: true, Start: 3, End: 9},
+				{Text: "?.txt", Pattern: `\?.txt`, Quoted: true, Start: 10, End: 16},
+			},
+		},
+		{
+			name: "comment",
+			src:  "ls # comment",
+			want: []Word{
+				{Text: "ls", Start: 0, End: 2},
+			},
+		},
+		{
+			name: "quote not closed",
+			src:  `echo "hello`,
+			want: []Word{
+				{Text: "echo", Start: 0, End: 4},
+				{Text: "hello", Quoted: true, Start: 5, End: 11},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitWords(tt.src)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrQuoteNotClosed)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -3,8 +3,6 @@ package parser
 import (
 	"errors"
 	"fmt"
-	"github.com/google/shlex"
-	"strings"
 )
 
 var (
@@ -71,28 +69,51 @@ func parseBackquote(source string) ([]string, error) {
 //
 //   []Tests{"current branch is ", " and current commit is ", "."}
 //   []Sessions{{"git", "branch"}, {"git", "rev-parse", "HEAD"}}
+//
+// Pattern is a glob pattern of Term that quoted wildcard characters are escaped.
+// It is empty if it is as same as Term.
 type Fragment struct {
 	Term     string
+	Pattern  string
 	Sessions []*Session
 	Texts    []string
 }
 
+// GlobPattern returns glob pattern of the fragment.
+// It returns false if the fragment doesn't have wildcard or it is a result of back quote.
+func (f Fragment) GlobPattern() (string, bool) {
+	if len(f.Sessions) > 0 {
+		return "", false
+	}
+	pattern := f.Pattern
+	if pattern == "" {
+		pattern = f.Term
+	}
+	if !HasWildcard(pattern) {
+		return "", false
+	}
+	return pattern, true
+}
+
 func ParseCommandStr(cmdStr string) ([][]*Session, error) {
 	lastSession := &Session{}
 	pipedSessions := []*Session{lastSession}
 	result := [][]*Session{pipedSessions}
 
-	l := shlex.NewLexer(strings.NewReader(cmdStr))
+	words, err := SplitWords(cmdStr)
+	if err != nil {
+		return nil, err
+	}
 	var nextStdin, nextStdout, nextStderr, nextStdoutAndStderr, nextAppend bool
 	var lastField string
-	for {
-		var err error
-		field, err := l.Next()
-		if err != nil {
-			break
-		}
+	for _, word := range words {
+		field := word.Text
 		lastField = field
-		switch field {
+		operator := field
+		if word.Quoted {
+			operator = ""
+		}
+		switch operator {
 		case "|":
 			if nextStdin || nextStdout || nextStderr || nextStdoutAndStderr {
 				return nil, fmt.Errorf("'%s' after redirect", field)
@@ -180,7 +201,8 @@ func ParseCommandStr(cmdStr string) ([][]*Session, error) {
 			}
 			if len(terms) == 1 {
 				lastSession.Fragments = append(lastSession.Fragments, Fragment{
-					Term: field,
+					Term:    field,
+					Pattern: word.Pattern,
 				})
 			} else {
 				f := Fragment{}
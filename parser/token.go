@@ -0,0 +1,252 @@
+package parser
+
+import (
+	"strings"
+)
+
+// TokenKind is a kind of Token.
+type TokenKind int
+
+const (
+	// WordToken is a command name, an argument or a redirect target.
+	WordToken TokenKind = iota
+	// OperatorToken is a separator of commands: "|", ";", "||" and "&&".
+	OperatorToken
+	// RedirectToken is a redirect operator like "<", ">" and "2>>".
+	RedirectToken
+	// CommentToken is from "#" to the end of line.
+	CommentToken
+)
+
+// PartKind is a kind of Part of word.
+type PartKind int
+
+const (
+	// LiteralPart is an unquoted text.
+	LiteralPart PartKind = iota
+	// SingleQuotedPart is a text in single quotes including quotes.
+	SingleQuotedPart
+	// DoubleQuotedPart is a text in double quotes including quotes. Variables and back quotes in it are separate parts.
+	DoubleQuotedPart
+	// EscapedPart is a back slash and the next character.
+	EscapedPart
+	// VariablePart is "$NAME" or "${NAME}". Variables are expanded even in single quotes.
+	VariablePart
+	// BackquotePart is a command substitution including back quotes.
+	BackquotePart
+)
+
+// Part is a piece of word. Start and End are byte offsets in the source string.
+type Part struct {
+	Kind  PartKind
+	Start int
+	End   int
+	// Unclosed is true if the quote or the back quote is not closed.
+	Unclosed bool
+}
+
+// Token is a word, an operator or a comment of command line with its position.
+type Token struct {
+	Kind TokenKind
+	// Word has text of the token. It is empty for CommentToken.
+	Word Word
+	// Parts are pieces of WordToken.
+	Parts []Part
+	// Start and End are byte offsets in the source string.
+	Start int
+	End   int
+	// Command is true if the word is at the command position.
+	Command bool
+}
+
+var operatorTokens = map[string]bool{
+	"|":  true,
+	";":  true,
+	"||": true,
+	"&&": true,
+}
+
+var redirectTokens = map[string]bool{
+	"<":   true,
+	">":   true,
+	">>":  true,
+	"2>":  true,
+	"2>>": true,
+	"&>":  true,
+	"&>>": true,
+}
+
+// Tokenize splits command line string into tokens for syntax highlighting.
+//
+// Unlike ParseCommandStr, it keeps comments and positions and it doesn't stop at the incomplete part.
+// If a quote or a back quote is not closed, it returns all tokens with ErrQuoteNotClosed or ErrBackquoteNotClosed.
+func Tokenize(src string) ([]Token, error) {
+	words, err := SplitWords(src)
+	var result []Token
+	commandPos := true
+	afterRedirect := false
+	prevEnd := 0
+	for _, w := range words {
+		result = appendComment(result, src, prevEnd, w.Start)
+		prevEnd = w.End
+		t := Token{Kind: WordToken, Word: w, Start: w.Start, End: w.End}
+		switch {
+		case !w.Quoted && operatorTokens[w.Text]:
+			t.Kind = OperatorToken
+			commandPos = true
+			afterRedirect = false
+		case !w.Quoted && redirectTokens[w.Text]:
+			t.Kind = RedirectToken
+			afterRedirect = true
+		default:
+			t.Parts = splitParts(src, w.Start, w.End)
+			if afterRedirect {
+				afterRedirect = false
+			} else {
+				t.Command = commandPos
+				commandPos = false
+			}
+		}
+		result = append(result, t)
+	}
+	result = appendComment(result, src, prevEnd, len(src))
+	if err != nil {
+		return result, err
+	}
+	for _, t := range result {
+		for _, p := range t.Parts {
+			if p.Kind == BackquotePart && p.Unclosed {
+				return result, ErrBackquoteNotClosed
+			}
+		}
+	}
+	return result, nil
+}
+
+// appendComment finds a comment between words.
+func appendComment(tokens []Token, src string, start, end int) []Token {
+	if end > len(src) {
+		end = len(src)
+	}
+	i := strings.IndexByte(src[start:end], '#')
+	if i == -1 {
+		return tokens
+	}
+	commentEnd := strings.IndexByte(src[start+i:end], '\n')
+	if commentEnd == -1 {
+		commentEnd = end
+	} else {
+		commentEnd += start + i
+	}
+	tokens = append(tokens, Token{Kind: CommentToken, Start: start + i, End: commentEnd})
+	// a comment ends at the end of line, so another one can follow
+	return appendComment(tokens, src, commentEnd, end)
+}
+
+// splitParts splits the word in src[start:end] into parts.
+func splitParts(src string, start, end int) []Part {
+	var parts []Part
+	add := func(kind PartKind, s, e int, unclosed bool) {
+		if s == e {
+			return
+		}
+		// join adjacent literal and quoted pieces of the same kind
+		if n := len(parts); n > 0 && parts[n-1].Kind == kind && parts[n-1].End == s && kind != VariablePart && kind != BackquotePart && kind != EscapedPart {
+			parts[n-1].End = e
+			parts[n-1].Unclosed = unclosed
+			return
+		}
+		parts = append(parts, Part{Kind: kind, Start: s, End: e, Unclosed: unclosed})
+	}
+	var quote byte
+	segStart, quoteStart := start, start
+	kind := LiteralPart
+loop:
+	for i := start; i < end; i++ {
+		c := src[i]
+		switch {
+		case quote == '\'' && c == '\'':
+			add(SingleQuotedPart, segStart, i+1, false)
+			quote, segStart, kind = 0, i+1, LiteralPart
+		case c == '$' && i+1 < end:
+			// variables and back quotes are expanded after removing quotes, so they work even in single quotes
+			n := variableLength(src[i:end])
+			if n == 0 {
+				continue
+			}
+			add(kind, segStart, i, false)
+			add(VariablePart, i, i+n, false)
+			i += n - 1
+			segStart = i + 1
+		case c == '`':
+			add(kind, segStart, i, false)
+			closing := strings.IndexByte(src[i+1:end], '`')
+			if closing == -1 {
+				add(BackquotePart, i, end, true)
+				segStart = end
+				break loop
+			}
+			add(BackquotePart, i, i+closing+2, false)
+			i += closing + 1
+			segStart = i + 1
+		case quote == '\'':
+		case c == '\\':
+			if quote == '"' {
+				i++
+				continue
+			}
+			add(kind, segStart, i, false)
+			e := i + 2
+			if e > end {
+				e = end
+			}
+			add(EscapedPart, i, e, false)
+			i = e - 1
+			segStart = e
+		case quote == '"':
+			if c == '"' {
+				add(DoubleQuotedPart, segStart, i+1, false)
+				quote, segStart, kind = 0, i+1, LiteralPart
+			}
+		case c == '\'' || c == '"':
+			add(kind, segStart, i, false)
+			quote, segStart, quoteStart = c, i, i
+			if c == '"' {
+				kind = DoubleQuotedPart
+			} else {
+				kind = SingleQuotedPart
+			}
+		}
+	}
+	add(kind, segStart, end, false)
+	if quote != 0 {
+		for i := range parts {
+			if parts[i].Start >= quoteStart && parts[i].Kind == kind {
+				parts[i].Unclosed = true
+			}
+		}
+	}
+	return parts
+}
+
+// variableLength returns the length of "$NAME" or "${NAME}" at the beginning of s like os.Expand.
+// It returns 0 if s doesn't start with a variable.
+func variableLength(s string) int {
+	if s[1] == '{' {
+		if i := strings.IndexByte(s, '}'); i > 2 {
+			return i + 1
+		}
+		return 0
+	}
+	if strings.IndexByte("*#$@!?-0123456789", s[1]) != -1 {
+		return 2
+	}
+	i := 1
+	for i < len(s) && (s[i] == '_' || 'a' <= s[i] && s[i] <= 'z' || 'A' <= s[i] && s[i] <= 'Z' || '0' <= s[i] && s[i] <= '9') {
+		i++
+	}
+	if i == 1 {
+		return 0
+	}
+	return i
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// describeTokens converts tokens into "kind:source" strings. Parts of words are written in brackets.
+func describeTokens(src string, tokens []Token) []string {
+	tokenKinds := []string{"word", "op", "redirect", "comment"}
+	partKinds := []string{"lit", "sq", "dq", "esc", "var", "bq"}
+	var result []string
+	for _, t := range tokens {
+		s := tokenKinds[t.Kind] + ":" + src[t.Start:t.End]
+		if t.Command {
+			s = "cmd:" + src[t.Start:t.End]
+		}
+		if len(t.Parts) > 1 || len(t.Parts) == 1 && t.Parts[0].Kind != LiteralPart {
+			for _, p := range t.Parts {
+				s += fmt.Sprintf(" [%s:%s", partKinds[p.Kind], src[p.Start:p.End])
+				if p.Unclosed {
+					s += "!"
+				}
+				s += "]"
+			}
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "command and operators",
+			src:  `ls -l | wc > out.txt && echo ok ; pwd`,
+			want: []string{"cmd:ls", "word:-l", "op:|", "cmd:wc", "redirect:>", "word:out.txt", "op:&&", "cmd:echo", "word:ok", "op:;", "cmd:pwd"},
+		},
+		{
+			name: "redirect before command",
+			src:  `< in.txt cat`,
+			want: []string{"redirect:<", "word:in.txt", "cmd:cat"},
+		},
+		{
+			name: "quotes and variables",
+			src:  `echo "home: $HOME" 'it''s' a\ b ${USER}s`,
+			want: []string{
+				"cmd:echo",
+				`word:"home: $HOME" [dq:"home: ] [var:$HOME] [dq:"]`,
+				`word:'it''s' [sq:'it''s']`,
+				`word:a\ b [lit:a] [esc:\ ] [lit:b]`,
+				"word:${USER}s [var:${USER}] [lit:s]",
+			},
+		},
+		{
+			name: "back quote",
+			src:  "echo \"`pwd`\"",
+			want: []string{"cmd:echo", "word:\"`pwd`\" [dq:\"] [bq:`pwd`] [dq:\"]"},
+		},
+		{
+			name: "comment",
+			src:  "ls # list files",
+			want: []string{"cmd:ls", "comment:# list files"},
+		},
+		{
+			name:    "unclosed quote",
+			src:     `echo "hello $USER`,
+			want:    []string{"cmd:echo", `word:"hello $USER [dq:"hello !] [var:$USER]`},
+			wantErr: ErrQuoteNotClosed,
+		},
+		{
+			name:    "unclosed back quote",
+			src:     "echo `pwd",
+			want:    []string{"cmd:echo", "word:`pwd [bq:`pwd!]"},
+			wantErr: ErrBackquoteNotClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.src)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, describeTokens(tt.src, tokens))
+		})
+	}
+}
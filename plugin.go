@@ -0,0 +1,316 @@
+package tish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shibukawa/tish/pluginrpc"
+)
+
+// DefaultPluginTimeout is a timeout of "describe" and "complete" requests to plugins.
+const DefaultPluginTimeout = 5 * time.Second
+
+// ErrPluginNotAllowed is returned by Shell.LoadPlugins in SafeMode.
+var ErrPluginNotAllowed = errors.New("plugins are not allowed in safe mode")
+
+// ErrPluginLoad is returned by LoadPlugins when some plugins can't be loaded.
+type ErrPluginLoad struct {
+	Errors []error
+}
+
+func (e ErrPluginLoad) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// LoadPlugins runs executables in the directory and returns their commands. See pluginrpc for the protocol.
+//
+// Plugins that fail to describe their commands are skipped and reported by ErrPluginLoad with the commands of
+// other plugins. stderr of plugins while describing and completing goes to stderr (nil discards it).
+// Plugins run as external processes, so they are not restricted by AllowPath.
+// Terminal input (os.Stdin) is not forwarded to plugins.
+func LoadPlugins(ctx context.Context, dir string, stderr io.Writer) ([]*Command, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Command
+	var errs []error
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if !isPluginExecutable(path) {
+			continue
+		}
+		var info pluginrpc.DescribeResult
+		if err := callPlugin(ctx, path, pluginrpc.MethodDescribe, nil, &info, stderr); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", path, err))
+			continue
+		}
+		for _, c := range info.Commands {
+			pc := &pluginCommand{path: path, info: c, stderr: stderr}
+			result = append(result, &Command{
+				Name:        c.Name,
+				Description: c.Description,
+				Executor:    pc.Executor,
+				Completer:   pc.Completer,
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return result, ErrPluginLoad{Errors: errs}
+	}
+	return result, nil
+}
+
+// LoadPlugins registers commands of plugins in the directory to the shell.
+// Commands of broken plugins are skipped and the others are registered even if it returns ErrPluginLoad.
+//
+// Plugins are arbitrary executables, so it returns ErrPluginNotAllowed in SafeMode.
+func (s *Shell) LoadPlugins(ctx context.Context, dir string, stderr io.Writer) error {
+	if s.Option().SafeMode {
+		return ErrPluginNotAllowed
+	}
+	cmds, err := LoadPlugins(ctx, dir, stderr)
+	for _, cmd := range cmds {
+		s.RegisterCommand(cmd)
+	}
+	return err
+}
+
+func isPluginExecutable(path string) bool {
+	stat, err := os.Stat(path)
+	if err != nil || !stat.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return stat.Mode()&0o111 != 0
+}
+
+type pluginCommand struct {
+	path string
+	info pluginrpc.CommandInfo
+	// stderr is used while completing
+	stderr io.Writer
+}
+
+// Completer returns flag names for "-" and asks the plugin for other inputs.
+func (pc *pluginCommand) Completer(input string) []string {
+	var result []string
+	if strings.HasPrefix(input, "-") {
+		names := []string{"--help", "-h"}
+		for _, f := range pc.info.Flags {
+			if f.Short != "" {
+				names = append(names, "-"+f.Short)
+			}
+			if f.Long != "" {
+				names = append(names, "--"+f.Long)
+			}
+		}
+		for _, name := range names {
+			if strings.HasPrefix(name, input) {
+				result = append(result, name)
+			}
+		}
+		sort.Strings(result)
+		return result
+	}
+	if !pc.info.Completion {
+		return nil
+	}
+	var r pluginrpc.CompleteResult
+	err := callPlugin(context.Background(), pc.path, pluginrpc.MethodComplete, pluginrpc.CompleteParams{
+		Command: pc.info.Name,
+		Input:   input,
+	}, &r, pc.stderr)
+	if err != nil {
+		return nil
+	}
+	return r.Candidates
+}
+
+// callPlugin starts the plugin and calls the method. stderr of the plugin goes to stderr.
+func callPlugin(ctx context.Context, path, method string, params, result interface{}, stderr io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultPluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	defer cmd.Wait()
+	defer stdin.Close()
+	conn := pluginrpc.NewConn(stdout, stdin)
+	id, err := conn.Request(method, params)
+	if err != nil {
+		return err
+	}
+	for {
+		m, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		if m.IsResponse() && m.ID == id {
+			if m.Error != nil {
+				return m.Error
+			}
+			return json.Unmarshal(m.Result, result)
+		}
+	}
+}
+
+// Executor runs the command in the plugin process and streams stdin, stdout and stderr.
+func (pc *pluginCommand) Executor(ctx context.Context, result *ExecResult, p *Process) (err error) {
+	cmd := exec.Command(pc.path)
+	cmd.Dir = p.Shell.WorkingDir()
+	cmd.Env = envList(p.Env())
+	// stderr of the plugin process and "stderr" notifications are written from different goroutines
+	stderr := &syncWriter{lock: &sync.Mutex{}, w: p.Stderr}
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	conn := pluginrpc.NewConn(stdout, stdin)
+	id, err := conn.Request(pluginrpc.MethodRun, pluginrpc.RunParams{
+		Command:    pc.info.Name,
+		Args:       p.Args,
+		Env:        p.Env(),
+		WorkingDir: p.Shell.WorkingDir(),
+	})
+	if err != nil {
+		stdin.Close()
+		cmd.Wait()
+		return err
+	}
+	done := make(chan struct{})
+	go pc.sendStdin(conn, p.Stdin, done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Notify(pluginrpc.MethodCancel, nil)
+			timer := time.NewTimer(p.Shell.killTimeout())
+			defer timer.Stop()
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+			}
+			terminate(cmd.Process, done, p.Shell.killTimeout())
+		case <-done:
+		}
+	}()
+
+	var runResult *pluginrpc.RunResult
+	for runResult == nil {
+		m, readErr := conn.Read()
+		if readErr != nil {
+			err = fmt.Errorf("plugin %s: %w", pc.info.Name, readErr)
+			break
+		}
+		switch {
+		case m.Method == pluginrpc.MethodStdout || m.Method == pluginrpc.MethodStderr:
+			var s pluginrpc.StreamParams
+			if json.Unmarshal(m.Params, &s) != nil {
+				continue
+			}
+			w := p.Stdout
+			if m.Method == pluginrpc.MethodStderr {
+				w = stderr
+			}
+			if _, writeErr := w.Write(s.Data); writeErr != nil {
+				err = writeErr
+				conn.Notify(pluginrpc.MethodCancel, nil)
+			}
+		case m.IsResponse() && m.ID == id:
+			runResult = &pluginrpc.RunResult{}
+			if m.Error != nil {
+				runResult.ExitCode = 1
+				runResult.Error = m.Error.Message
+			} else if json.Unmarshal(m.Result, runResult) != nil {
+				runResult.ExitCode = 1
+			}
+		}
+	}
+	stdin.Close()
+	cmd.Wait()
+	close(done)
+
+	if runResult == nil {
+		result.SetInternalProcessResult(1)
+		return err
+	}
+	result.SetInternalProcessResult(runResult.ExitCode)
+	if err == nil && runResult.Error != "" {
+		err = errors.New(runResult.Error)
+	}
+	return err
+}
+
+// sendStdin streams stdin to the plugin until EOF or the end of the command.
+func (pc *pluginCommand) sendStdin(conn *pluginrpc.Conn, r io.Reader, done <-chan struct{}) {
+	// reading terminal can't be stopped, so it is not forwarded
+	if r == nil || r == os.Stdin {
+		conn.Notify(pluginrpc.MethodStdinClose, nil)
+		return
+	}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if n > 0 {
+			if conn.Notify(pluginrpc.MethodStdin, pluginrpc.StreamParams{Data: buf[:n]}) != nil {
+				return
+			}
+		}
+		if err != nil {
+			conn.Notify(pluginrpc.MethodStdinClose, nil)
+			return
+		}
+	}
+}
+
+type syncWriter struct {
+	lock *sync.Mutex
+	w    io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,271 @@
+// Package plugin is an SDK to write tish plugin commands in Go.
+//
+// A plugin is an executable that calls Serve in its main function:
+//
+//	func main() {
+//		plugin.Serve(&plugin.Command{
+//			Name:        "greet",
+//			Description: "say hello",
+//			Options:     &greetOptions{},
+//			Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) (err error) {
+//				opts := &greetOptions{}
+//				args, err := flags.ParseArgs(opts, p.Args)
+//				...
+//			},
+//		})
+//	}
+//
+// Put the executable in the plugins directory and load it with tish.LoadPlugins or Shell.LoadPlugins.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/shibukawa/tish"
+	"github.com/shibukawa/tish/pluginrpc"
+)
+
+// Command is a command that the plugin provides.
+//
+// Executor has the same signature as applets. The Process has a Shell that has the environment variables
+// and the working directory of the caller. Changes of the Shell are not sent back to the caller.
+type Command struct {
+	Name        string
+	Description string
+	// Options is a pointer to go-flags options struct to describe flags for help and completion.
+	// Executor parses arguments by itself.
+	Options   interface{}
+	Executor  tish.Executor
+	Completer tish.Completer
+}
+
+// Serve serves the commands on stdin and stdout until the host closes stdin.
+// Interrupt signal cancels the running command instead of killing the plugin.
+func Serve(commands ...*Command) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+	if err := ServeConn(ctx, os.Stdin, os.Stdout, commands...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// ServeConn serves the commands on the reader and writer until the reader reaches EOF.
+func ServeConn(ctx context.Context, r io.Reader, w io.Writer, commands ...*Command) error {
+	conn := pluginrpc.NewConn(r, w)
+	s := &server{
+		conn:     conn,
+		commands: map[string]*Command{},
+	}
+	for _, cmd := range commands {
+		s.commands[cmd.Name] = cmd
+	}
+	defer s.wait()
+	for {
+		m, err := conn.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		s.handle(ctx, m, commands)
+	}
+}
+
+type server struct {
+	conn     *pluginrpc.Conn
+	commands map[string]*Command
+	current  *run
+}
+
+type run struct {
+	cancel context.CancelFunc
+	stdin  chan []byte
+	closed bool
+	done   chan struct{}
+}
+
+func (s *server) wait() {
+	if s.current != nil {
+		s.closeStdin()
+		<-s.current.done
+	}
+}
+
+func (s *server) closeStdin() {
+	if s.current != nil && !s.current.closed {
+		s.current.closed = true
+		close(s.current.stdin)
+	}
+}
+
+func (s *server) handle(ctx context.Context, m *pluginrpc.Message, commands []*Command) {
+	switch m.Method {
+	case pluginrpc.MethodDescribe:
+		result := pluginrpc.DescribeResult{Commands: []pluginrpc.CommandInfo{}}
+		for _, cmd := range commands {
+			result.Commands = append(result.Commands, pluginrpc.CommandInfo{
+				Name:        cmd.Name,
+				Description: cmd.Description,
+				Flags:       describeFlags(cmd.Options),
+				Completion:  cmd.Completer != nil,
+			})
+		}
+		s.conn.Respond(m.ID, result)
+	case pluginrpc.MethodComplete:
+		var params pluginrpc.CompleteParams
+		if err := jsonParams(m, &params); err != nil {
+			s.conn.RespondError(m.ID, pluginrpc.InvalidParams, err.Error())
+			return
+		}
+		result := pluginrpc.CompleteResult{Candidates: []string{}}
+		if cmd, ok := s.commands[params.Command]; ok && cmd.Completer != nil {
+			result.Candidates = append(result.Candidates, cmd.Completer(params.Input)...)
+		}
+		s.conn.Respond(m.ID, result)
+	case pluginrpc.MethodRun:
+		var params pluginrpc.RunParams
+		if err := jsonParams(m, &params); err != nil {
+			s.conn.RespondError(m.ID, pluginrpc.InvalidParams, err.Error())
+			return
+		}
+		cmd, ok := s.commands[params.Command]
+		if !ok {
+			s.conn.RespondError(m.ID, pluginrpc.InvalidParams, "unknown command: "+params.Command)
+			return
+		}
+		if s.current != nil {
+			s.conn.RespondError(m.ID, pluginrpc.InvalidRequest, "command is already running")
+			return
+		}
+		s.current = s.start(ctx, m.ID, cmd, params)
+	case pluginrpc.MethodStdin:
+		var params pluginrpc.StreamParams
+		if s.current == nil || s.current.closed || jsonParams(m, &params) != nil {
+			return
+		}
+		s.current.stdin <- params.Data
+	case pluginrpc.MethodStdinClose:
+		s.closeStdin()
+	case pluginrpc.MethodCancel:
+		if s.current != nil {
+			s.current.cancel()
+		}
+	default:
+		if m.ID != 0 {
+			s.conn.RespondError(m.ID, pluginrpc.MethodNotFound, "method not found: "+m.Method)
+		}
+	}
+}
+
+// start runs the executor in a goroutine and responds to the request when it finishes.
+func (s *server) start(ctx context.Context, id int, cmd *Command, params pluginrpc.RunParams) *run {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &run{
+		cancel: cancel,
+		stdin:  make(chan []byte, 64),
+		done:   make(chan struct{}),
+	}
+	stdinReader, stdinWriter := io.Pipe()
+	go func() {
+		for data := range r.stdin {
+			// the executor may finish without reading stdin
+			stdinWriter.Write(data)
+		}
+		stdinWriter.Close()
+	}()
+
+	env := make([]string, 0, len(params.Env))
+	for k, v := range params.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	shell := tish.NewShell(params.WorkingDir, env)
+	p := tish.NewProcess(shell, cmd.Executor, params.Command, params.Args, 0, shell.Pid, nil)
+	p.Args = params.Args
+	p.Stdin = stdinReader
+	p.Stdout = s.conn.Writer(pluginrpc.MethodStdout)
+	p.Stderr = s.conn.Writer(pluginrpc.MethodStderr)
+	p.Result = &tish.ExecResult{}
+
+	go func() {
+		defer close(r.done)
+		defer cancel()
+		err := execute(ctx, p)
+		stdinReader.Close()
+		result := pluginrpc.RunResult{ExitCode: p.Result.ExitCode()}
+		if err != nil {
+			result.Error = err.Error()
+			if result.ExitCode == 0 {
+				result.ExitCode = 1
+			}
+		}
+		s.conn.Respond(id, result)
+	}()
+	return r
+}
+
+func execute(ctx context.Context, p *tish.Process) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return p.Executor(ctx, p.Result, p)
+}
+
+// describeFlags returns flags of go-flags options struct.
+func describeFlags(options interface{}) []pluginrpc.FlagInfo {
+	if options == nil {
+		return nil
+	}
+	parser := flags.NewParser(nil, flags.None)
+	if _, err := parser.AddGroup("Options", "", options); err != nil {
+		return nil
+	}
+	var result []pluginrpc.FlagInfo
+	var walk func(groups []*flags.Group)
+	walk = func(groups []*flags.Group) {
+		for _, g := range groups {
+			for _, o := range g.Options() {
+				if o.Hidden {
+					continue
+				}
+				info := pluginrpc.FlagInfo{
+					Long:        o.LongNameWithNamespace(),
+					Description: o.Description,
+					HasArg:      o.Field().Type.Kind() != reflect.Bool,
+				}
+				if o.ShortName != 0 {
+					info.Short = string(o.ShortName)
+				}
+				result = append(result, info)
+			}
+			walk(g.Groups())
+		}
+	}
+	walk(parser.Groups())
+	return result
+}
+
+func jsonParams(m *pluginrpc.Message, v interface{}) error {
+	if len(m.Params) == 0 {
+		return errors.New("params is required")
+	}
+	return json.Unmarshal(m.Params, v)
+}
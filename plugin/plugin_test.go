@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/shibukawa/tish"
+	"github.com/stretchr/testify/assert"
+)
+
+type greetOptions struct {
+	Upper bool   `short:"u" long:"upper" description:"greet loudly"`
+	Name  string `short:"n" long:"name" description:"name to greet"`
+}
+
+var testCommands = []*Command{
+	{
+		Name:        "greet",
+		Description: "say hello",
+		Options:     &greetOptions{},
+		Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) (err error) {
+			opts := &greetOptions{}
+			if _, err := flags.ParseArgs(opts, p.Args); err != nil {
+				result.SetInternalProcessResult(2)
+				return nil
+			}
+			msg := fmt.Sprintf("hello %s in %s", opts.Name, filepath.Base(p.Shell.WorkingDir()))
+			if opts.Upper {
+				msg = strings.ToUpper(msg)
+			}
+			fmt.Fprintln(p.Stdout, msg)
+			fmt.Fprintln(p.Stderr, p.Shell.Getenv("GREETING"))
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+		Completer: func(input string) []string {
+			return []string{input + "-candidate"}
+		},
+	},
+	{
+		Name: "count",
+		Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) (err error) {
+			input, err := ioutil.ReadAll(p.Stdin)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(p.Stdout, "%d bytes\n", len(input))
+			result.SetInternalProcessResult(3)
+			return nil
+		},
+	},
+	{
+		Name: "wait",
+		Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) (err error) {
+			if err := tish.SleepContext(ctx, 10*time.Second); err != nil {
+				result.SetInternalProcessResult(130)
+				return err
+			}
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	},
+}
+
+const helperEnv = "TISH_PLUGIN_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		Serve(testCommands...)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// pluginShell creates a shell that loads this test binary as a plugin.
+func pluginShell(t *testing.T) (*tish.Shell, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink to the test binary is not supported")
+	}
+	root := tish.CreateTestFolders(t, "plugin", map[string]string{
+		"work/": "",
+	})
+	os.Setenv(helperEnv, "1")
+	t.Cleanup(func() {
+		os.Unsetenv(helperEnv)
+	})
+	pluginDir := filepath.Join(root, "plugins")
+	os.MkdirAll(pluginDir, 0o755)
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(self, filepath.Join(pluginDir, "test-plugin")); err != nil {
+		t.Fatal(err)
+	}
+	s := tish.NewShell(filepath.Join(root, "work"), []string{helperEnv + "=1", "GREETING=hi"})
+	if err := s.LoadPlugins(context.Background(), pluginDir, os.Stderr); err != nil {
+		t.Fatal(err)
+	}
+	return s, root
+}
+
+func TestLoadPlugins(t *testing.T) {
+	s, _ := pluginShell(t)
+	var names []string
+	for _, cmd := range s.Commands() {
+		names = append(names, cmd.Name)
+	}
+	assert.Subset(t, names, []string{"count", "greet", "wait"})
+
+	var greet *tish.Command
+	for _, cmd := range s.Commands() {
+		if cmd.Name == "greet" {
+			greet = cmd
+		}
+	}
+	if assert.NotNil(t, greet) {
+		assert.Equal(t, []string{"--name"}, greet.Completer("--n"))
+		assert.Equal(t, []string{"-u"}, greet.Completer("-u"))
+		assert.Equal(t, []string{"file-candidate"}, greet.Completer("file"))
+	}
+}
+
+func TestPluginCommand_Run(t *testing.T) {
+	s, _ := pluginShell(t)
+
+	var stdout, stderr bytes.Buffer
+	code, err := s.Run(context.Background(), "greet -u --name tish", &stdout, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "HELLO TISH IN WORK\n", stdout.String())
+	assert.Equal(t, "hi\n", stderr.String())
+
+	stdout.Reset()
+	code, err = s.RunWithStdin(context.Background(), "count", strings.NewReader(strings.Repeat("a", 100000)), &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "100000 bytes\n", stdout.String())
+}
+
+func TestPluginCommand_Cancel(t *testing.T) {
+	s, _ := pluginShell(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	code, err := s.Run(ctx, "wait", io.Discard, io.Discard)
+	assert.ErrorIs(t, err, tish.ErrInterrupted)
+	assert.Equal(t, 130, code)
+	assert.True(t, time.Since(start) < 2*time.Second)
+}
@@ -0,0 +1,97 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// goCommand returns the go command to build test programs. The test is skipped if it is not found.
+func goCommand(t *testing.T) string {
+	t.Helper()
+	goCmd, err := exec.LookPath(filepath.Join(runtime.GOROOT(), "bin", "go"))
+	if err != nil {
+		if goCmd, err = exec.LookPath("go"); err != nil {
+			t.Skip("go command is not found")
+		}
+	}
+	return goCmd
+}
+
+// buildPlugins builds plugins in testdata/plugins into a directory.
+func buildPlugins(t *testing.T, names ...string) string {
+	t.Helper()
+	goCmd := goCommand(t)
+	root := CreateTestFolders(t, "plugin-host")
+	t.Cleanup(func() {
+		os.RemoveAll(root)
+	})
+	dir := filepath.Join(root, "plugins")
+	for _, name := range names {
+		out := filepath.Join(dir, name)
+		if runtime.GOOS == "windows" {
+			out += ".exe"
+		}
+		cmd := exec.Command(goCmd, "build", "-o", out, "./testdata/plugins/"+name)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("can't build plugin %s: %v\n%s", name, err, output)
+		}
+	}
+	return dir
+}
+
+func TestShell_LoadPlugins(t *testing.T) {
+	dir := buildPlugins(t, "hello", "broken")
+
+	s := NewShell(filepath.Dir(dir), []string{})
+	var stderr bytes.Buffer
+	err := s.LoadPlugins(context.Background(), dir, &stderr)
+
+	// the broken plugin is reported and skipped
+	var loadErr ErrPluginLoad
+	if assert.True(t, errors.As(err, &loadErr)) {
+		assert.Len(t, loadErr.Errors, 1)
+		assert.Contains(t, loadErr.Error(), "broken")
+	}
+	assert.Contains(t, stderr.String(), "broken plugin")
+	assert.Contains(t, stderr.String(), "hello plugin started")
+	assert.True(t, s.HasCommand("hello"))
+
+	var hello *Command
+	for _, cmd := range s.Commands() {
+		if cmd.Name == "hello" {
+			hello = cmd
+		}
+	}
+	if assert.NotNil(t, hello) {
+		assert.Equal(t, "say hello", hello.Description)
+		stderr.Reset()
+		assert.Equal(t, []string{"abc-candidate"}, hello.Completer("abc"))
+		assert.Equal(t, "hello plugin started\n", stderr.String(), "stderr while completing")
+	}
+
+	var stdout bytes.Buffer
+	stderr.Reset()
+	code, err := s.RunWithStdin(context.Background(), "hello a b", strings.NewReader("input"), &stdout, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "hello [a b] input\n", stdout.String())
+	assert.Contains(t, stderr.String(), "to stderr\n")
+}
+
+func TestShell_LoadPlugins_SafeMode(t *testing.T) {
+	dir := buildPlugins(t, "hello")
+
+	s := NewShell(filepath.Dir(dir), []string{}, Option{SafeMode: true})
+	err := s.LoadPlugins(context.Background(), dir, nil)
+	assert.ErrorIs(t, err, ErrPluginNotAllowed)
+	assert.False(t, s.HasCommand("hello"))
+}
@@ -0,0 +1,229 @@
+// Package pluginrpc defines the protocol between tish and plugin commands.
+//
+// A plugin is an executable in the plugins directory. tish starts it without arguments and talks
+// JSON-RPC 2.0 over its stdin and stdout. Each message is a JSON object on a line.
+//
+// To list commands, tish sends "describe" request. To run a command, tish starts the plugin again and sends
+// "run" request, then streams stdin with "stdin" and "stdin.close" notifications. The plugin streams
+// output with "stdout" and "stderr" notifications and responds to "run" with the exit code.
+// "cancel" notification is sent when the command is interrupted. tish closes stdin of the plugin when the session
+// is finished, then the plugin should exit.
+//
+// Use github.com/shibukawa/tish/plugin to write plugins in Go.
+package pluginrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Version is a version of JSON-RPC.
+const Version = "2.0"
+
+const (
+	// MethodDescribe is a request to list commands. Params is nil and result is DescribeResult.
+	MethodDescribe = "describe"
+	// MethodComplete is a request of completion candidates. Params is CompleteParams and result is CompleteResult.
+	MethodComplete = "complete"
+	// MethodRun is a request to run a command. Params is RunParams and result is RunResult.
+	MethodRun = "run"
+	// MethodCancel is a notification to interrupt the running command.
+	MethodCancel = "cancel"
+
+	// MethodStdin is a notification of stdin data (host to plugin). Params is StreamParams.
+	MethodStdin = "stdin"
+	// MethodStdinClose is a notification of the end of stdin (host to plugin).
+	MethodStdinClose = "stdin.close"
+	// MethodStdout is a notification of stdout data (plugin to host). Params is StreamParams.
+	MethodStdout = "stdout"
+	// MethodStderr is a notification of stderr data (plugin to host). Params is StreamParams.
+	MethodStderr = "stderr"
+)
+
+// Error codes of JSON-RPC.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Message is a request, response or notification. Notifications don't have ID.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsResponse returns true if the message is a response of request.
+func (m Message) IsResponse() bool {
+	return m.Method == "" && m.ID != 0
+}
+
+// Error is an error object of JSON-RPC.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// DescribeResult is a result of "describe".
+type DescribeResult struct {
+	Commands []CommandInfo `json:"commands"`
+}
+
+// CommandInfo describes a command of the plugin.
+type CommandInfo struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Flags       []FlagInfo `json:"flags,omitempty"`
+	// Completion is true if the plugin responds to "complete" for the command.
+	Completion bool `json:"completion,omitempty"`
+}
+
+// FlagInfo describes a flag of the command.
+type FlagInfo struct {
+	Short       string `json:"short,omitempty"`
+	Long        string `json:"long,omitempty"`
+	Description string `json:"description,omitempty"`
+	HasArg      bool   `json:"has_arg,omitempty"`
+}
+
+// CompleteParams is params of "complete".
+type CompleteParams struct {
+	Command string `json:"command"`
+	Input   string `json:"input"`
+}
+
+// CompleteResult is a result of "complete".
+type CompleteResult struct {
+	Candidates []string `json:"candidates"`
+}
+
+// RunParams is params of "run".
+type RunParams struct {
+	Command    string            `json:"command"`
+	Args       []string          `json:"args"`
+	Env        map[string]string `json:"env"`
+	WorkingDir string            `json:"working_dir"`
+}
+
+// RunResult is a result of "run". Error is a message of the error that the executor returned.
+type RunResult struct {
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// StreamParams is params of stream notifications. Data is encoded in base64.
+type StreamParams struct {
+	Data []byte `json:"data"`
+}
+
+// Conn reads and writes messages. Writes are safe to use from multiple goroutines.
+type Conn struct {
+	lock   *sync.Mutex
+	enc    *json.Encoder
+	dec    *json.Decoder
+	lastID int
+}
+
+// NewConn creates Conn that reads messages from r and writes to w.
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{
+		lock: &sync.Mutex{},
+		enc:  json.NewEncoder(w),
+		dec:  json.NewDecoder(r),
+	}
+}
+
+// Read reads the next message. It returns io.EOF at the end.
+func (c *Conn) Read() (*Message, error) {
+	var m Message
+	if err := c.dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Request sends the request and returns its ID. Read the response with Read.
+func (c *Conn) Request(method string, params interface{}) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lastID++
+	m, err := newMessage(method, params)
+	if err != nil {
+		return 0, err
+	}
+	m.ID = c.lastID
+	return m.ID, c.enc.Encode(m)
+}
+
+// Notify sends the notification.
+func (c *Conn) Notify(method string, params interface{}) error {
+	m, err := newMessage(method, params)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.enc.Encode(m)
+}
+
+// Respond sends the result of the request.
+func (c *Conn) Respond(id int, result interface{}) error {
+	r, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.enc.Encode(&Message{JSONRPC: Version, ID: id, Result: r})
+}
+
+// RespondError sends the error of the request.
+func (c *Conn) RespondError(id, code int, message string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.enc.Encode(&Message{JSONRPC: Version, ID: id, Error: &Error{Code: code, Message: message}})
+}
+
+func newMessage(method string, params interface{}) (*Message, error) {
+	m := &Message{JSONRPC: Version, Method: method}
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		m.Params = p
+	}
+	return m, nil
+}
+
+// Writer returns a writer that sends written bytes with the stream notification (MethodStdout or MethodStderr).
+func (c *Conn) Writer(method string) io.Writer {
+	return &streamWriter{conn: c, method: method}
+}
+
+type streamWriter struct {
+	conn   *Conn
+	method string
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.conn.Notify(w.method, StreamParams{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package pluginrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConn(t *testing.T) {
+	var buffer bytes.Buffer
+	c := NewConn(&buffer, &buffer)
+
+	id, err := c.Request(MethodRun, RunParams{Command: "greet", Args: []string{"a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.NoError(t, c.Notify(MethodStdinClose, nil))
+	io.WriteString(c.Writer(MethodStdout), "hello")
+	assert.NoError(t, c.Respond(id, RunResult{ExitCode: 2}))
+	assert.NoError(t, c.RespondError(2, MethodNotFound, "method not found"))
+
+	m, err := c.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, MethodRun, m.Method)
+	assert.False(t, m.IsResponse())
+	var run RunParams
+	assert.NoError(t, json.Unmarshal(m.Params, &run))
+	assert.Equal(t, "greet", run.Command)
+
+	m, err = c.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, &Message{JSONRPC: Version, Method: MethodStdinClose}, m)
+
+	m, err = c.Read()
+	assert.NoError(t, err)
+	var stream StreamParams
+	assert.NoError(t, json.Unmarshal(m.Params, &stream))
+	assert.Equal(t, "hello", string(stream.Data))
+
+	m, err = c.Read()
+	assert.NoError(t, err)
+	assert.True(t, m.IsResponse())
+	assert.JSONEq(t, `{"exit_code":2}`, string(m.Result))
+
+	m, err = c.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, &Error{Code: MethodNotFound, Message: "method not found"}, m.Error)
+
+	_, err = c.Read()
+	assert.Equal(t, io.EOF, err)
+}
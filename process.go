@@ -4,7 +4,9 @@ import (
 	"context"
 	"io"
 	"os"
+	"runtime"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -34,14 +36,12 @@ type Process struct {
 
 	Result *ExecResult
 
+	log       *logNode
 	wg        *sync.WaitGroup
 	execError error
 }
 
 func NewProcess(s *Shell, executor Executor, cmd string, args []string, ppid, pid int, env map[string]string) *Process {
-	if env == nil {
-		env = s.Env
-	}
 	return &Process{
 		Shell:     s,
 		Pid:       pid,
@@ -66,7 +66,11 @@ func (p *Process) Pipe(other *Process) {
 }
 
 func (p *Process) RedirectStdin(path string) error {
-	f, err := os.Open(p.Shell.ExpandPath(path))
+	path, err := p.Shell.ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	f, err := p.Shell.FileSystem().Open(path)
 	if err != nil {
 		return err
 	}
@@ -82,7 +86,11 @@ func (p *Process) RedirectStdout(path string, append bool) error {
 	} else {
 		flag += os.O_TRUNC
 	}
-	f, err := os.OpenFile(p.Shell.ExpandPath(path), flag, 0o777)
+	path, err := p.Shell.ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	f, err := p.Shell.FileSystem().OpenFile(path, flag, 0o777)
 	if err != nil {
 		return err
 	}
@@ -98,7 +106,11 @@ func (p *Process) RedirectStderr(path string, append bool) error {
 	} else {
 		flag += os.O_TRUNC
 	}
-	f, err := os.OpenFile(p.Shell.ExpandPath(path), flag, 0o777)
+	path, err := p.Shell.ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	f, err := p.Shell.FileSystem().OpenFile(path, flag, 0o777)
 	if err != nil {
 		return err
 	}
@@ -107,11 +119,9 @@ func (p *Process) RedirectStderr(path string, append bool) error {
 	return nil
 }
 
+// Env returns environment variables of the shell overwritten by variables of the process.
 func (e Process) Env() map[string]string {
-	result := map[string]string{}
-	for k, v := range e.Shell.Env {
-		result[k] = v
-	}
+	result := e.Shell.Environ()
 	for k, v := range e.env {
 		result[k] = v
 	}
@@ -138,12 +148,72 @@ func (p *Process) Start(ctx context.Context) error {
 			return env[key]
 		}))
 	}
+	if err := p.Shell.acquireProcess(p.Cmd); err != nil {
+		return err
+	}
+	if p.log != nil {
+		ctx = p.log.start(ctx, p)
+	}
+	p.Shell.emit(ProcessStarted{
+		Pid:       p.Pid,
+		ParentPid: p.ParentPid,
+		Cmd:       p.Cmd,
+		Args:      p.Args,
+	})
+	timeout := p.Shell.Option().CommandTimeout
+	cmdCtx, cancel := withTimeout(ctx, timeout)
 	p.wg.Add(1)
+	done := make(chan struct{})
 	go func() {
-		p.execError = p.Executor(ctx, r, p)
+		// measure CPU time of the applet on this thread
+		runtime.LockOSThread()
+		user, system := threadCPUTime()
+		p.execError = p.Executor(cmdCtx, r, p)
+		endUser, endSystem := threadCPUTime()
+		runtime.UnlockOSThread()
+		r.setCPUTime(endUser-user, endSystem-system)
+		if timedOut(ctx, cmdCtx) {
+			p.execError = &ErrLimitExceeded{
+				Limit:   CommandTimeoutLimit,
+				Command: p.Cmd,
+				Value:   timeout,
+			}
+		}
+		cancel()
 		r.Finish()
+		if p.log != nil {
+			p.log.finish(r, exitCode(r, p.execError))
+		}
+		p.Shell.emit(ProcessFinished{
+			Pid:       p.Pid,
+			ParentPid: p.ParentPid,
+			Cmd:       p.Cmd,
+			Args:      p.Args,
+			Result:    r,
+			ExitCode:  exitCode(r, p.execError),
+			Err:       p.execError,
+		})
+		p.Shell.addTimes(r)
+		if c := timeCollectorFrom(ctx); c != nil {
+			c.add(r)
+		}
+		p.Shell.releaseProcess()
+		close(done)
 		p.wg.Done()
 	}()
+	go func() {
+		select {
+		case <-cmdCtx.Done():
+			// unblock commands that are reading from or writing to pipe
+			if p.StdinCloser != nil {
+				p.StdinCloser.Close()
+			}
+			if p.StdoutCloser != nil {
+				p.StdoutCloser.Close()
+			}
+		case <-done:
+		}
+	}()
 	return nil
 }
 
@@ -162,8 +232,7 @@ func (p *Process) Wait() error {
 }
 
 type ExecResult struct {
-	start time.Time
-	// initialStat cpu.TimesStat
+	start    time.Time
 	state    *os.ProcessState
 	system   time.Duration
 	user     time.Duration
@@ -172,15 +241,8 @@ type ExecResult struct {
 }
 
 func newExecResult() (*ExecResult, error) {
-	/*
-		s, err := cpu.Times(false)
-		if err != nil {
-			return nil, err
-		}
-	*/
 	return &ExecResult{
 		start: time.Now(),
-		// initialStat: s[0],
 	}, nil
 }
 
@@ -193,49 +255,70 @@ func (e *ExecResult) Finish() {
 	e.wall = time.Now().Sub(e.start)
 }
 
+// setCPUTime sets CPU time that internal applet consumed.
+func (e *ExecResult) setCPUTime(user, system time.Duration) {
+	e.user = user
+	e.system = system
+}
+
+// External returns true if the result is of external command.
+func (e ExecResult) External() bool {
+	return e.state != nil
+}
+
 func (e ExecResult) WallTime() time.Duration {
 	return e.wall
 }
 
+// SystemTime returns system CPU time. It is rusage of external command or thread CPU time of internal applet.
 func (e ExecResult) SystemTime() time.Duration {
 	if e.state != nil {
-		e.state.SystemTime()
+		return e.state.SystemTime()
 	}
-	return time.Duration(0)
+	return e.system
 }
 
+// UserTime returns user CPU time. It is rusage of external command or thread CPU time of internal applet.
 func (e ExecResult) UserTime() time.Duration {
 	if e.state != nil {
-		e.state.UserTime()
+		return e.state.UserTime()
 	}
-	return time.Duration(0)
+	return e.user
 }
 
+// CPUUsage returns percentage of CPU time in wall-clock time.
 func (e ExecResult) CPUUsage() int {
-	return 0
+	if e.wall <= 0 {
+		return 0
+	}
+	return int((e.UserTime() + e.SystemTime()) * 100 / e.wall)
 }
 
 func (e *ExecResult) SetInternalProcessResult(exitCode int) error {
-	/* s, err := cpu.Times(false)
-	if err != nil {
-		return err
-	}
-	*/
 	e.exitCode = exitCode
-	// e.system = time.Duration(s[0].System) * time.Second
-	// e.user = time.Duration(s[0].User) * time.Second
 	return nil
 }
 
+// ExitCode returns the exit code. If the external command is killed by signal, it returns 128+signal like bash.
 func (e ExecResult) ExitCode() int {
 	if e.state != nil {
+		if ws, ok := e.state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
 		return e.state.ExitCode()
 	}
 	return e.exitCode
 }
 
 type Command struct {
-	Name      string
+	Name string
+	// Description is a one-line summary shown by help.
+	Description string
+	// Options is a go-flags options struct (or pointer to it) that the executor parses arguments into.
+	// It is used to generate "--help" and flag completion. Anonymous structs are supported.
+	Options interface{}
+	// NoHelp disables "--help" and "-h" for commands whose arguments are arbitrary text like echo.
+	NoHelp    bool
 	Executor  Executor
 	Completer Completer
 }
@@ -2,86 +2,480 @@ package tish
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/gookit/color"
-)
 
-var (
-	homeDirPrefix = "~" + string(filepath.Separator)
-
-	timeColor = color.S256(255, 39)
-	userColor = color.S256(238, 81)
-	userSepColor = color.S256(100, 81)
-	pathColor = color.S256(238, 159)
-	pathSepColor = color.S256(166, 159)
-	statusOkColor = color.S256(28, 195)
-	statusNgColor = color.S256(196, 195)
-	cursorColor = color.C256(61)
+	"github.com/shibukawa/tish/gitstatus"
 )
 
+var homeDirPrefix = "~" + string(filepath.Separator)
+
+// GitInfo is the state of the git repository of the working directory.
+type GitInfo = gitstatus.Status
+
+// PromptContext is information shown in the prompt.
+type PromptContext struct {
+	User       string
+	Host       string
+	WorkingDir string
+	HomeDir    string
+	Time       time.Time
+	// Status is the exit code of the last command.
+	Status int
+	// Duration is the wall-clock time of the last command.
+	Duration time.Duration
+	// Git is nil if the working directory is not in a git repository.
+	Git *GitInfo
+
+	// Theme is used for the prompt variables that are not set. DefaultTheme is used if it is nil.
+	Theme *Theme
+	// Plain disables colors of the theme.
+	Plain bool
+}
+
+// Dir returns the working directory. The home directory is replaced with "~" (bash's \w).
+func (pc PromptContext) Dir() string {
+	if pc.HomeDir == "" {
+		return pc.WorkingDir
+	}
+	rel, err := filepath.Rel(pc.HomeDir, pc.WorkingDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return pc.WorkingDir
+	}
+	if rel == "." {
+		return "~"
+	}
+	return homeDirPrefix + rel
+}
+
+// Base returns the base name of Dir (bash's \W).
+func (pc PromptContext) Base() string {
+	dir := pc.Dir()
+	if dir == "~" || dir == string(filepath.Separator) {
+		return dir
+	}
+	return filepath.Base(dir)
+}
+
+// PathParts returns Dir split by the path separator. The first part is empty for the absolute path.
+func (pc PromptContext) PathParts() []string {
+	return strings.Split(pc.Dir(), string(filepath.Separator))
+}
+
+// ShortHost returns the host name up to the first "." (bash's \h).
+func (pc PromptContext) ShortHost() string {
+	if i := strings.IndexByte(pc.Host, '.'); i != -1 {
+		return pc.Host[:i]
+	}
+	return pc.Host
+}
+
+// Sigil returns "#" for root and "$" for other users (bash's \$).
+func (pc PromptContext) Sigil() string {
+	if pc.User == "root" {
+		return "#"
+	}
+	return "$"
+}
+
+// NewPromptContext creates PromptContext from the state of the shell (USER, HOSTNAME, HOME, working directory
+// and theme). NO_COLOR environment variable enables Plain.
+//
+// Git is read from the .git directory and cached per working tree. It is nil if the file system is not OSFileSystem.
+func (s *Shell) NewPromptContext() PromptContext {
+	pc := s.newPromptContext()
+	if _, ok := s.FileSystem().(OSFileSystem); ok {
+		if status, err := gitstatus.Cached(pc.WorkingDir); err == nil {
+			pc.Git = status
+		}
+	}
+	return pc
+}
+
+// newPromptContext creates PromptContext without Git for PS4, which is rendered for each command.
+func (s *Shell) newPromptContext() PromptContext {
+	env := s.Environ()
+	pc := PromptContext{
+		User:       env["USER"],
+		Host:       env["HOSTNAME"],
+		WorkingDir: s.WorkingDir(),
+		Time:       time.Now(),
+		Theme:      s.Theme(),
+		Plain:      env["NO_COLOR"] != "",
+	}
+	if pc.User == "" {
+		pc.User = env["LOGNAME"]
+	}
+	if pc.Host == "" {
+		pc.Host, _ = os.Hostname()
+	}
+	pc.HomeDir, _ = s.HomeDir()
+	return pc
+}
+
+// Theme returns the theme of the shell. It is nil if it is not set.
+func (s *Shell) Theme() *Theme {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.theme
+}
+
+// SetTheme sets the theme used by NewPromptContext.
+func (s *Shell) SetTheme(t *Theme) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.theme = t
+}
+
+// Theme is a set of prompt templates and colors.
+//
+// Templates are used when the prompt variables (PS1, PS2, PS4 and RPROMPT) are not set.
+// Colors are "fg" or "fg,bg" of 256-color numbers or names like "red" and "bright-blue".
+// They are referred by the "style" function of templates.
+type Theme struct {
+	Name    string            `json:"name"`
+	PS1     string            `json:"ps1"`
+	PS2     string            `json:"ps2"`
+	PS4     string            `json:"ps4"`
+	RPrompt string            `json:"rprompt"`
+	Colors  map[string]string `json:"colors"`
+}
+
 // https://github.com/chris-marsh/pureline
-func Prompt(user, host, workdir, homedir string, now time.Time, status int, test bool) string {
-	var buffer bytes.Buffer
-	buffer.WriteString(timeColor.Sprint(now.Format("  🕓15:04:05  ")))
-	if test {
-		buffer.WriteString(userColor.Sprint("  👤" + user + "@" + host + "  "))
-	} else {
-		buffer.WriteString(userColor.Sprint("  👤" + user))
-		buffer.WriteString(userSepColor.Sprint("@"))
-		buffer.WriteString(userColor.Sprint(host + "  "))
-	}
-
-	var pathStr string
-	if rel, err := filepath.Rel(homedir, workdir); err == nil {
-		if rel == "." {
-			pathStr = "~"
-		} else if strings.HasPrefix(rel, "..") {
-			pathStr = workdir
-		} else {
-			pathStr = homeDirPrefix + strings.TrimPrefix(rel, homeDirPrefix)
+var pureline = Theme{
+	Name: "pureline",
+	PS1: `{{style "time" (.Time.Format "  🕓15:04:05  ")}}` +
+		`{{style "user" (print "  👤" .User)}}{{style "userSep" "@"}}{{style "user" (print .Host "  ")}}` +
+		`{{style "path" "  📁"}}{{range $i, $p := .PathParts}}{{if $i}}{{style "pathSep" "/"}}{{end}}{{style "path" $p}}{{end}}{{style "path" "  "}}` +
+		`{{with .Git}}{{$s := "git"}}{{if or .Staged .Dirty .Untracked}}{{$s = "gitDirty"}}{{end}}` +
+		`{{if .Detached}}{{style $s (print "  ➦ " .Branch)}}{{else}}{{style $s (print "  ⎇ " .Branch)}}{{end}}` +
+		`{{if .Ahead}}{{style $s (print " ↑" .Ahead)}}{{end}}{{if .Behind}}{{style $s (print " ↓" .Behind)}}{{end}}` +
+		`{{if .Staged}}{{style $s " +"}}{{end}}{{if .Dirty}}{{style $s " ±"}}{{end}}{{if .Untracked}}{{style $s " …"}}{{end}}` +
+		`{{style $s "  "}}{{end}}` +
+		`{{if eq .Status 0}}{{style "ok" "  ✔︎  "}}{{else}}{{style "ng" "  ✘  "}}{{end}}` +
+		`{{style "cursor" "\n≫ "}}`,
+	PS2: `{{style "cursor" "… "}}`,
+	PS4: "+ ",
+	Colors: map[string]string{
+		"time":     "255,39",
+		"user":     "238,81",
+		"userSep":  "100,81",
+		"path":     "238,159",
+		"pathSep":  "166,159",
+		"git":      "238,150",
+		"gitDirty": "238,229",
+		"ok":       "28,195",
+		"ng":       "196,195",
+		"cursor":   "61",
+	},
+}
+
+var simple = Theme{
+	Name: "simple",
+	PS1:  `{{style "user" (print .User "@" .ShortHost)}}:{{style "path" .Dir}}\$ `,
+	PS2:  "> ",
+	PS4:  "+ ",
+	Colors: map[string]string{
+		"user": "bright-green",
+		"path": "bright-blue",
+	},
+}
+
+// DefaultTheme is the theme used when PromptContext.Theme is nil.
+var DefaultTheme = &pureline
+
+// BuiltinTheme returns a copy of the builtin theme ("pureline" or "simple").
+func BuiltinTheme(name string) (*Theme, bool) {
+	for _, t := range []Theme{pureline, simple} {
+		if t.Name == name {
+			return t.copy(), true
 		}
-	} else {
-		pathStr = workdir
-	}
-	if test {
-		buffer.WriteString(pathColor.Sprint("  📁" + pathStr + "  "))
-	} else {
-		var fragments []string
-		if pathStr == "~" {
-			fragments = append(fragments, "~")
+	}
+	return nil, false
+}
+
+// LoadTheme reads the theme in JSON. Missing fields are taken from the builtin theme named by "name"
+// (DefaultTheme if it is empty or unknown).
+func LoadTheme(r io.Reader) (*Theme, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	t, ok := BuiltinTheme(header.Name)
+	if !ok {
+		t = DefaultTheme.copy()
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadThemeFile reads the theme from the JSON file.
+func LoadThemeFile(path string) (*Theme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := LoadTheme(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t Theme) copy() *Theme {
+	colors := make(map[string]string, len(t.Colors))
+	for k, v := range t.Colors {
+		colors[k] = v
+	}
+	t.Colors = colors
+	return &t
+}
+
+// template returns the template of the prompt variable.
+func (t *Theme) template(name string) string {
+	switch name {
+	case "PS1":
+		return t.PS1
+	case "PS2":
+		return t.PS2
+	case "PS4":
+		return t.PS4
+	case "RPROMPT":
+		return t.RPrompt
+	}
+	return ""
+}
+
+var basicColors = map[string]uint8{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+// parseColor parses "fg" or "fg,bg" of 256-color numbers or names.
+func parseColor(spec string) (*color.Style256, bool) {
+	parts := strings.SplitN(spec, ",", 2)
+	codes := make([]uint8, len(parts))
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if n, err := strconv.ParseUint(p, 10, 8); err == nil {
+			codes[i] = uint8(n)
+		} else if c, ok := basicColors[strings.TrimPrefix(p, "bright-")]; ok {
+			if strings.HasPrefix(p, "bright-") {
+				c += 8
+			}
+			codes[i] = c
 		} else {
-			for {
-				dir, file := filepath.Split(pathStr)
-				if file == "" {
-					if dir == "/" {
-						fragments = append(fragments, "")
-					}
-					break
-				}
-				fragments = append(fragments, file)
-				pathStr = strings.TrimSuffix(dir, string(filepath.Separator))
+			return nil, false
+		}
+	}
+	if len(codes) == 2 {
+		return color.S256(codes[0], codes[1]), true
+	}
+	return color.S256(codes[0]), true
+}
+
+// Prompt renders PS1 of the theme of the context. Use Shell.RenderPrompt to use PS1 variable and "$(...)".
+func Prompt(pc PromptContext) string {
+	result, err := expandPrompt(pc.theme().PS1, pc, nil)
+	if err != nil {
+		return "$ "
+	}
+	return result
+}
+
+func (pc PromptContext) theme() *Theme {
+	if pc.Theme != nil {
+		return pc.Theme
+	}
+	return DefaultTheme
+}
+
+// RenderPrompt renders the prompt variable ("PS1", "PS2", "PS4" or "RPROMPT").
+// The theme of the context is used if the variable is not set.
+//
+// The template supports bash escapes (\u, \h, \H, \w, \W, \t, \d, \?, \j, \$, \n, \e, \\ and \[ \]),
+// "$(command)" and Go text/template actions. The data of the template is PromptContext and
+// the functions are:
+//
+//	style NAME TEXT  colors the text with the color of the theme
+//	color SPEC TEXT  colors the text with the color like "255,39"
+//	sh COMMAND       runs the command and returns its stdout
+//
+// Commands run in a forked shell, so they don't change the state of the shell.
+// They aren't logged and don't emit events or run hooks.
+func (s *Shell) RenderPrompt(ctx context.Context, name string, pc PromptContext) (string, error) {
+	tmpl, ok := s.LookupEnv(name)
+	if !ok {
+		tmpl = pc.theme().template(name)
+	}
+	return expandPrompt(tmpl, pc, func(cmd string) string {
+		var stdout bytes.Buffer
+		// commands in prompts don't run hooks and aren't traced
+		ctx := context.WithValue(ctx, inPromptKey{}, true)
+		ctx = context.WithValue(ctx, inHookKey{}, name)
+		s.promptShell().Run(ctx, cmd, &stdout, ioutil.Discard)
+		return strings.TrimRight(stdout.String(), "\n")
+	})
+}
+
+type inPromptKey struct{}
+
+// promptShell returns the fork to run commands of prompts. Its logs and event bus are separated from the shell.
+func (s *Shell) promptShell() *Shell {
+	f := s.Fork()
+	f.option.Log = false
+	f.logs = newLogRecorder()
+	f.events = newEventBus()
+	return f
+}
+
+// expandPrompt translates escapes and "$(...)" of the prompt into template actions and executes it.
+// Values are never parsed as templates, so directory names like "{{" are shown as they are.
+func expandPrompt(src string, pc PromptContext, run func(cmd string) string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "{{"):
+			end := strings.Index(src[i:], "}}")
+			if end == -1 {
+				b.WriteString(src[i:])
+				i = len(src)
+				continue
+			}
+			b.WriteString(src[i : i+end+2])
+			i += end + 1
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'u':
+				b.WriteString("{{.User}}")
+			case 'h':
+				b.WriteString("{{.ShortHost}}")
+			case 'H':
+				b.WriteString("{{.Host}}")
+			case 'w':
+				b.WriteString("{{.Dir}}")
+			case 'W':
+				b.WriteString("{{.Base}}")
+			case 't':
+				b.WriteString(`{{.Time.Format "15:04:05"}}`)
+			case 'd':
+				b.WriteString(`{{.Time.Format "Mon Jan 02"}}`)
+			case '?':
+				b.WriteString("{{.Status}}")
+			case 'j':
+				// tish has no background jobs
+				b.WriteByte('0')
+			case '$':
+				b.WriteString("{{.Sigil}}")
+			case 'n':
+				b.WriteByte('\n')
+			case 'e':
+				b.WriteByte('\x1b')
+			case '[', ']':
+				// markers of non-printing characters for bash compatibility
+			default:
+				b.WriteByte(src[i])
 			}
+		case c == '$' && strings.HasPrefix(src[i:], "$("):
+			end := closingParen(src, i+2)
+			if end == -1 {
+				b.WriteString(src[i:])
+				i = len(src)
+				continue
+			}
+			fmt.Fprintf(&b, "{{sh %q}}", src[i+2:end])
+			i = end
+		default:
+			b.WriteByte(c)
 		}
-		buffer.WriteString(pathColor.Sprint("  📁"))
-		for i := len(fragments)-1; i >= 0; i-- {
-			f := fragments[i]
-			if f != "" {
-				buffer.WriteString(pathColor.Sprint(f))
+	}
+
+	theme := pc.theme()
+	funcs := template.FuncMap{
+		"style": func(name string, text interface{}) string {
+			return colorize(pc.Plain, theme.Colors[name], fmt.Sprint(text))
+		},
+		"color": func(spec string, text interface{}) string {
+			return colorize(pc.Plain, spec, fmt.Sprint(text))
+		},
+		"sh": func(cmd string) string {
+			if run == nil {
+				return ""
+			}
+			return run(cmd)
+		},
+	}
+	t, err := template.New("prompt").Funcs(funcs).Parse(b.String())
+	if err != nil {
+		return "", err
+	}
+	var result bytes.Buffer
+	if err := t.Execute(&result, pc); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// closingParen returns the index of ")" that closes the parenthesis opened before start.
+func closingParen(src string, start int) int {
+	depth := 1
+	var quote byte
+	for i := start; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
 			}
-			if i != 0 {
-				buffer.WriteString(pathSepColor.Sprint("/"))
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
 			}
 		}
-		buffer.WriteString(pathColor.Sprint("  "))
 	}
-	if status != 0 {
-		buffer.WriteString(statusNgColor.Sprint("  ✘  "))
-	} else {
-		buffer.WriteString(statusOkColor.Sprint("  ✔︎  "))
+	return -1
+}
+
+func colorize(plain bool, spec, text string) string {
+	if plain || spec == "" || text == "" {
+		return text
+	}
+	style, ok := parseColor(spec)
+	if !ok {
+		return text
 	}
-	buffer.WriteString(cursorColor.Sprint("\n≫ "))
-	return buffer.String()
+	return style.Sprint(text)
 }
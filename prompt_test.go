@@ -1,9 +1,13 @@
 package tish
 
 import (
+	"context"
+	"io"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPrompt(t *testing.T) {
@@ -14,6 +18,7 @@ func TestPrompt(t *testing.T) {
 		home  string
 		now    time.Time
 		status int
+		git    *GitInfo
 	}
 	tests := []struct {
 		name string
@@ -81,12 +86,191 @@ func TestPrompt(t *testing.T) {
 			},
 			want: " ✘ ",
 		},
+		{
+			name: "contains git branch",
+			args: args{
+				git: &GitInfo{Branch: "main", Ahead: 1, Behind: 2, Staged: true, Dirty: true, Untracked: true},
+			},
+			want: "⎇ main ↑1 ↓2 + ± … ",
+		},
+		{
+			name: "contains detached head",
+			args: args{
+				git: &GitInfo{Branch: "1a2b3c4", Detached: true},
+			},
+			want: "➦ 1a2b3c4  ",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := Prompt(tt.args.user, tt.args.host, tt.args.wd, tt.args.home, tt.args.now, tt.args.status, true); !strings.Contains(got, tt.want) {
+			got := Prompt(PromptContext{
+				User:       tt.args.user,
+				Host:       tt.args.host,
+				WorkingDir: tt.args.wd,
+				HomeDir:    tt.args.home,
+				Time:       tt.args.now,
+				Status:     tt.args.status,
+				Git:        tt.args.git,
+				Plain:      true,
+			})
+			if !strings.Contains(got, tt.want) {
 				t.Errorf("Prompt() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestShell_RenderPrompt(t *testing.T) {
+	root := CreateTestFolders(t, "render-prompt")
+
+	pc := PromptContext{
+		User:       "myname",
+		Host:       "host.example.com",
+		WorkingDir: "/users/home/work/{{tish}}",
+		HomeDir:    "/users/home",
+		Time:       time.Date(2021, time.April, 10, 16, 17, 18, 0, time.Local),
+		Status:     1,
+		Duration:   1500 * time.Millisecond,
+		Git:        &GitInfo{Branch: "main", Dirty: true},
+		Plain:      true,
+	}
+	tests := []struct {
+		name string
+		env  []string
+		ps   string
+		pc   func(pc PromptContext) PromptContext
+		want string
+	}{
+		{
+			name: "bash escapes",
+			env:  []string{`PS1=\u@\h(\H):\w [\W] \t \? \j\$ `},
+			want: "myname@host(host.example.com):~/work/{{tish}} [{{tish}}] 16:17:18 1 0$ ",
+		},
+		{
+			name: "other escapes",
+			env:  []string{`PS1=\[\e[1m\]\d\n\\> `},
+			want: "\x1b[1mSat Apr 10\n\\> ",
+		},
+		{
+			name: "template",
+			env:  []string{`PS1={{.Git.Branch}}{{if .Git.Dirty}}*{{end}} {{.Duration}} {{style "path" .Base}}> `},
+			want: "main* 1.5s {{tish}}> ",
+		},
+		{
+			name: "command substitution",
+			env:  []string{"PS1=$(echo (a) b) > "},
+			want: "(a) b > ",
+		},
+		{
+			name: "root",
+			env:  []string{`PS1=\$ `},
+			pc: func(pc PromptContext) PromptContext {
+				pc.User = "root"
+				return pc
+			},
+			want: "# ",
+		},
+		{
+			name: "theme",
+			pc: func(pc PromptContext) PromptContext {
+				pc.Theme, _ = BuiltinTheme("simple")
+				return pc
+			},
+			want: "myname@host:~/work/{{tish}}$ ",
+		},
+		{
+			name: "secondary prompt of default theme",
+			ps:   "PS2",
+			want: "… ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(root, tt.env)
+			c := pc
+			if tt.pc != nil {
+				c = tt.pc(c)
+			}
+			ps := tt.ps
+			if ps == "" {
+				ps = "PS1"
+			}
+			got, err := s.RenderPrompt(context.Background(), ps, c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	s := NewShell(root, []string{"PS1={{.Unknown}}"})
+	_, err := s.RenderPrompt(context.Background(), "PS1", pc)
+	assert.Error(t, err)
+}
+
+func TestPrompt_Color(t *testing.T) {
+	theme, ok := BuiltinTheme("simple")
+	assert.True(t, ok)
+	pc := PromptContext{User: "myname", Host: "host", WorkingDir: "/tmp", Theme: theme}
+	colored := Prompt(pc)
+	pc.Plain = true
+	assert.Equal(t, "myname@host:/tmp$ ", Prompt(pc))
+	if colored != Prompt(pc) {
+		assert.Contains(t, colored, "\x1b[")
+	}
+
+	s := NewShell("/tmp", []string{"NO_COLOR=1", "USER=tish", "HOSTNAME=example", "HOME=/home/tish"})
+	s.SetTheme(theme)
+	pc = s.NewPromptContext()
+	assert.True(t, pc.Plain)
+	assert.Equal(t, "tish@example:/tmp$ ", Prompt(pc))
+}
+
+func TestLoadTheme(t *testing.T) {
+	theme, err := LoadTheme(strings.NewReader(`{"name": "simple", "ps1": "\\W> ", "colors": {"path": "1,2"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "simple", theme.Name)
+	assert.Equal(t, `\W> `, theme.PS1)
+	assert.Equal(t, "> ", theme.PS2)
+	assert.Equal(t, map[string]string{"user": "bright-green", "path": "1,2"}, theme.Colors)
+
+	theme, err = LoadTheme(strings.NewReader(`{"rprompt": "\\t"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultTheme.PS1, theme.PS1)
+	assert.Equal(t, `\t`, theme.RPrompt)
+
+	_, err = LoadTheme(strings.NewReader(`{`))
+	assert.Error(t, err)
+
+	_, ok := BuiltinTheme("unknown")
+	assert.False(t, ok)
+}
+
+func TestShell_XTrace(t *testing.T) {
+	root := CreateTestFolders(t, "xtrace")
+
+	s := NewShell(root, []string{"PS4=+$(echo traced) "}, Option{XTrace: true})
+	registerMockCommand(t, s, "mock")
+	var stderr strings.Builder
+	_, err := s.Run(context.Background(), "mock 'a b' c", &strings.Builder{}, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, "+traced mock 'a b' c\n", stderr.String())
+}
+
+func TestShell_RenderPrompt_Quiet(t *testing.T) {
+	root := CreateTestFolders(t, "render-prompt-quiet")
+
+	s := NewShell(root, []string{"PS1=$(mock) > "}, Option{Log: true})
+	mock := registerMockCommand(t, s, "mock")
+	mock.Stdout = "out"
+	var events, hooks int
+	s.Subscribe(func(e Event) { events++ })
+	s.AddHook(PreExec, func(ctx context.Context, s *Shell, args []string, stdout, stderr io.Writer) error {
+		hooks++
+		return nil
+	})
+	got, err := s.RenderPrompt(context.Background(), "PS1", PromptContext{})
+	assert.NoError(t, err)
+	assert.Equal(t, "out > ", got)
+	assert.Empty(t, s.Log().Logs)
+	assert.Equal(t, 0, events)
+	assert.Equal(t, 0, hooks)
+}
@@ -0,0 +1,110 @@
+package tish
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RegisterCommand registers the command to the shell. If the command of the same name exists, it is overridden.
+//
+// It doesn't affect other shells. Use package level RegisterCommand to add an applet to all shells.
+func (s *Shell) RegisterCommand(command *Command) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, cmd := range s.commands {
+		if cmd.Name == command.Name {
+			s.commands[i] = command
+			return
+		}
+	}
+	s.commands = append(s.commands, command)
+	sort.Slice(s.commands, func(i, j int) bool {
+		return s.commands[i].Name < s.commands[j].Name
+	})
+}
+
+// UnregisterCommand removes the command from the shell. It returns false if the command is not registered.
+//
+// External commands are still available unless SafeMode is enabled.
+func (s *Shell) UnregisterCommand(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, cmd := range s.commands {
+		if cmd.Name == name {
+			s.commands = append(s.commands[:i:i], s.commands[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Commands returns the commands registered to the shell in name order.
+func (s *Shell) Commands() []*Command {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]*Command{}, s.commands...)
+}
+
+// HasCommand returns true if the command is registered to the shell.
+func (s *Shell) HasCommand(name string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, cmd := range s.commands {
+		if cmd.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Applets returns the applets registered by package level RegisterCommand in name order.
+func Applets() []*Command {
+	return append([]*Command{}, commands...)
+}
+
+// ErrUnknownCommand is returned by Option.Validate if Option.Commands has a name that is not an applet.
+type ErrUnknownCommand struct {
+	Name string
+}
+
+func (e ErrUnknownCommand) Error() string {
+	return fmt.Sprintf("unknown command: %s", e.Name)
+}
+
+// Validate checks the option. It returns ErrUnknownCommand if Commands has a name of applet that is not registered.
+//
+// NewShell ignores unknown names of Commands, so call it before NewShell if the option is given by users.
+func (o Option) Validate() error {
+	for _, name := range o.Commands {
+		found := false
+		for _, cmd := range commands {
+			if cmd.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrUnknownCommand{Name: name}
+		}
+	}
+	return nil
+}
+
+// selectApplets returns the applets of the names. All applets are returned if names is empty.
+// Unknown names are ignored (see Option.Validate).
+func selectApplets(names []string) []*Command {
+	if len(names) == 0 {
+		return Applets()
+	}
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled[name] = true
+	}
+	var result []*Command
+	for _, cmd := range commands {
+		if enabled[cmd.Name] {
+			result = append(result, cmd)
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func registerTestApplets(t *testing.T, names ...string) map[string]*mockExecutor {
+	t.Helper()
+	saved := commands
+	t.Cleanup(func() {
+		commands = saved
+	})
+	commands = nil
+	mocks := map[string]*mockExecutor{}
+	for _, name := range names {
+		me := &mockExecutor{Stdout: name}
+		mocks[name] = me
+		RegisterCommand(&Command{Name: name, Executor: me.Executor})
+	}
+	return mocks
+}
+
+func commandNames(cmds []*Command) []string {
+	var names []string
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+func TestShell_RegisterCommand(t *testing.T) {
+	root := CreateTestFolders(t, "registry")
+	registerTestApplets(t, "rm", "cat", "echo")
+
+	s := NewShell(root, []string{})
+	other := NewShell(root, []string{})
+	assert.Equal(t, []string{"cat", "echo", "rm"}, commandNames(s.Commands()))
+
+	// override
+	safeRm := &mockExecutor{Stdout: "safe rm"}
+	s.RegisterCommand(&Command{Name: "rm", Executor: safeRm.Executor})
+	// add
+	s.RegisterCommand(&Command{Name: "hello", Executor: (&mockExecutor{Stdout: "hello"}).Executor})
+	assert.Equal(t, []string{"cat", "echo", "hello", "rm"}, commandNames(s.Commands()))
+
+	var stdout bytes.Buffer
+	_, err := s.Run(context.Background(), "rm file", &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, "safe rm", stdout.String())
+
+	// unregister
+	assert.True(t, s.UnregisterCommand("cat"))
+	assert.False(t, s.UnregisterCommand("cat"))
+	assert.False(t, s.HasCommand("cat"))
+	assert.True(t, s.HasCommand("hello"))
+
+	// other shell is not affected
+	assert.Equal(t, []string{"cat", "echo", "rm"}, commandNames(other.Commands()))
+	stdout.Reset()
+	_, err = other.Run(context.Background(), "rm file", &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, "rm", stdout.String())
+}
+
+func TestNewShell_CommandsOption(t *testing.T) {
+	root := CreateTestFolders(t, "registry-subset")
+	registerTestApplets(t, "rm", "cat", "echo", "ls")
+
+	s := NewShell(root, []string{}, Option{
+		SafeMode: true,
+		Commands: []string{"cat", "echo", "ls", "unknown"},
+	})
+	assert.Equal(t, []string{"cat", "echo", "ls"}, commandNames(s.Commands()))
+
+	code, err := s.Run(context.Background(), "rm file", io.Discard, io.Discard)
+	assert.Error(t, err)
+	assert.Equal(t, 127, code)
+
+	// forked shell keeps the subset
+	assert.Equal(t, []string{"cat", "echo", "ls"}, commandNames(s.Fork().Commands()))
+
+	assert.Equal(t, ErrUnknownCommand{Name: "unknown"}, Option{Commands: []string{"cat", "unknown"}}.Validate())
+	assert.NoError(t, Option{Commands: []string{"cat", "echo"}}.Validate())
+	assert.NoError(t, Option{}.Validate())
+}
@@ -0,0 +1,134 @@
+package tish
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ReplayResult is a result of replaying a recorded command.
+type ReplayResult struct {
+	Index      int
+	Command    string
+	WorkingDir string
+
+	ExpectedExitCode int
+	ActualExitCode   int
+
+	// StdoutDiff and StderrDiff are line diffs ("-" expected, "+" actual, " " same).
+	// They are nil if the output is as same as the recording.
+	StdoutDiff []string
+	StderrDiff []string
+}
+
+// OK returns true if the replayed command has the same output and exit code.
+func (r ReplayResult) OK() bool {
+	return r.ExpectedExitCode == r.ActualExitCode && r.StdoutDiff == nil && r.StderrDiff == nil
+}
+
+// Replay runs each recorded command of the log in a fresh Shell with the recorded env and working directory,
+// then compares stdout, stderr and exit code with the recording.
+//
+// Command substitutions and child tasks are not replayed separately because they run again as a part of the command.
+// setup is called for each Shell before running (to set file system, commands and so on).
+func Replay(ctx context.Context, root LogRoot, setup ...func(s *Shell)) []ReplayResult {
+	opt := root.Option
+	opt.Log = false
+	results := make([]ReplayResult, 0, len(root.Logs))
+	for i, l := range root.Logs {
+		s := NewShell(l.WorkingDir, envList(l.Env), opt)
+		for _, f := range setup {
+			f(s)
+		}
+		stdin := strings.NewReader("")
+		if len(l.Stdin) > 0 {
+			stdin = strings.NewReader(strings.Join(logLines(l.Stdin), "\n") + "\n")
+		}
+		stdout := newLineRecorder()
+		stderr := newLineRecorder()
+		code, _ := s.RunWithStdin(ctx, l.Command, stdin, stdout, stderr)
+		results = append(results, ReplayResult{
+			Index:            i,
+			Command:          l.Command,
+			WorkingDir:       l.WorkingDir,
+			ExpectedExitCode: l.ExitCode,
+			ActualExitCode:   code,
+			StdoutDiff:       diffLines(logLines(l.Stdout), logLines(stdout.flush())),
+			StderrDiff:       diffLines(logLines(l.Stderr), logLines(stderr.flush())),
+		})
+	}
+	return results
+}
+
+func envList(env map[string]string) []string {
+	result := make([]string, 0, len(env))
+	for k, v := range env {
+		result = append(result, k+"="+v)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func logLines(lines []LogLine) []string {
+	result := make([]string, len(lines))
+	for i, l := range lines {
+		result[i] = l.Line
+	}
+	return result
+}
+
+// diffLines returns line diff of two texts based on longest common subsequence.
+// It returns nil if they are the same.
+func diffLines(expected, actual []string) []string {
+	if len(expected) == len(actual) {
+		same := true
+		for i := range expected {
+			if expected[i] != actual[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return nil
+		}
+	}
+	// lcs[i][j] is the length of LCS of expected[i:] and actual[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	result := []string{}
+	i, j := 0, 0
+	for i < len(expected) && j < len(actual) {
+		switch {
+		case expected[i] == actual[j]:
+			result = append(result, " "+expected[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, "-"+expected[i])
+			i++
+		default:
+			result = append(result, "+"+actual[j])
+			j++
+		}
+	}
+	for ; i < len(expected); i++ {
+		result = append(result, "-"+expected[i])
+	}
+	for ; j < len(actual); j++ {
+		result = append(result, "+"+actual[j])
+	}
+	return result
+}
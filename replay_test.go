@@ -0,0 +1,93 @@
+package tish
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplay(t *testing.T) {
+	root := CreateTestFolders(t, "replay")
+
+	s := NewShell(root, []string{"KEY=value"}, Option{Log: true})
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock1.Stdout = "hello\nworld\n"
+	mock2 := registerMockCommand(t, s, "mock2")
+	mock2.Stderr = "warning\n"
+	mock2.ExitCode = 2
+
+	_, err := s.Run(context.Background(), `mock1 "it's a test" | mock2 ; mock2 $KEY`, io.Discard, io.Discard)
+	assert.NoError(t, err)
+	log := s.Log()
+
+	var replayed1 []*mockExecutor
+	var replayed2 *mockExecutor
+	setup := func(stdout string, exitCode int) func(s *Shell) {
+		replayed1 = nil
+		return func(s *Shell) {
+			mock1 := registerMockCommand(t, s, "mock1")
+			mock1.Stdout = stdout
+			replayed1 = append(replayed1, mock1)
+			replayed2 = registerMockCommand(t, s, "mock2")
+			replayed2.Stderr = "warning\n"
+			replayed2.ExitCode = exitCode
+		}
+	}
+
+	results := Replay(context.Background(), log, setup("hello\nworld\n", 2))
+	if assert.Len(t, results, 3) {
+		for _, r := range results {
+			assert.True(t, r.OK(), r)
+			assert.Equal(t, root, r.WorkingDir)
+		}
+		assert.Equal(t, `mock1 'it'\''s a test'`, results[0].Command)
+		assert.Equal(t, "mock2 value", results[2].Command)
+	}
+	assert.Equal(t, []string{"value"}, replayed2.Args)
+
+	results = Replay(context.Background(), log, setup("hello\nWorld\n", 1))
+	if assert.Len(t, results, 3) {
+		assert.False(t, results[0].OK())
+		assert.Equal(t, []string{" hello", "-world", "+World"}, results[0].StdoutDiff)
+		assert.Nil(t, results[0].StderrDiff)
+		assert.False(t, results[1].OK())
+		assert.Equal(t, 2, results[1].ExpectedExitCode)
+		assert.Equal(t, 1, results[1].ActualExitCode)
+	}
+	assert.Equal(t, []string{"it's a test"}, replayed1[0].Args)
+}
+
+func Test_diffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+		actual   []string
+		want     []string
+	}{
+		{
+			name:     "same",
+			expected: []string{"a", "b"},
+			actual:   []string{"a", "b"},
+			want:     nil,
+		},
+		{
+			name:     "changed",
+			expected: []string{"a", "b", "c"},
+			actual:   []string{"a", "x", "c", "d"},
+			want:     []string{" a", "-b", "+x", " c", "+d"},
+		},
+		{
+			name:     "removed",
+			expected: []string{"a", "b"},
+			actual:   []string{},
+			want:     []string{"-a", "-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diffLines(tt.expected, tt.actual))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package tish
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathNotAllowed is returned when a path is out of Option.AllowPath.
+type ErrPathNotAllowed struct {
+	Path     string
+	Resolved string
+}
+
+func (e ErrPathNotAllowed) Error() string {
+	if e.Path != e.Resolved {
+		return fmt.Sprintf("path is not allowed: %s (%s)", e.Path, e.Resolved)
+	}
+	return fmt.Sprintf("path is not allowed: %s", e.Path)
+}
+
+// initSandbox resolves Option.AllowPath to absolute paths.
+//
+// In safe mode without AllowPath, the initial working directory is the only allowed root.
+func (s *Shell) initSandbox() {
+	opt := s.Option()
+	roots := opt.AllowPath
+	if opt.SafeMode && len(roots) == 0 {
+		roots = []string{s.WorkingDir()}
+	}
+	fsys := s.FileSystem()
+	var allowRoots []string
+	for _, root := range roots {
+		allowRoots = append(allowRoots, resolveSymlinks(fsys, s.ExpandPath(root)))
+	}
+	s.lock.Lock()
+	s.allowRoots = allowRoots
+	s.lock.Unlock()
+}
+
+// Sandboxed returns true if file access of the shell is restricted to Option.AllowPath.
+func (s *Shell) Sandboxed() bool {
+	return len(s.roots()) > 0
+}
+
+func (s *Shell) roots() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.allowRoots
+}
+
+// ResolvePath expands path like ExpandPath and checks it is in allowed roots.
+//
+// Symbolic links are resolved before checking, so the link to outside of roots is not allowed.
+// It returns ErrPathNotAllowed if the path is not allowed.
+func (s *Shell) ResolvePath(path string) (string, error) {
+	abs := s.ExpandPath(path)
+	if err := s.checkPath(abs); err != nil {
+		return "", err
+	}
+	return abs, nil
+}
+
+func (s *Shell) checkPath(abs string) error {
+	roots := s.roots()
+	if len(roots) == 0 {
+		return nil
+	}
+	resolved := resolveSymlinks(s.FileSystem(), abs)
+	for _, root := range roots {
+		if isSubPath(root, resolved) {
+			return nil
+		}
+	}
+	return &ErrPathNotAllowed{
+		Path:     abs,
+		Resolved: resolved,
+	}
+}
+
+// resolveSymlinks resolves symbolic links of the path.
+// If the path doesn't exist yet (like redirect target), it resolves the existing parent.
+func resolveSymlinks(fsys FileSystem, path string) string {
+	path = filepath.Clean(path)
+	if resolved, err := fsys.EvalSymlinks(path); err == nil {
+		return resolved
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return path
+	}
+	dir, base := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if dir == path {
+		return path
+	}
+	return filepath.Join(resolveSymlinks(fsys, dir), base)
+}
+
+func isSubPath(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
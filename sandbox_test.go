@@ -0,0 +1,125 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shibukawa/tish/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_ResolvePath(t *testing.T) {
+	outside := CreateTestFolders(t, "sandbox-outside", map[string]string{
+		"secret.txt": "secret",
+	})
+	root := CreateTestFolders(t, "sandbox", map[string]string{
+		"work/test.txt":  "hello",
+		"other/test.txt": "hello",
+	})
+	os.Symlink(outside, filepath.Join(root, "work", "link"))
+
+	tests := []struct {
+		name    string
+		option  Option
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "no restriction",
+			path: "../other/test.txt",
+		},
+		{
+			name:   "safe mode: in working directory",
+			option: Option{SafeMode: true},
+			path:   "test.txt",
+		},
+		{
+			name:   "safe mode: new file",
+			option: Option{SafeMode: true},
+			path:   "new/file.txt",
+		},
+		{
+			name:    "safe mode: out of working directory",
+			option:  Option{SafeMode: true},
+			path:    "../other/test.txt",
+			wantErr: true,
+		},
+		{
+			name:    "safe mode: symlink to outside",
+			option:  Option{SafeMode: true},
+			path:    "link/secret.txt",
+			wantErr: true,
+		},
+		{
+			name:   "allow path",
+			option: Option{AllowPath: []string{filepath.Join(root, "work"), filepath.Join(root, "other")}},
+			path:   "../other/test.txt",
+		},
+		{
+			name:    "allow path: absolute path",
+			option:  Option{AllowPath: []string{filepath.Join(root, "work")}},
+			path:    outside,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(filepath.Join(root, "work"), nil, tt.option)
+			_, err := s.ResolvePath(tt.path)
+			if tt.wantErr {
+				var notAllowed *ErrPathNotAllowed
+				assert.ErrorAs(t, err, &notAllowed)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestShell_SafeMode(t *testing.T) {
+	root := CreateTestFolders(t, "safe-mode", map[string]string{
+		"work/test.txt": "hello",
+	})
+	wd := filepath.Join(root, "work")
+	s := NewShell(wd, nil, Option{SafeMode: true})
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock1.Stdout = "hello"
+
+	t.Run("external command is disabled", func(t *testing.T) {
+		assert.Nil(t, s.lookupCommand("sh"))
+		code, err := s.Run(context.Background(), "sh -c true", io.Discard, io.Discard)
+		assert.Equal(t, 127, code)
+		var notFound ErrCmdNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+	t.Run("redirect to outside", func(t *testing.T) {
+		code, err := s.Run(context.Background(), "mock1 > ../out.txt", io.Discard, io.Discard)
+		assert.Equal(t, 126, code)
+		var notAllowed *ErrPathNotAllowed
+		assert.ErrorAs(t, err, &notAllowed)
+		_, err = os.Stat(filepath.Join(root, "out.txt"))
+		assert.True(t, os.IsNotExist(err))
+	})
+	t.Run("redirect to inside", func(t *testing.T) {
+		code, err := s.Run(context.Background(), "mock1 > out.txt", io.Discard, io.Discard)
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+	})
+	t.Run("change directory to outside", func(t *testing.T) {
+		var stderr bytes.Buffer
+		err := s.SetWorkingDir("cd", "..", &stderr)
+		var notAllowed *ErrPathNotAllowed
+		assert.ErrorAs(t, err, &notAllowed)
+		assert.Equal(t, wd, s.WorkingDir())
+		assert.Equal(t, "cd: not allowed: ..\n", stderr.String())
+	})
+	t.Run("wildcard doesn't read outside", func(t *testing.T) {
+		files, err := s.expandWildcard(parser.Fragment{Term: "../*/*.txt"})
+		assert.ErrorIs(t, err, ErrWildcardNoMatchError)
+		assert.Empty(t, files)
+	})
+}
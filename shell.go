@@ -12,6 +12,8 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/shibukawa/tish/parser"
 )
@@ -23,6 +25,7 @@ var (
 	ErrCommandError         = errors.New("command error")
 	ErrRedirectError        = errors.New("redirect error")
 	ErrWildcardNoMatchError = errors.New("wild card no match")
+	ErrInterrupted          = errors.New("interrupted")
 )
 
 type ErrCmdNotFound struct {
@@ -47,9 +50,60 @@ func (e ErrFileNotFound) Error() string {
 
 var EnvVarPattern = regexp.MustCompile(`([a-zA-Z_]+[a-zA-Z0-9_]*)=(.*)`)
 
+// NoMatchBehavior is a behavior when wildcard doesn't match any files.
+type NoMatchBehavior int
+
+const (
+	// KeepPattern passes the pattern itself to the command like bash.
+	KeepPattern NoMatchBehavior = iota
+	// NullGlob removes the pattern from arguments (bash's nullglob).
+	NullGlob
+	// FailGlob stops the command with ErrWildcardNoMatchError (bash's failglob).
+	FailGlob
+)
+
 type Option struct {
 	IgnoreError bool     `json:"ignore_error"`
 	AllowPath   []string `json:"allow_path"`
+
+	// SafeMode disables external commands and restricts file access to AllowPath
+	// (or initial working directory if AllowPath is empty).
+	SafeMode bool `json:"safe_mode"`
+
+	// GlobStar enables "**" that matches any files and zero or more directories.
+	GlobStar bool `json:"globstar"`
+	// DotGlob makes wildcard match files begin with ".".
+	DotGlob bool `json:"dotglob"`
+	// NoMatch is a behavior when wildcard doesn't match any files.
+	NoMatch NoMatchBehavior `json:"no_match"`
+
+	// KillTimeout is a duration to wait after sending SIGINT and SIGTERM to external commands
+	// when the context is cancelled. Default is DefaultKillTimeout.
+	KillTimeout time.Duration `json:"kill_timeout"`
+
+	// CommandTimeout is a wall-clock timeout of each command. Zero means no limit.
+	CommandTimeout time.Duration `json:"command_timeout"`
+	// RunTimeout is a wall-clock timeout of each Shell.Run call. Zero means no limit.
+	RunTimeout time.Duration `json:"run_timeout"`
+	// MaxOutputBytes is the maximum bytes of stdout and stderr (each) of each Shell.Run call. Zero means no limit.
+	MaxOutputBytes int64 `json:"max_output_bytes"`
+	// OutputLimit is a behavior when output exceeds MaxOutputBytes.
+	OutputLimit OutputLimitBehavior `json:"output_limit"`
+	// MaxProcesses is the maximum number of concurrently running processes. Zero means no limit.
+	MaxProcesses int `json:"max_processes"`
+	// MaxSubstitutionDepth is the maximum nesting depth of command substitution. Zero means no limit.
+	MaxSubstitutionDepth int `json:"max_substitution_depth"`
+
+	// Log enables the execution log. Use Shell.Log or Shell.DumpLog to get it.
+	Log bool `json:"log"`
+
+	// Commands limits applets of the shell to the names (like "cat", "echo", "ls"). Empty means all applets.
+	// Unknown names are ignored by NewShell. Use Option.Validate to detect them.
+	// Use Shell.RegisterCommand and Shell.UnregisterCommand to change commands after creating the shell.
+	Commands []string `json:"commands,omitempty"`
+
+	// XTrace writes each command with its expanded arguments to stderr after PS4 prompt like "set -x".
+	XTrace bool `json:"xtrace"`
 }
 
 var (
@@ -64,20 +118,43 @@ func newProcessID() int {
 	return lastPid
 }
 
+// Shell keeps the state of shell (working directory, environment variables, directory stack and so on).
+//
+// It is safe to use from multiple goroutines. Commands in pipeline run in parallel and
+// they access the state through methods. Environ, LookupEnv, SetEnv and DelEnv replace the former Env field,
+// and DirStack, PushDir and PopDir replace the former Dirs field.
 type Shell struct {
 	wd       string
 	commands []*Command
-	Env      map[string]string
-	Dirs     []string
+	env      map[string]string
+	dirs     []string
 	Pid      int
-	lock     *sync.Mutex
+	lock     *sync.RWMutex
 	option   Option
+	aliases  map[string]string
+	fs       FileSystem
+
+	allowRoots []string
+	processes  *processCounter
+	times      CPUTimes
+	logs       *logRecorder
+	events     *eventBus
+	history    *History
+	theme      *Theme
+	bindings   map[string]string
+	// suggestions is a cache of Suggest
+	suggestions *suggestCache
+
+	middlewares        []Middleware
+	commandMiddlewares map[string][]Middleware
 }
 
-type CurrentShellStatus struct {
-	s    *Shell
-	envs map[string]string
-	pid  int
+// ShellState is a snapshot of the state of Shell.
+type ShellState struct {
+	WorkingDir string
+	Env        map[string]string
+	Dirs       []string
+	Aliases    map[string]string
 }
 
 func NewShell(cwd string, envs []string, opt ...Option) *Shell {
@@ -92,39 +169,185 @@ func NewShell(cwd string, envs []string, opt ...Option) *Shell {
 	}
 	s := &Shell{
 		wd:       cwd,
-		Env:      envMap,
-		lock:     &sync.Mutex{},
-		commands: commands,
+		env:      envMap,
+		lock:     &sync.RWMutex{},
+		commands: Applets(),
 		Pid:      newProcessID(),
+		aliases:  map[string]string{},
+		fs:       OSFileSystem{},
+		logs:     newLogRecorder(),
+		events:   newEventBus(),
+		history:  NewHistory(HistoryOption{}),
+		bindings: map[string]string{},
+
+		suggestions: &suggestCache{},
+		processes:   newProcessCounter(),
 	}
 	if len(opt) > 0 {
 		s.option = opt[0]
+		s.commands = selectApplets(s.option.Commands)
 	}
+	s.initSandbox()
 	return s
 }
 
+// Snapshot returns a copy of the current state.
+func (s *Shell) Snapshot() ShellState {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return ShellState{
+		WorkingDir: s.wd,
+		Env:        copyMap(s.env),
+		Dirs:       append([]string{}, s.dirs...),
+		Aliases:    copyMap(s.aliases),
+	}
+}
+
+// Fork creates a child shell that has a copy of the current state.
+//
+// Changes of the child (cd, export, alias and so on) don't affect the parent, like subshell.
+// File system, event subscribers, hooks, history, theme and the count of Option.MaxProcesses are shared. Options, commands, key bindings and middlewares are copied.
+func (s *Shell) Fork() *Shell {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return &Shell{
+		wd:         s.wd,
+		commands:   append([]*Command{}, s.commands...),
+		env:        copyMap(s.env),
+		dirs:       append([]string{}, s.dirs...),
+		Pid:        newProcessID(),
+		lock:       &sync.RWMutex{},
+		option:     s.option,
+		aliases:    copyMap(s.aliases),
+		fs:         s.fs,
+		allowRoots: s.allowRoots,
+		processes:  s.processes,
+		logs:       s.logs,
+		events:     s.events,
+		history:    s.history,
+		theme:      s.theme,
+		bindings:   copyMap(s.bindings),
+
+		suggestions:        &suggestCache{},
+		middlewares:        append([]Middleware{}, s.middlewares...),
+		commandMiddlewares: copyMiddlewares(s.commandMiddlewares),
+	}
+}
+
+func copyMap(src map[string]string) map[string]string {
+	result := make(map[string]string, len(src))
+	for k, v := range src {
+		result[k] = v
+	}
+	return result
+}
+
 func (s *Shell) Run(ctx context.Context, cmdStr string, stdout, stderr io.Writer) (code int, err error) {
-	sessionGroups, err := parser.ParseCommandStr(cmdStr)
+	return s.RunWithStdin(ctx, cmdStr, nil, stdout, stderr)
+}
+
+// RunWithStdin is Run that reads stdin from the reader instead of os.Stdin.
+//
+// PreExec hooks run before the command line and PreCmd hooks run after it.
+func (s *Shell) RunWithStdin(ctx context.Context, cmdStr string, stdin io.Reader, stdout, stderr io.Writer) (code int, err error) {
+	sessionGroups, err := parser.ParseCommandStr(s.expandAlias(cmdStr))
 	if err != nil {
 		return 1, err
 	}
-	s.runSessionGroups(ctx, sessionGroups, stdout, stderr)
-	return 0, nil
+	s.emit(CommandParsed{
+		Command:       cmdStr,
+		SessionGroups: sessionGroups,
+	})
+	opt := s.Option()
+	runCtx, cancel := withTimeout(ctx, opt.RunTimeout)
+	defer cancel()
+	var limitedStdout, limitedStderr *limitWriter
+	if opt.MaxOutputBytes > 0 {
+		limitedStdout = s.newLimitWriter(stdout, cancel)
+		limitedStderr = s.newLimitWriter(stderr, cancel)
+		stdout, stderr = limitedStdout, limitedStderr
+	}
+	s.RunHooks(runCtx, PreExec, stdout, stderr, cmdStr)
+	result, err := s.runSessionGroups(runCtx, sessionGroups, stdin, stdout, stderr)
+	s.RunHooks(runCtx, PreCmd, stdout, stderr)
+	if timedOut(ctx, runCtx) {
+		err = &ErrLimitExceeded{
+			Limit: RunTimeoutLimit,
+			Value: opt.RunTimeout,
+		}
+	} else if limitedStdout != nil {
+		if limitErr := limitedStdout.exceeded(); limitErr != nil {
+			err = limitErr
+		} else if limitErr := limitedStderr.exceeded(); limitErr != nil {
+			err = limitErr
+		}
+	}
+	return exitCode(result, err), err
+}
+
+// exitCode returns exit code of the result like bash.
+func exitCode(result *ExecResult, err error) int {
+	var notFound ErrCmdNotFound
+	var notAllowed *ErrPathNotAllowed
+	var limitExceeded *ErrLimitExceeded
+	switch {
+	case errors.As(err, &limitExceeded):
+		return limitExceeded.ExitCode()
+	case errors.Is(err, ErrInterrupted):
+		return 130
+	case errors.As(err, &notFound):
+		return 127
+	case errors.As(err, &notAllowed):
+		return 126
+	case result != nil:
+		return result.ExitCode()
+	case err != nil:
+		return 1
+	}
+	return 0
 }
 
-func (s *Shell) runSessionGroups(ctx context.Context, sessionGroups [][]*parser.Session, stdout, stderr io.Writer) (result *ExecResult, err error) {
+func (s *Shell) runSessionGroups(ctx context.Context, sessionGroups [][]*parser.Session, stdin io.Reader, stdout, stderr io.Writer) (result *ExecResult, err error) {
 	for _, sg := range sessionGroups {
-		result, err = s.runSessionGroup(ctx, sg, stdout, stderr)
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx)
+		}
+		jobID := s.newJobID()
+		jobCmd := sessionGroupString(sg)
+		s.emit(JobStateChanged{
+			JobID:   jobID,
+			Command: jobCmd,
+			State:   JobRunning,
+		})
+		wd := s.WorkingDir()
+		if opt, ok := parseTimeKeyword(sg); ok {
+			result, err = s.runTimedSessionGroup(ctx, opt, sg, stdin, stdout, stderr)
+		} else {
+			result, err = s.runSessionGroup(ctx, sg, stdin, stdout, stderr)
+		}
+		state := JobDone
+		if errors.Is(err, ErrInterrupted) {
+			state = JobInterrupted
+		}
+		s.emit(JobStateChanged{
+			JobID:    jobID,
+			Command:  jobCmd,
+			State:    state,
+			ExitCode: exitCode(result, err),
+		})
+		if s.WorkingDir() != wd {
+			s.RunHooks(ctx, ChPwd, stdout, stderr)
+		}
 		last := sg[len(sg)-1]
 		switch last.Separator {
 		case parser.Semicolon:
 			// do nothing
 		case parser.LogicalOr:
-			if result.ExitCode() == 0 {
+			if exitCode(result, err) == 0 {
 				return
 			}
 		case parser.LogicalAnd:
-			if result.ExitCode() != 0 {
+			if exitCode(result, err) != 0 {
 				return
 			}
 		}
@@ -132,36 +355,32 @@ func (s *Shell) runSessionGroups(ctx context.Context, sessionGroups [][]*parser.
 	return
 }
 
-func (s *Shell) runSessionGroup(ctx context.Context, sessions []*parser.Session, stdout, stderr io.Writer) (*ExecResult, error) {
+func (s *Shell) runSessionGroup(ctx context.Context, sessions []*parser.Session, stdin io.Reader, stdout, stderr io.Writer) (*ExecResult, error) {
 	var procs []*Process
 	for i, ses := range sessions {
 		pid := newProcessID()
-		for _, f := range ses.Fragments {
-			for _, subSes := range f.Sessions {
-				cmdName, args := subSes.GetCommand()
-				cmd := s.lookupCommand(cmdName)
-				if cmd == nil {
-					return nil, fmt.Errorf("command '%s' is not found: %w", cmdName, ErrCmdNotFound{})
-				}
-				subProc := NewProcess(s, cmd.Executor, cmdName, args, pid, newProcessID(), s.Env)
-				var stdout bytes.Buffer
-				subProc.Stdout = &stdout
-				err := subProc.StartAndWait(ctx)
-				if err != nil {
-					// todo: human readable error
-					return nil, ErrCommandError
-				}
-				f.Term = stdout.String()
-			}
+		log := s.newLog(ctx)
+		if err := s.substitute(ctx, ses, pid, log); err != nil {
+			return nil, err
+		}
+		cmdName, args, err := s.expandCommand(ses)
+		if err != nil {
+			return nil, err
+		}
+		if s.Option().XTrace && ctx.Value(inPromptKey{}) == nil {
+			s.trace(ctx, stderr, cmdName, args)
 		}
-		cmdName, args := ses.GetCommand()
 		cmd := s.lookupCommand(cmdName)
 		if cmd == nil {
 			return nil, fmt.Errorf("command '%s' is not found: %w", cmdName, ErrCmdNotFound{})
 		}
-		proc := NewProcess(s, cmd.Executor, cmdName, args, s.Pid, pid, s.Env)
+		proc := NewProcess(s, cmd.Executor, cmdName, args, s.Pid, pid, nil)
+		proc.log = log
 		proc.Stdout = stdout
 		proc.Stderr = stderr
+		if i == 0 && stdin != nil {
+			proc.Stdin = stdin
+		}
 		if i != 0 {
 			procs[i-1].Pipe(proc)
 		}
@@ -194,17 +413,111 @@ func (s *Shell) runSessionGroup(ctx context.Context, sessions []*parser.Session,
 		}
 		procs = append(procs, proc)
 	}
-	for _, proc := range procs {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for i, proc := range procs {
 		err := proc.Start(ctx)
 		if err != nil {
+			// stop and clean up processes that are already started
+			cancel()
+			for _, started := range procs[:i] {
+				started.Wait()
+			}
 			// todo: human readable error
 			return nil, err
 		}
 	}
+	var err, limitErr error
 	for _, proc := range procs {
-		proc.Wait()
+		err = proc.Wait()
+		var limitExceeded *ErrLimitExceeded
+		if limitErr == nil && errors.As(err, &limitExceeded) {
+			limitErr = err
+		}
+	}
+	if parent.Err() != nil {
+		return procs[len(procs)-1].Result, interrupted(parent)
+	}
+	if limitErr != nil {
+		return procs[len(procs)-1].Result, limitErr
+	}
+	return procs[len(procs)-1].Result, err
+}
+
+// trace writes the command after PS4 prompt.
+func (s *Shell) trace(ctx context.Context, stderr io.Writer, cmdName string, args []string) {
+	ps4, err := s.RenderPrompt(ctx, "PS4", s.newPromptContext())
+	if err != nil {
+		ps4 = "+ "
+	}
+	fmt.Fprintln(stderr, ps4+quoteWords(append([]string{cmdName}, args...)))
+}
+
+// substitute runs command substitutions (back quotes) in the session and replaces the fragments with their output.
+func (s *Shell) substitute(ctx context.Context, ses *parser.Session, ppid int, parentLog *logNode) error {
+	for i := range ses.Fragments {
+		f := &ses.Fragments[i]
+		if len(f.Sessions) == 0 {
+			continue
+		}
+		var term strings.Builder
+		for j, subSes := range f.Sessions {
+			if j < len(f.Texts) {
+				term.WriteString(f.Texts[j])
+			}
+			output, err := s.runSubstitution(ctx, subSes, ppid, parentLog)
+			if err != nil {
+				return err
+			}
+			term.WriteString(output)
+		}
+		for j := len(f.Sessions); j < len(f.Texts); j++ {
+			term.WriteString(f.Texts[j])
+		}
+		f.Term = term.String()
+	}
+	return nil
+}
+
+func (s *Shell) runSubstitution(ctx context.Context, ses *parser.Session, ppid int, parentLog *logNode) (string, error) {
+	var name string
+	if len(ses.Fragments) > 0 {
+		name = ses.Fragments[0].Term
+	}
+	ctx, err := s.enterSubstitution(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	pid := newProcessID()
+	log := s.newPreLog(parentLog)
+	if err := s.substitute(ctx, ses, pid, log); err != nil {
+		return "", err
+	}
+	cmdName, args, err := s.expandCommand(ses)
+	if err != nil {
+		return "", err
+	}
+	cmd := s.lookupCommand(cmdName)
+	if cmd == nil {
+		return "", fmt.Errorf("command '%s' is not found: %w", cmdName, ErrCmdNotFound{})
 	}
-	return procs[len(procs)-1].Result, nil
+	proc := NewProcess(s, cmd.Executor, cmdName, args, ppid, pid, nil)
+	proc.log = log
+	var stdout bytes.Buffer
+	proc.Stdout = &stdout
+	err = proc.StartAndWait(ctx)
+	if ctx.Err() != nil {
+		return "", interrupted(ctx)
+	}
+	var limitExceeded *ErrLimitExceeded
+	if errors.As(err, &limitExceeded) {
+		return "", err
+	} else if err != nil {
+		// todo: human readable error
+		return "", ErrCommandError
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
 }
 
 func (s *Shell) RunChildProcess(ctx context.Context, p *Process, cmdName string, args []string) (*ExecResult, error) {
@@ -213,35 +526,129 @@ func (s *Shell) RunChildProcess(ctx context.Context, p *Process, cmdName string,
 		return nil, fmt.Errorf("command '%s' is not found: %w", cmdName, ErrCmdNotFound{})
 	}
 	proc := NewProcess(s, cmd.Executor, cmdName, args, p.Pid, newProcessID(), p.Env())
+	proc.Stdin = p.Stdin
+	proc.Stdout = p.Stdout
+	proc.Stderr = p.Stderr
+	proc.log = s.newLog(ctx)
 	err := proc.StartAndWait(ctx)
 	return proc.Result, err
 }
 
+// Getenv returns the value of environment variable. It returns empty string if it is not set.
+func (s *Shell) Getenv(key string) string {
+	v, _ := s.LookupEnv(key)
+	return v
+}
+
+// LookupEnv returns the value of environment variable and whether it is set.
+func (s *Shell) LookupEnv(key string) (string, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	v, ok := s.env[key]
+	return v, ok
+}
+
+// Environ returns a copy of all environment variables.
+func (s *Shell) Environ() map[string]string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return copyMap(s.env)
+}
+
 func (s *Shell) SetEnv(key, value string) {
-	s.Env[key] = value
+	s.lock.Lock()
+	s.env[key] = value
+	s.lock.Unlock()
+	s.emit(EnvChanged{Key: key, Value: value})
 }
 
 func (s *Shell) DelEnv(key string) {
-	delete(s.Env, key)
+	s.lock.Lock()
+	delete(s.env, key)
+	s.lock.Unlock()
+	s.emit(EnvChanged{Key: key, Deleted: true})
+}
+
+// PushDir pushes the directory to the directory stack.
+func (s *Shell) PushDir(dir string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.dirs = append(s.dirs, dir)
 }
 
+// PopDir pops the last directory from the directory stack.
+// It returns false if the stack is empty.
+func (s *Shell) PopDir() (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.dirs) == 0 {
+		return "", false
+	}
+	last := s.dirs[len(s.dirs)-1]
+	s.dirs = s.dirs[:len(s.dirs)-1]
+	return last, true
+}
+
+// DirStack returns a copy of the directory stack. The last one is the latest.
+func (s *Shell) DirStack() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]string{}, s.dirs...)
+}
+
+// lookupCommand finds the command and wraps its executor by middlewares.
 func (s *Shell) lookupCommand(cmdName string) *Command {
-	// todo: internal cmmand only mode (safe mode)
+	s.lock.RLock()
 	for _, cmd := range s.commands {
 		if cmd.Name == cmdName {
-			return cmd
+			s.lock.RUnlock()
+			return s.wrapCommand(cmdName, cmd)
 		}
 	}
+	s.lock.RUnlock()
+	if s.Option().SafeMode {
+		return nil
+	}
 	if cmd, err := lookupExternalCommand(cmdName); err == nil {
-		return cmd
+		return s.wrapCommand(cmdName, cmd)
 	}
 	return nil
 }
 
-func (s Shell) WorkingDir() string {
+// killTimeout returns Option.KillTimeout or DefaultKillTimeout if it is not set.
+func (s *Shell) killTimeout() time.Duration {
+	if timeout := s.Option().KillTimeout; timeout > 0 {
+		return timeout
+	}
+	return DefaultKillTimeout
+}
+
+// FileSystem returns the file system that internal commands and redirects access.
+func (s *Shell) FileSystem() FileSystem {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.fs
+}
+
+// SetFileSystem replaces the file system. Default is OSFileSystem.
+func (s *Shell) SetFileSystem(fsys FileSystem) {
+	s.lock.Lock()
+	s.fs = fsys
+	s.lock.Unlock()
+	s.initSandbox()
+}
+
+func (s *Shell) WorkingDir() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
 	return s.wd
 }
 
+// SetWorkingDir changes the working directory. The home directory is used if dirName is empty.
+// Errors are reported to stderr with commandName if it is not nil.
+//
+// It returns ErrPathNotAllowed if the directory is out of allowed roots, ErrFileNotFound if it doesn't exist
+// and *os.PathError for other errors like permission denied and "not a directory".
 func (s *Shell) SetWorkingDir(commandName, dirName string, stderr io.Writer) error {
 	var err error
 	if dirName == "" {
@@ -250,8 +657,19 @@ func (s *Shell) SetWorkingDir(commandName, dirName string, stderr io.Writer) err
 			return err
 		}
 	}
-	wd := s.ExpandPath(dirName)
-	_, err = os.Lstat(wd)
+	wd, err := s.ResolvePath(dirName)
+	if err != nil {
+		if stderr != nil {
+			var notAllowed *ErrPathNotAllowed
+			if errors.As(err, &notAllowed) {
+				fmt.Fprintf(stderr, "%s: not allowed: %s\n", commandName, dirName)
+			} else {
+				fmt.Fprintf(stderr, "%s: %s: %v\n", commandName, dirName, err)
+			}
+		}
+		return err
+	}
+	info, err := s.FileSystem().Stat(wd)
 	if os.IsNotExist(err) {
 		if stderr != nil {
 			fmt.Fprintf(stderr, "%s: no such file or directory: %s\n", commandName, dirName)
@@ -261,8 +679,27 @@ func (s *Shell) SetWorkingDir(commandName, dirName string, stderr io.Writer) err
 			NotFound: dirName,
 			Current:  s.WorkingDir(),
 		}
+	} else if err == nil && !info.IsDir() {
+		err = &os.PathError{Op: "chdir", Path: wd, Err: syscall.ENOTDIR}
+	}
+	if err != nil {
+		if stderr != nil {
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				fmt.Fprintf(stderr, "%s: %v: %s\n", commandName, pathErr.Err, dirName)
+			} else {
+				fmt.Fprintf(stderr, "%s: %v: %s\n", commandName, err, dirName)
+			}
+		}
+		return fmt.Errorf("%s: %w", commandName, err)
 	}
+	s.lock.Lock()
+	old := s.wd
 	s.wd = wd
+	s.lock.Unlock()
+	if old != wd {
+		s.emit(WorkingDirChanged{Old: old, New: wd})
+	}
 	return nil
 }
 
@@ -274,7 +711,7 @@ func (s *Shell) HomeDir() (string, error) {
 	case "plan9":
 		env, enverr = "home", "$home"
 	}
-	if v := s.Env[env]; v != "" {
+	if v := s.Getenv(env); v != "" {
 		return v, nil
 	}
 	switch runtime.GOOS {
@@ -286,19 +723,7 @@ func (s *Shell) HomeDir() (string, error) {
 	return "", errors.New(enverr + " is not defined")
 }
 
-func (s *Shell) expandWildcard(f parser.Fragment) ([]string, error) {
-	if !strings.ContainsAny(f.Term, "*?[") {
-		return []string{f.Term}, nil
-	}
-	path := f.Term
-	files, err := filepath.Glob(s.ExpandPath(path))
-	if len(files) == 0 {
-		return nil, ErrWildcardNoMatchError
-	}
-	return files, err
-}
-
-func (s Shell) ExpandPath(path string) string {
+func (s *Shell) ExpandPath(path string) string {
 	if filepath.IsAbs(path) {
 		return path
 	}
@@ -6,8 +6,13 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -39,6 +44,22 @@ func TestExecExternalCommand(t *testing.T) {
 	assert.NotEmpty(t, stdout.String())
 }
 
+func TestExecExternalCommand_Env(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("printenv is not available")
+	}
+	if _, err := exec.LookPath("printenv"); err != nil {
+		t.Skip("printenv is not available")
+	}
+	s := NewShell(".", []string{"GREETING=hello"})
+	s.SetEnv("EXPORTED", "world")
+	var stdout bytes.Buffer
+	code, err := s.Run(context.Background(), "printenv GREETING EXPORTED", &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "hello\nworld\n", stdout.String())
+}
+
 func TestShell_runSessionGroup_Pipe(t *testing.T) {
 	root := CreateTestFolders(t, "run-session-group-pipe")
 
@@ -63,7 +84,7 @@ func TestShell_runSessionGroup_Pipe(t *testing.T) {
 			},
 		},
 	}
-	result, err := s.runSessionGroup(context.Background(), sessions, io.Discard, io.Discard)
+	result, err := s.runSessionGroup(context.Background(), sessions, nil, io.Discard, io.Discard)
 
 	assert.NotNil(t, result)
 	assert.NoError(t, err)
@@ -121,7 +142,7 @@ func TestShell_runSessionGroup_RedirectStdout(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			sessions[0].Stdout = tc.args.filename
 			sessions[0].StdoutAppend = tc.args.append
-			result, err := s.runSessionGroup(context.Background(), sessions, io.Discard, io.Discard)
+			result, err := s.runSessionGroup(context.Background(), sessions, nil, io.Discard, io.Discard)
 
 			assert.NotNil(t, result)
 			assert.NoError(t, err)
@@ -183,7 +204,7 @@ func TestShell_runSessionGroup_RedirectStderr(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			sessions[0].Stderr = tc.args.filename
 			sessions[0].StderrAppend = tc.args.append
-			result, err := s.runSessionGroup(context.Background(), sessions, io.Discard, io.Discard)
+			result, err := s.runSessionGroup(context.Background(), sessions, nil, io.Discard, io.Discard)
 
 			assert.NotNil(t, result)
 			assert.NoError(t, err)
@@ -232,7 +253,7 @@ func TestShell_runSessionGroup_RedirectStdin(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			sessions[0].Stdin = tc.args.filename
-			result, err := s.runSessionGroup(context.Background(), sessions, io.Discard, io.Discard)
+			result, err := s.runSessionGroup(context.Background(), sessions, nil, io.Discard, io.Discard)
 
 			assert.NotNil(t, result)
 			assert.NoError(t, err)
@@ -405,10 +426,293 @@ func TestShell_runSessionGroup_Separator(t *testing.T) {
 			mock2.Callback = func() {
 				mock2Called = true
 			}
-			_, err := s.runSessionGroups(context.Background(), sessions, io.Discard, io.Discard)
+			_, err := s.runSessionGroups(context.Background(), sessions, nil, io.Discard, io.Discard)
 
 			assert.NoError(t, err)
 			assert.Equal(t, tc.wantCalled, mock2Called)
 		})
 	}
 }
+
+func TestShell_Wildcard_Option(t *testing.T) {
+	root := CreateTestFolders(t, "wildcard-option", map[string]string{
+		"a.go":          "",
+		"b.txt":         "",
+		".hidden.go":    "",
+		"sub/c.go":      "",
+		"sub/deep/d.go": "",
+		"sub/.e.go":     "",
+	})
+
+	tests := []struct {
+		name    string
+		option  Option
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "dot files are hidden",
+			pattern: "*.go",
+			want:    []string{"a.go"},
+		},
+		{
+			name:    "dot glob",
+			option:  Option{DotGlob: true},
+			pattern: "*.go",
+			want:    []string{".hidden.go", "a.go"},
+		},
+		{
+			name:    "explicit dot",
+			pattern: ".*.go",
+			want:    []string{".hidden.go"},
+		},
+		{
+			name:    "sub directory",
+			pattern: "*/*.go",
+			want:    []string{"sub/c.go"},
+		},
+		{
+			name:    "double asterisk without globstar",
+			pattern: "**/*.go",
+			want:    []string{"sub/c.go"},
+		},
+		{
+			name:    "globstar",
+			option:  Option{GlobStar: true},
+			pattern: "**/*.go",
+			want:    []string{"a.go", "sub/c.go", "sub/deep/d.go"},
+		},
+		{
+			name:    "globstar at last",
+			option:  Option{GlobStar: true},
+			pattern: "sub/**",
+			want:    []string{"sub/c.go", "sub/deep", "sub/deep/d.go"},
+		},
+		{
+			name:    "directories only",
+			pattern: "*/",
+			want:    []string{"sub/"},
+		},
+		{
+			name:    "keep pattern",
+			pattern: "*.rs",
+			want:    []string{"*.rs"},
+		},
+		{
+			name:    "null glob",
+			option:  Option{NoMatch: NullGlob},
+			pattern: "*.rs",
+			want:    nil,
+		},
+		{
+			name:    "fail glob",
+			option:  Option{NoMatch: FailGlob},
+			pattern: "*.rs",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(root, []string{}, tt.option)
+			files, err := s.expandFragment(parser.Fragment{Term: tt.pattern})
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrWildcardNoMatchError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, files)
+			}
+		})
+	}
+}
+
+func TestShell_Run_Wildcard(t *testing.T) {
+	root := CreateTestFolders(t, "run-wildcard", map[string]string{
+		"b.go":  "",
+		"a.go":  "",
+		"c.txt": "",
+	})
+
+	s := NewShell(root, []string{})
+	mock1 := registerMockCommand(t, s, "mock1")
+
+	_, err := s.Run(context.Background(), `mock1 *.go "*.go" \*.txt`, io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "b.go", "*.go", "*.txt"}, mock1.Args)
+}
+
+func TestShell_Run_Parallel(t *testing.T) {
+	root := CreateTestFolders(t, "run-parallel", map[string]string{
+		"dir/": "",
+	})
+
+	s := NewShell(root, []string{})
+	s.RegisterCommand(&Command{
+		Name: "setter",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			if p.Stdin != nil {
+				io.Copy(io.Discard, p.Stdin)
+			}
+			p.Shell.SetEnv("KEY"+p.Args[0], p.Args[0])
+			p.Shell.PushDir(p.Shell.WorkingDir())
+			p.Shell.SetAlias("a"+p.Args[0], "setter")
+			io.WriteString(p.Stdout, p.Env()["KEY"+p.Args[0]])
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var stdout bytes.Buffer
+			n := strconv.Itoa(i)
+			code, err := s.Run(context.Background(), "setter "+n+" | setter x"+n, &stdout, io.Discard)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, code)
+			assert.Equal(t, "x"+n, stdout.String())
+			s.Snapshot()
+			s.AliasNames()
+			s.SetWorkingDir("cd", "dir", io.Discard)
+			s.SetWorkingDir("cd", root, io.Discard)
+		}(i)
+	}
+	wg.Wait()
+
+	state := s.Snapshot()
+	assert.Len(t, state.Dirs, 40)
+	assert.Len(t, state.Aliases, 40)
+	assert.Equal(t, "7", state.Env["KEY7"])
+	assert.Equal(t, "x7", state.Env["KEYx7"])
+}
+
+func TestShell_Fork(t *testing.T) {
+	root := CreateTestFolders(t, "fork", map[string]string{
+		"dir/": "",
+	})
+
+	s := NewShell(root, []string{})
+	s.SetEnv("KEY", "parent")
+	s.SetAlias("ll", "ls -l")
+
+	child := s.Fork()
+	assert.NotEqual(t, s.Pid, child.Pid)
+	assert.Equal(t, s.Snapshot(), child.Snapshot())
+
+	child.SetEnv("KEY", "child")
+	child.DelAlias("ll")
+	child.PushDir(child.WorkingDir())
+	assert.NoError(t, child.SetWorkingDir("cd", "dir", io.Discard))
+
+	state := s.Snapshot()
+	assert.Equal(t, root, state.WorkingDir)
+	assert.Equal(t, "parent", state.Env["KEY"])
+	assert.Equal(t, map[string]string{"ll": "ls -l"}, state.Aliases)
+	assert.Empty(t, state.Dirs)
+
+	childState := child.Snapshot()
+	assert.Equal(t, filepath.Join(root, "dir"), childState.WorkingDir)
+	assert.Equal(t, "child", childState.Env["KEY"])
+	assert.Equal(t, []string{root}, childState.Dirs)
+}
+
+func TestShell_Run_Interrupt(t *testing.T) {
+	root := CreateTestFolders(t, "run-interrupt")
+
+	s := NewShell(root, []string{})
+	s.RegisterCommand(&Command{
+		Name: "block",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	mock1 := registerMockCommand(t, s, "mock1")
+	mock1.Stdout = "hello"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	code, err := s.Run(ctx, "mock1 | block ; mock1", io.Discard, io.Discard)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, 130, code)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// next command works with new context
+	var stdout bytes.Buffer
+	code, err = s.Run(context.Background(), "mock1", &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "hello", stdout.String())
+}
+
+func TestShell_Run_InterruptExternalCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signal is not supported")
+	}
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep command is not found")
+	}
+	root := CreateTestFolders(t, "run-interrupt-external")
+
+	s := NewShell(root, []string{})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	code, err := s.Run(ctx, "sleep 10", io.Discard, io.Discard)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, 130, code)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestShell_SetWorkingDir(t *testing.T) {
+	root := CreateTestFolders(t, "set-working-dir", map[string]string{
+		"dir/file.txt": "",
+	})
+	os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "link"))
+
+	s := NewShell(root, []string{})
+	t.Run("directory", func(t *testing.T) {
+		assert.NoError(t, s.SetWorkingDir("cd", "dir", io.Discard))
+		assert.Equal(t, filepath.Join(root, "dir"), s.WorkingDir())
+		assert.NoError(t, s.SetWorkingDir("cd", root, io.Discard))
+	})
+	t.Run("link to directory", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic link requires privilege on Windows")
+		}
+		assert.NoError(t, s.SetWorkingDir("cd", "link", io.Discard))
+		assert.NoError(t, s.SetWorkingDir("cd", root, io.Discard))
+	})
+	t.Run("not exist", func(t *testing.T) {
+		var stderr bytes.Buffer
+		err := s.SetWorkingDir("cd", "missing", &stderr)
+		var notFound *ErrFileNotFound
+		assert.ErrorAs(t, err, &notFound)
+		assert.Equal(t, "cd: no such file or directory: missing\n", stderr.String())
+		assert.Equal(t, root, s.WorkingDir())
+	})
+	t.Run("file", func(t *testing.T) {
+		var stderr bytes.Buffer
+		err := s.SetWorkingDir("cd", filepath.Join("dir", "file.txt"), &stderr)
+		assert.ErrorIs(t, err, syscall.ENOTDIR)
+		assert.Equal(t, "cd: not a directory: "+filepath.Join("dir", "file.txt")+"\n", stderr.String())
+		assert.Equal(t, root, s.WorkingDir())
+	})
+	t.Run("permission denied", func(t *testing.T) {
+		if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+			t.Skip("permission is not checked")
+		}
+		assert.NoError(t, os.Chmod(filepath.Join(root, "dir"), 0))
+		defer os.Chmod(filepath.Join(root, "dir"), 0755)
+		var stderr bytes.Buffer
+		err := s.SetWorkingDir("cd", filepath.Join("dir", "sub"), &stderr)
+		assert.ErrorIs(t, err, os.ErrPermission)
+		assert.Equal(t, "cd: permission denied: "+filepath.Join("dir", "sub")+"\n", stderr.String())
+	})
+}
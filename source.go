@@ -0,0 +1,86 @@
+package tish
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrScript is an error of the command at the line of the script file.
+type ErrScript struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e ErrScript) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e ErrScript) Unwrap() error {
+	return e.Err
+}
+
+// Source runs the script file in the shell like "source" of bash, so aliases, variables and options set by it remain.
+//
+// Each command line runs one by one. Lines continued by quotes, trailing backslash or operators are joined.
+// Errors of command lines are written to stderr as ErrScript and the script continues.
+// It stops and returns ErrExit if the script runs exit. Hooks don't run while the script is running.
+func (s *Shell) Source(ctx context.Context, path string, stdout, stderr io.Writer) (code int, err error) {
+	abs, err := s.ResolvePath(path)
+	if err != nil {
+		return 1, err
+	}
+	f, err := s.FileSystem().Open(abs)
+	if err != nil {
+		return 1, err
+	}
+	defer f.Close()
+	ctx = context.WithValue(ctx, inHookKey{}, path)
+
+	scanner := bufio.NewScanner(f)
+	lineNo, start := 0, 0
+	cmd := ""
+	run := func() error {
+		code, err = s.Run(ctx, cmd, stdout, stderr)
+		cmd = ""
+		if errors.Is(err, ErrExit) || errors.Is(err, ErrInterrupted) {
+			return err
+		} else if err != nil {
+			fmt.Fprintln(stderr, ErrScript{File: path, Line: start, Err: err})
+		}
+		return nil
+	}
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if cmd == "" {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			cmd, start = line, lineNo
+		} else {
+			cmd = JoinContinuation(cmd, line)
+		}
+		if NeedsContinuation(cmd) {
+			continue
+		}
+		if err := run(); err != nil {
+			return code, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 1, err
+	}
+	if cmd != "" {
+		// the file ends in the middle of the command line
+		if err := run(); err != nil {
+			return code, err
+		}
+	}
+	return code, nil
+}
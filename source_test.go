@@ -0,0 +1,63 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_Source(t *testing.T) {
+	root := CreateTestFolders(t, "source", map[string]string{
+		"tishrc": "# comment\nrecord a \\\n  b\n\nunknown-cmd x\nrecord \"c\nd\" &&\n  record e\nrecord f\n",
+	})
+	s := NewShell(root, nil)
+	var calls [][]string
+	s.RegisterCommand(&Command{
+		Name: "record",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) error {
+			calls = append(calls, p.Args)
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+	var stderr bytes.Buffer
+	code, err := s.Source(context.Background(), "tishrc", &bytes.Buffer{}, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c\nd"}, {"e"}, {"f"}}, calls)
+	assert.True(t, strings.HasPrefix(stderr.String(), "tishrc:5: "), stderr.String())
+	assert.Equal(t, 1, strings.Count(stderr.String(), "\n"))
+}
+
+func TestShell_Source_Exit(t *testing.T) {
+	root := CreateTestFolders(t, "source-exit", map[string]string{
+		"tishrc": "quit\nrecord\n",
+	})
+	s := NewShell(root, nil)
+	called := false
+	s.RegisterCommand(&Command{
+		Name: "quit",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) error {
+			result.SetInternalProcessResult(3)
+			return ErrExit
+		},
+	})
+	s.RegisterCommand(&Command{
+		Name: "record",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) error {
+			called = true
+			return nil
+		},
+	})
+	_, err := s.Source(context.Background(), filepath.Join(root, "tishrc"), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrExit))
+	assert.False(t, called)
+
+	_, err = s.Source(context.Background(), "missing", &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,255 @@
+package tish
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// Suggest returns the command line suggested for the typed line like fish, or "" if there is no suggestion.
+//
+// The newest history entry that starts with the line is used, and entries run in the current working directory are
+// preferred. Entries are skipped if their commands don't resolve or their paths don't exist anymore.
+// Paths are arguments that have "/" and ones that existed when the entry was added by AddHistory.
+// If the history has nothing, the unique completion of the last word is used.
+//
+// It is called for each key stroke, so results are cached until the history or the working directory is changed
+// or ResetSuggestions is called.
+func (s *Shell) Suggest(line string) string {
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+	wd := s.WorkingDir()
+	h := s.History()
+	entries := h.Entries()
+	key := suggestKey{history: h, wd: wd, count: len(entries)}
+	if len(entries) > 0 {
+		key.last = entries[len(entries)-1].Number
+	}
+	cache := s.suggestions.get(key)
+	fallback := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		cmd := entries[i].Command
+		if len(cmd) <= len(line) || !strings.HasPrefix(cmd, line) || strings.ContainsRune(cmd, '\n') {
+			continue
+		}
+		if !cache.valid(s, entries[i]) {
+			continue
+		}
+		if entries[i].WorkingDir == wd {
+			return cmd
+		} else if fallback == "" {
+			fallback = cmd
+		}
+	}
+	if fallback != "" || strings.HasSuffix(line, " ") {
+		return fallback
+	}
+	return cache.completion(s, line)
+}
+
+// ResetSuggestions clears the cache of Suggest. Files and commands may be changed by other programs,
+// so call it before each prompt.
+func (s *Shell) ResetSuggestions() {
+	s.suggestions.reset()
+}
+
+// canSuggest returns true if the command names of the entry resolve and its paths exist.
+func (s *Shell) canSuggest(entry HistoryEntry) bool {
+	for _, path := range entry.Paths {
+		if s.checkPath(path) != nil || !FileExists(s.FileSystem(), path) {
+			return false
+		}
+	}
+	tokens, err := parser.Tokenize(entry.Command)
+	if err != nil {
+		return false
+	}
+	input := false
+	for _, t := range tokens {
+		if t.Kind == parser.RedirectToken {
+			input = t.Word.Text == "<"
+			continue
+		}
+		checkPath := input
+		input = false
+		if t.Kind != parser.WordToken || !isStaticWord(t) {
+			continue
+		}
+		word := t.Word.Text
+		switch {
+		case t.Command:
+			if s.LookupCommandKind(word) == CommandNotFound {
+				return false
+			}
+		case checkPath || looksLikePath(word):
+			path := s.resolveIn(entry.WorkingDir, word)
+			if s.checkPath(path) != nil || !FileExists(s.FileSystem(), path) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// existingPaths returns absolute paths of arguments and input redirects of the line that exist in dir.
+// Output redirects are not included, because they are created by the command.
+func (s *Shell) existingPaths(line, dir string) []string {
+	tokens, err := parser.Tokenize(line)
+	if err != nil {
+		return nil
+	}
+	var result []string
+	found := map[string]bool{}
+	output := false
+	for _, t := range tokens {
+		if t.Kind == parser.RedirectToken {
+			output = t.Word.Text != "<"
+			continue
+		}
+		skip := output
+		output = false
+		if skip || t.Kind != parser.WordToken || t.Command || !isStaticWord(t) || !isPathCandidate(t.Word.Text) {
+			continue
+		}
+		path := s.resolveIn(dir, t.Word.Text)
+		if found[path] || s.checkPath(path) != nil || !FileExists(s.FileSystem(), path) {
+			continue
+		}
+		found[path] = true
+		result = append(result, path)
+	}
+	return result
+}
+
+// resolveIn returns the absolute path of the word relative to dir (or the working directory if dir is empty).
+func (s *Shell) resolveIn(dir, word string) string {
+	if filepath.IsAbs(word) {
+		return word
+	}
+	if dir == "" {
+		return s.ExpandPath(word)
+	}
+	return filepath.Join(dir, word)
+}
+
+// isPathCandidate returns true if the argument can be a file path, not a flag, an URL or a glob pattern.
+func isPathCandidate(word string) bool {
+	return word != "" && !strings.HasPrefix(word, "-") && !strings.Contains(word, "://") && !strings.ContainsAny(word, "*?[")
+}
+
+// looksLikePath returns true if the argument is a file path even if it was not recorded by AddHistory.
+func looksLikePath(word string) bool {
+	if !isPathCandidate(word) {
+		return false
+	}
+	return strings.HasPrefix(word, "~") || strings.ContainsRune(word, '/') || strings.ContainsRune(word, os.PathSeparator)
+}
+
+// suggestKey is the state of the shell that results of Suggest depend on.
+type suggestKey struct {
+	history *History
+	wd      string
+	count   int
+	last    int
+}
+
+// suggestCache keeps results of Suggest between key strokes.
+type suggestCache struct {
+	lock sync.Mutex
+	key  suggestKey
+	// validity of history entries
+	entries map[string]bool
+	// completions of lines
+	completions map[string]string
+	// names of applets, aliases and executables in PATH. It is nil until it is needed.
+	commands []string
+}
+
+// get returns the cache. It is cleared if the key is changed.
+func (c *suggestCache) get(key suggestKey) *suggestCache {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil || c.key != key {
+		c.key = key
+		c.entries = map[string]bool{}
+		c.completions = map[string]string{}
+		c.commands = nil
+	}
+	return c
+}
+
+// reset clears the cache. It is filled again by the next get.
+func (c *suggestCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+}
+
+func (c *suggestCache) valid(s *Shell, entry HistoryEntry) bool {
+	key := entry.Command + "\x00" + entry.WorkingDir + "\x00" + strings.Join(entry.Paths, "\x00")
+	c.lock.Lock()
+	ok, checked := c.entries[key]
+	c.lock.Unlock()
+	if checked {
+		return ok
+	}
+	ok = s.canSuggest(entry)
+	c.lock.Lock()
+	c.entries[key] = ok
+	c.lock.Unlock()
+	return ok
+}
+
+// completion returns the unique completion of the last word. Command names are listed once, because
+// reading all directories in PATH for each key stroke is slow.
+func (c *suggestCache) completion(s *Shell, line string) string {
+	c.lock.Lock()
+	result, ok := c.completions[line]
+	c.lock.Unlock()
+	if ok {
+		return result
+	}
+	var head string
+	var completions []string
+	if cc := AnalyzeCompletion(line, len(line)); cc.Kind == CompleteCommand && cc.Quote == 0 && !strings.ContainsAny(cc.Word, "/"+string(filepath.Separator)) {
+		head = line[:cc.Start]
+		for _, name := range c.commandNames(s) {
+			if strings.HasPrefix(name, cc.Word) {
+				completions = append(completions, name)
+			}
+		}
+	} else {
+		var tail string
+		head, completions, tail = s.Complete(line, len(line))
+		if tail != "" {
+			completions = nil
+		}
+	}
+	if len(completions) == 1 {
+		if candidate := head + completions[0]; len(candidate) > len(line) && strings.HasPrefix(candidate, line) {
+			result = candidate
+		}
+	}
+	c.lock.Lock()
+	c.completions[line] = result
+	c.lock.Unlock()
+	return result
+}
+
+func (c *suggestCache) commandNames(s *Shell) []string {
+	c.lock.Lock()
+	commands := c.commands
+	c.lock.Unlock()
+	if commands != nil {
+		return commands
+	}
+	commands = s.completeCommands("")
+	c.lock.Lock()
+	c.commands = commands
+	c.lock.Unlock()
+	return commands
+}
@@ -0,0 +1,103 @@
+package tish
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_Suggest(t *testing.T) {
+	root := CreateTestFolders(t, "suggest", map[string]string{
+		"doc/readme.md": "",
+		"main.go":       "",
+	})
+	defer os.RemoveAll(root)
+	other := filepath.Join(root, "doc")
+
+	s := NewShell(root, []string{"HOME=/home/tish", "PATH="}, Option{})
+	registerMockCommand(t, s, "mock")
+	registerMockCommand(t, s, "cat")
+	registerMockCommand(t, s, "mkdir")
+	s.SetAlias("mm", "mock -m")
+	for _, e := range []HistoryEntry{
+		{Command: "mock here", WorkingDir: root},
+		{Command: "mock there", WorkingDir: other},
+		{Command: "cat doc/readme.md", WorkingDir: root},
+		{Command: "cat doc/removed.md", WorkingDir: root},
+		{Command: "removed-command arg", WorkingDir: root},
+		{Command: "mm alias", WorkingDir: other},
+		{Command: "cat < main.go", WorkingDir: root},
+		{Command: "cat < deleted.txt", WorkingDir: root},
+		{Command: "mock 'a\nb'", WorkingDir: root},
+	} {
+		s.History().Add(e)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "empty", line: "", want: ""},
+		{name: "working directory first", line: "mock ", want: "mock here"},
+		{name: "newest in working directory", line: "mo", want: "mock here"},
+		{name: "other directory", line: "mock t", want: "mock there"},
+		{name: "alias", line: "mm", want: "mm alias"},
+		{name: "existing path", line: "cat doc", want: "cat doc/readme.md"},
+		{name: "removed path", line: "cat doc/rem", want: ""},
+		{name: "removed command", line: "remo", want: ""},
+		{name: "input redirect", line: "cat <", want: "cat < main.go"},
+		{name: "same as line", line: "mock here", want: ""},
+		{name: "completion", line: "mkd", want: "mkdir"},
+		{name: "completion of path", line: "cat ma", want: "cat main.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Suggest(tt.line))
+		})
+	}
+}
+
+func TestShell_Suggest_RecordedPaths(t *testing.T) {
+	root := CreateTestFolders(t, "suggest-paths", map[string]string{
+		"notes.txt":     "",
+		"sub/local.txt": "",
+	})
+	defer os.RemoveAll(root)
+	sub := filepath.Join(root, "sub")
+
+	s := NewShell(root, []string{"PATH="}, Option{})
+	registerMockCommand(t, s, "cat")
+	registerMockCommand(t, s, "rm")
+	for _, e := range []HistoryEntry{
+		{Command: "cat local.txt", WorkingDir: sub},
+		{Command: "cat notes.txt > out.txt", WorkingDir: root},
+	} {
+		_, err := s.AddHistory(e)
+		assert.NoError(t, err)
+	}
+	entries := s.History().Entries()
+	assert.Equal(t, []string{filepath.Join(sub, "local.txt")}, entries[0].Paths)
+	assert.Equal(t, []string{filepath.Join(root, "notes.txt")}, entries[1].Paths, "output redirect is not recorded")
+
+	// bare arguments are resolved in the directory of the entry
+	assert.Equal(t, "cat local.txt", s.Suggest("cat l"))
+	assert.Equal(t, "cat notes.txt > out.txt", s.Suggest("cat n"))
+
+	// results are cached while the history is not changed
+	assert.NoError(t, os.Remove(filepath.Join(root, "notes.txt")))
+	assert.Equal(t, "cat notes.txt > out.txt", s.Suggest("cat n"))
+
+	s.AddHistory(HistoryEntry{Command: "rm notes.txt", WorkingDir: root})
+	assert.Equal(t, "", s.Suggest("cat n"))
+	assert.Equal(t, "rm notes.txt", s.Suggest("rm "), "removed files are not recorded")
+
+	// files removed by other programs are detected after reset
+	assert.Equal(t, "cat local.txt", s.Suggest("cat l"))
+	assert.NoError(t, os.Remove(filepath.Join(sub, "local.txt")))
+	assert.Equal(t, "cat local.txt", s.Suggest("cat l"))
+	s.ResetSuggestions()
+	assert.Equal(t, "", s.Suggest("cat l"))
+}
@@ -0,0 +1,12 @@
+// broken is a plugin that exits without describing its commands.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "broken plugin")
+	os.Exit(1)
+}
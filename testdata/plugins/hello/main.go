@@ -0,0 +1,34 @@
+// hello is a plugin for tests of the plugin host.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/shibukawa/tish"
+	"github.com/shibukawa/tish/plugin"
+)
+
+func main() {
+	// describe and complete requests write it to stderr of the host
+	fmt.Fprintln(os.Stderr, "hello plugin started")
+	plugin.Serve(&plugin.Command{
+		Name:        "hello",
+		Description: "say hello",
+		Executor: func(ctx context.Context, result *tish.ExecResult, p *tish.Process) (err error) {
+			input, err := ioutil.ReadAll(p.Stdin)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(p.Stdout, "hello %v %s\n", p.Args, input)
+			fmt.Fprintln(p.Stderr, "to stderr")
+			result.SetInternalProcessResult(len(p.Args))
+			return nil
+		},
+		Completer: func(input string) []string {
+			return []string{input + "-candidate"}
+		},
+	})
+}
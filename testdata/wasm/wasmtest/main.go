@@ -0,0 +1,41 @@
+// wasmtest is a WASI module for tests of wasm applets. The first argument selects the behavior.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: wasmtest command [args...]")
+		os.Exit(2)
+	}
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "echo":
+		fmt.Println(os.Args[0], args)
+	case "stdin":
+		io.Copy(os.Stdout, os.Stdin)
+		fmt.Fprintln(os.Stderr, "to stderr")
+	case "env":
+		fmt.Println(os.Getenv(args[0]))
+	case "cat":
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(data)
+	case "exit":
+		code, _ := strconv.Atoi(args[0])
+		os.Exit(code)
+	case "sleep":
+		for {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -32,7 +32,7 @@ func CreateTestFolders(t *testing.T, commandName string, files ...map[string]str
 			if len(f) > 1 { //like |755
 				m, err := strconv.ParseUint(f[1], 8, 32)
 				if err != nil {
-					t.Errorf("parse mode error: %s %w", pathRule, err)
+					t.Errorf("parse mode error: %s %v", pathRule, err)
 					continue
 				}
 				mode = uint32(m)
@@ -79,7 +79,7 @@ func registerMockCommand(t *testing.T, s *Shell, name string) *mockExecutor {
 		Executor:  me.Executor,
 		Completer: nil,
 	}
-	s.commands = append(s.commands, dummyCommand)
+	s.RegisterCommand(dummyCommand)
 	return me
 }
 
@@ -110,3 +110,43 @@ func (d *mockExecutor) Executor(ctx context.Context, result *ExecResult, p *Proc
 	result.SetInternalProcessResult(d.ExitCode)
 	return nil
 }
+
+// CreateTestFileSystem creates MemoryFileSystem that has files like CreateTestFolders.
+//
+// It returns the root folder in the file system and the file system itself.
+// Set it to Shell by Shell.SetFileSystem().
+func CreateTestFileSystem(t *testing.T, commandName string, files ...map[string]string) (string, *MemoryFileSystem) {
+	t.Helper()
+	fsys := NewMemoryFileSystem()
+	root := filepath.Join(string(filepath.Separator), fmt.Sprintf("tish-%s-test", commandName))
+	fsys.MkdirAll(root, 0755)
+	if len(files) > 0 {
+		for pathRule, content := range files[0] {
+			var isSymLink bool
+			if strings.HasPrefix(pathRule, "@") {
+				isSymLink = true
+				pathRule = strings.TrimPrefix(pathRule, "@")
+			}
+			f := strings.Split(pathRule, "|")
+			path := f[0]
+			mode := os.FileMode(0777)
+			if len(f) > 1 { //like |755
+				m, err := strconv.ParseUint(f[1], 8, 32)
+				if err != nil {
+					t.Errorf("parse mode error: %s %v", pathRule, err)
+					continue
+				}
+				mode = os.FileMode(m)
+			}
+			fsys.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755)
+			if isSymLink {
+				fsys.Symlink(filepath.Join(root, content), filepath.Join(root, path))
+			} else if strings.HasSuffix(path, "/") {
+				fsys.MkdirAll(filepath.Join(root, path), 0755)
+			} else {
+				fsys.WriteFile(filepath.Join(root, path), []byte(content), mode)
+			}
+		}
+	}
+	return root, fsys
+}
@@ -0,0 +1,203 @@
+package tish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// PosixTimeFormat is a format of "time -p".
+const PosixTimeFormat = "real %2R\nuser %2U\nsys %2S"
+
+// CPUTimes is cumulative CPU time of the processes that the shell ran.
+type CPUTimes struct {
+	// User and System are CPU time of internal applets.
+	User   time.Duration
+	System time.Duration
+	// ChildUser and ChildSystem are CPU time of external commands.
+	ChildUser   time.Duration
+	ChildSystem time.Duration
+}
+
+// Times returns cumulative CPU time like times builtin of bash.
+func (s *Shell) Times() CPUTimes {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.times
+}
+
+func (s *Shell) addTimes(r *ExecResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if r.External() {
+		s.times.ChildUser += r.UserTime()
+		s.times.ChildSystem += r.SystemTime()
+	} else {
+		s.times.User += r.UserTime()
+		s.times.System += r.SystemTime()
+	}
+}
+
+// timeCollector sums CPU time of processes that run with the context.
+type timeCollector struct {
+	lock   sync.Mutex
+	user   time.Duration
+	system time.Duration
+}
+
+type timeCollectorKey struct{}
+
+func timeCollectorFrom(ctx context.Context) *timeCollector {
+	c, _ := ctx.Value(timeCollectorKey{}).(*timeCollector)
+	return c
+}
+
+func (c *timeCollector) add(r *ExecResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.user += r.UserTime()
+	c.system += r.SystemTime()
+}
+
+func (c *timeCollector) result(wall time.Duration) *ExecResult {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return &ExecResult{
+		wall:   wall,
+		user:   c.user,
+		system: c.system,
+	}
+}
+
+// FormatTime formats the result like TIMEFORMAT of bash.
+//
+//	%%        a literal %
+//	%[p][l]R  elapsed time in seconds
+//	%[p][l]U  user CPU time in seconds
+//	%[p][l]S  system CPU time in seconds
+//	%P        CPU percentage, computed as (U + S) / R
+//
+// p is a precision (0-3, default is 3) and l means the longer format like "1m2.345s".
+func FormatTime(format string, r *ExecResult) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		j := i + 1
+		precision := 3
+		if j < len(format) && '0' <= format[j] && format[j] <= '9' {
+			precision = int(format[j] - '0')
+			if precision > 3 {
+				precision = 3
+			}
+			j++
+		}
+		long := false
+		if j < len(format) && format[j] == 'l' {
+			long = true
+			j++
+		}
+		if j >= len(format) {
+			b.WriteString(format[i:])
+			break
+		}
+		switch format[j] {
+		case '%':
+			b.WriteByte('%')
+		case 'R':
+			b.WriteString(FormatDuration(r.WallTime(), precision, long))
+		case 'U':
+			b.WriteString(FormatDuration(r.UserTime(), precision, long))
+		case 'S':
+			b.WriteString(FormatDuration(r.SystemTime(), precision, long))
+		case 'P':
+			if r.WallTime() > 0 {
+				fmt.Fprintf(&b, "%.2f", float64(r.UserTime()+r.SystemTime())*100/float64(r.WallTime()))
+			} else {
+				b.WriteString("0.00")
+			}
+		default:
+			b.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// FormatDuration formats the duration in seconds like "1.234" or "0m1.234s" (long format).
+func FormatDuration(d time.Duration, precision int, long bool) string {
+	if long {
+		minutes := int(d / time.Minute)
+		seconds := float64(d%time.Minute) / float64(time.Second)
+		return fmt.Sprintf("%dm%.*fs", minutes, precision, seconds)
+	}
+	return fmt.Sprintf("%.*f", precision, float64(d)/float64(time.Second))
+}
+
+// FormatTimeSummary formats the result in default format of time (when TIMEFORMAT is not set).
+func FormatTimeSummary(cmd string, r *ExecResult) string {
+	user := float64(r.UserTime()) / float64(time.Second)
+	system := float64(r.SystemTime()) / float64(time.Second)
+	wall := float64(r.WallTime()) / float64(time.Second)
+	return fmt.Sprintf("%s  %.2fs user %.2fs system %d%% cpu %.3f total", cmd, user, system, r.CPUUsage(), wall)
+}
+
+// timeOption is an option of time keyword.
+type timeOption struct {
+	posix   bool
+	cmdLine string
+}
+
+// parseTimeKeyword removes "time" keyword and its options from the beginning of pipeline.
+func parseTimeKeyword(sessions []*parser.Session) (*timeOption, bool) {
+	first := sessions[0]
+	if len(first.Fragments) == 0 || first.Fragments[0].Term != "time" || len(first.Fragments[0].Sessions) > 0 {
+		return nil, false
+	}
+	opt := &timeOption{}
+	i := 1
+	for ; i < len(first.Fragments); i++ {
+		term := first.Fragments[i].Term
+		if term == "--" {
+			i++
+			break
+		} else if term == "-p" {
+			opt.posix = true
+		} else {
+			break
+		}
+	}
+	first.Fragments = first.Fragments[i:]
+	opt.cmdLine = sessionGroupString(sessions)
+	return opt, true
+}
+
+// runTimedSessionGroup runs the pipeline and reports its time to stderr like time keyword of bash.
+func (s *Shell) runTimedSessionGroup(ctx context.Context, opt *timeOption, sessions []*parser.Session, stdin io.Reader, stdout, stderr io.Writer) (result *ExecResult, err error) {
+	c := &timeCollector{}
+	start := time.Now()
+	if len(sessions) > 1 || len(sessions[0].Fragments) > 0 {
+		result, err = s.runSessionGroup(context.WithValue(ctx, timeCollectorKey{}, c), sessions, stdin, stdout, stderr)
+	}
+	total := c.result(time.Since(start))
+
+	var report string
+	if opt.posix {
+		report = FormatTime(PosixTimeFormat, total)
+	} else if format, ok := s.LookupEnv("TIMEFORMAT"); ok {
+		report = FormatTime(format, total)
+	} else {
+		report = FormatTimeSummary(opt.cmdLine, total)
+	}
+	if report != "" {
+		io.WriteString(stderr, report+"\n")
+	}
+	return result, err
+}
@@ -0,0 +1,131 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTime(t *testing.T) {
+	r := &ExecResult{
+		wall:   62*time.Second + 345*time.Millisecond,
+		user:   1500 * time.Millisecond,
+		system: 250 * time.Millisecond,
+	}
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "default precision",
+			format: "%R %U %S",
+			want:   "62.345 1.500 0.250",
+		},
+		{
+			name:   "precision",
+			format: "%0R %1U %2S %9R",
+			want:   "62 1.5 0.25 62.345",
+		},
+		{
+			name:   "long format",
+			format: "real\t%3lR\nuser\t%3lU",
+			want:   "real\t1m2.345s\nuser\t0m1.500s",
+		},
+		{
+			name:   "percentage and literal",
+			format: "%P%% %x %",
+			want:   "2.81% %x %",
+		},
+		{
+			name:   "posix",
+			format: PosixTimeFormat,
+			want:   "real 62.34\nuser 1.50\nsys 0.25",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatTime(tt.format, r))
+		})
+	}
+}
+
+func TestShell_Run_Time(t *testing.T) {
+	root := CreateTestFolders(t, "run-time")
+
+	tests := []struct {
+		name       string
+		cmd        string
+		timeFormat string
+		want       *regexp.Regexp
+	}{
+		{
+			name: "default",
+			cmd:  "time mock1 | mock2",
+			want: regexp.MustCompile(`^mock1 \| mock2  \d+\.\d\ds user \d+\.\d\ds system \d+% cpu 0\.\d{3} total\n$`),
+		},
+		{
+			name: "posix",
+			cmd:  "time -p mock1 | mock2",
+			want: regexp.MustCompile(`^real 0\.\d\d\nuser \d+\.\d\d\nsys \d+\.\d\d\n$`),
+		},
+		{
+			name:       "TIMEFORMAT",
+			cmd:        "time mock1 | mock2",
+			timeFormat: "elapsed %1R",
+			want:       regexp.MustCompile(`^elapsed 0\.\d\n$`),
+		},
+		{
+			name: "bare time",
+			cmd:  "time -p",
+			want: regexp.MustCompile(`^real 0\.00\nuser 0\.00\nsys 0\.00\n$`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewShell(root, []string{})
+			if tt.timeFormat != "" {
+				s.SetEnv("TIMEFORMAT", tt.timeFormat)
+			}
+			mock1 := registerMockCommand(t, s, "mock1")
+			mock1.Stdout = "hello"
+			registerMockCommand(t, s, "mock2")
+
+			var stdout, stderr bytes.Buffer
+			code, err := s.Run(context.Background(), tt.cmd, &stdout, &stderr)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, code)
+			assert.Regexp(t, tt.want, stderr.String())
+		})
+	}
+}
+
+func TestShell_Times(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("thread CPU time is available only on Linux")
+	}
+	root := CreateTestFolders(t, "times")
+
+	s := NewShell(root, []string{})
+	s.RegisterCommand(&Command{
+		Name: "busy",
+		Executor: func(ctx context.Context, result *ExecResult, p *Process) (err error) {
+			end := time.Now().Add(50 * time.Millisecond)
+			for time.Now().Before(end) {
+			}
+			result.SetInternalProcessResult(0)
+			return nil
+		},
+	})
+
+	_, err := s.Run(context.Background(), "busy", io.Discard, io.Discard)
+	assert.NoError(t, err)
+	times := s.Times()
+	assert.True(t, times.User+times.System > 10*time.Millisecond, times)
+}
@@ -0,0 +1,291 @@
+package tish
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// LoadWasmApplets compiles WebAssembly (WASI preview 1) modules in the directory and returns their commands.
+// The command name is the file name without ".wasm".
+//
+// Each command instantiates the module per process. Modules can access only the working directory and
+// the roots of Option.AllowPath of the real file system. If the shell is sandboxed, symbolic links to outside
+// of the roots are not followed. Modules can't start processes, so wasm applets are allowed in SafeMode
+// unlike plugins.
+func LoadWasmApplets(ctx context.Context, dir string) ([]*Command, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// the runtime is shared by commands and lives while they are used
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		return nil, err
+	}
+	var result []*Command
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".wasm") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		bin, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wasm applet %s: %w", path, err))
+			continue
+		}
+		compiled, err := rt.CompileModule(ctx, bin)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wasm applet %s: %w", path, err))
+			continue
+		}
+		wc := &wasmCommand{
+			name:     strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())),
+			runtime:  rt,
+			compiled: compiled,
+		}
+		result = append(result, &Command{
+			Name:        wc.name,
+			Description: "WebAssembly applet " + e.Name(),
+			Executor:    wc.Executor,
+		})
+	}
+	if len(errs) > 0 {
+		return result, ErrPluginLoad{Errors: errs}
+	}
+	return result, nil
+}
+
+// LoadWasmApplets registers wasm applets in the directory to the shell.
+// Broken modules are skipped and the others are registered even if it returns ErrPluginLoad.
+func (s *Shell) LoadWasmApplets(ctx context.Context, dir string) error {
+	cmds, err := LoadWasmApplets(ctx, dir)
+	for _, cmd := range cmds {
+		s.RegisterCommand(cmd)
+	}
+	return err
+}
+
+type wasmCommand struct {
+	name     string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Executor runs the module with stdio, arguments, environment variables and the preopened directories of the process.
+func (wc *wasmCommand) Executor(ctx context.Context, result *ExecResult, p *Process) error {
+	wd := p.Shell.WorkingDir()
+	env := p.Env()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	config := wazero.NewModuleConfig().
+		// empty name allows running the same module in parallel (e.g. in pipelines)
+		WithName("").
+		WithArgs(append([]string{wc.name}, p.Args...)...).
+		WithStdin(p.Stdin).
+		WithStdout(p.Stdout).
+		WithStderr(p.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader).
+		WithFSConfig(wasmFSConfig(wd, p.Shell.roots()))
+	for _, k := range keys {
+		if k != "PWD" {
+			config = config.WithEnv(k, env[k])
+		}
+	}
+	// WASI has no working directory. Programs (e.g. Go's wasip1) resolve relative paths with PWD.
+	config = config.WithEnv("PWD", wasmGuestPath(wd))
+
+	mod, err := wc.runtime.InstantiateModule(ctx, wc.compiled, config)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	if ctx.Err() != nil {
+		result.SetInternalProcessResult(130)
+		return interrupted(ctx)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		result.SetInternalProcessResult(int(exitErr.ExitCode()))
+		return nil
+	}
+	if err != nil {
+		result.SetInternalProcessResult(1)
+		return fmt.Errorf("%s: %w", wc.name, err)
+	}
+	result.SetInternalProcessResult(0)
+	return nil
+}
+
+// wasmFSConfig mounts the working directory and the allowed roots at the same paths in the module.
+// The roots are resolved already by initSandbox. If roots are given, the mounts check paths like ResolvePath.
+func wasmFSConfig(wd string, roots []string) wazero.FSConfig {
+	config := wazero.NewFSConfig()
+	if len(roots) == 0 {
+		return config.WithDirMount(wd, wasmGuestPath(wd))
+	}
+	mount := func(dir string) {
+		sandbox := &wasmSandboxFS{FS: sysfs.DirFS(dir), dir: dir, roots: roots}
+		config = config.(sysfs.FSConfig).WithSysFSMount(sandbox, wasmGuestPath(dir))
+	}
+	mount(wd)
+	for _, root := range roots {
+		if root != wd {
+			mount(root)
+		}
+	}
+	return config
+}
+
+// wasmSandboxFS rejects paths that are out of the roots after resolving symbolic links.
+// wazero's DirFS follows symbolic links, so links in the mounted directory can point anywhere.
+type wasmSandboxFS struct {
+	experimentalsys.FS
+	dir   string
+	roots []string
+}
+
+// check returns EACCES if the path is not in the roots. If follow is false, the last element is not resolved
+// for operations on the link itself like Lstat and Unlink.
+func (f *wasmSandboxFS) check(path string, follow bool) experimentalsys.Errno {
+	host := filepath.Join(f.dir, filepath.FromSlash(path))
+	var resolved string
+	if follow {
+		var err error
+		resolved, err = filepath.EvalSymlinks(host)
+		if err != nil {
+			if _, err := os.Lstat(host); err == nil {
+				// broken link: creating a file through it would write outside of the roots
+				return experimentalsys.EACCES
+			}
+			resolved = resolveSymlinks(OSFileSystem{}, host)
+		}
+	} else {
+		dir, base := filepath.Split(host)
+		resolved = filepath.Join(resolveSymlinks(OSFileSystem{}, dir), base)
+	}
+	for _, root := range f.roots {
+		if isSubPath(root, resolved) {
+			return 0
+		}
+	}
+	return experimentalsys.EACCES
+}
+
+func (f *wasmSandboxFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	if errno := f.check(path, flag&experimentalsys.O_NOFOLLOW == 0); errno != 0 {
+		return nil, errno
+	}
+	return f.FS.OpenFile(path, flag, perm)
+}
+
+func (f *wasmSandboxFS) Lstat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	if errno := f.check(path, false); errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	return f.FS.Lstat(path)
+}
+
+func (f *wasmSandboxFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	if errno := f.check(path, true); errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	return f.FS.Stat(path)
+}
+
+func (f *wasmSandboxFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	if errno := f.check(path, true); errno != 0 {
+		return errno
+	}
+	return f.FS.Mkdir(path, perm)
+}
+
+func (f *wasmSandboxFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	if errno := f.check(path, true); errno != 0 {
+		return errno
+	}
+	return f.FS.Chmod(path, perm)
+}
+
+func (f *wasmSandboxFS) Rename(from, to string) experimentalsys.Errno {
+	if errno := f.check(from, false); errno != 0 {
+		return errno
+	}
+	if errno := f.check(to, false); errno != 0 {
+		return errno
+	}
+	return f.FS.Rename(from, to)
+}
+
+func (f *wasmSandboxFS) Rmdir(path string) experimentalsys.Errno {
+	if errno := f.check(path, false); errno != 0 {
+		return errno
+	}
+	return f.FS.Rmdir(path)
+}
+
+func (f *wasmSandboxFS) Unlink(path string) experimentalsys.Errno {
+	if errno := f.check(path, false); errno != 0 {
+		return errno
+	}
+	return f.FS.Unlink(path)
+}
+
+func (f *wasmSandboxFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	if errno := f.check(oldPath, true); errno != 0 {
+		return errno
+	}
+	if errno := f.check(newPath, false); errno != 0 {
+		return errno
+	}
+	return f.FS.Link(oldPath, newPath)
+}
+
+func (f *wasmSandboxFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	// the target is checked when the link is followed
+	if errno := f.check(linkName, false); errno != 0 {
+		return errno
+	}
+	return f.FS.Symlink(oldPath, linkName)
+}
+
+func (f *wasmSandboxFS) Readlink(path string) (string, experimentalsys.Errno) {
+	if errno := f.check(path, false); errno != 0 {
+		return "", errno
+	}
+	return f.FS.Readlink(path)
+}
+
+func (f *wasmSandboxFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	if errno := f.check(path, true); errno != 0 {
+		return errno
+	}
+	return f.FS.Utimens(path, atim, mtim)
+}
+
+// wasmGuestPath converts the host path to the path in the module like "/C:/Users" for "C:\Users".
+func wasmGuestPath(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
@@ -0,0 +1,124 @@
+package tish
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildWasmApplet builds testdata/wasm/wasmtest as wasmtest.wasm into a directory.
+func buildWasmApplet(t *testing.T) string {
+	t.Helper()
+	goCmd := goCommand(t)
+	dir := CreateTestFolders(t, "wasm-applets")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	cmd := exec.Command(goCmd, "build", "-o", filepath.Join(dir, "wasmtest.wasm"), "./testdata/wasm/wasmtest")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("can't build wasm applet: %v\n%s", err, output)
+	}
+	// not a module
+	os.WriteFile(filepath.Join(dir, "broken.wasm"), []byte("broken"), 0644)
+	return dir
+}
+
+func TestShell_LoadWasmApplets(t *testing.T) {
+	dir := buildWasmApplet(t)
+	outside := CreateTestFolders(t, "wasm-outside", map[string]string{
+		"secret.txt": "secret",
+	})
+	defer os.RemoveAll(outside)
+	other := CreateTestFolders(t, "wasm-other", map[string]string{
+		"other.txt": "other",
+	})
+	defer os.RemoveAll(other)
+	wd := CreateTestFolders(t, "wasm-wd", map[string]string{
+		"sub/notes.txt": "notes",
+	})
+	defer os.RemoveAll(wd)
+	// links in the mounted directory are checked like ResolvePath
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(wd, "link-out"))
+	os.Symlink(outside, filepath.Join(wd, "dir-out"))
+	os.Symlink(filepath.Join(other, "other.txt"), filepath.Join(wd, "link-other"))
+
+	s := NewShell(wd, []string{"GREETING=hello"}, Option{SafeMode: true, AllowPath: []string{wd, other}})
+	err := s.LoadWasmApplets(context.Background(), dir)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "broken.wasm")
+	}
+	assert.True(t, s.HasCommand("wasmtest"))
+	assert.False(t, s.HasCommand("broken"))
+
+	tests := []struct {
+		name       string
+		wd         string
+		cmd        string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "args", cmd: "wasmtest echo a 'b c'", wantStdout: "wasmtest [a b c]\n"},
+		{name: "stdio", cmd: "wasmtest stdin", stdin: "input", wantStdout: "input", wantStderr: "to stderr\n"},
+		{name: "pipe", cmd: "wasmtest echo a | wasmtest stdin", wantStdout: "wasmtest [a]\n", wantStderr: "to stderr\n"},
+		{name: "env", cmd: "wasmtest env GREETING", wantStdout: "hello\n"},
+		{name: "pwd", cmd: "wasmtest env PWD", wantStdout: filepath.ToSlash(wd) + "\n"},
+		{name: "relative path", cmd: "wasmtest cat sub/notes.txt", wantStdout: "notes"},
+		{name: "subdir", wd: "sub", cmd: "wasmtest cat notes.txt", wantStdout: "notes"},
+		{name: "allowed root", cmd: "wasmtest cat " + filepath.Join(other, "other.txt"), wantStdout: "other"},
+		{name: "outside", cmd: "wasmtest cat " + filepath.Join(outside, "secret.txt"), wantCode: 1},
+		{name: "link to outside", cmd: "wasmtest cat link-out", wantCode: 1},
+		{name: "dir link to outside", cmd: "wasmtest cat dir-out/secret.txt", wantCode: 1},
+		{name: "link to allowed root", cmd: "wasmtest cat link-other", wantStdout: "other"},
+		{name: "exit code", cmd: "wasmtest exit 3", wantCode: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, s.SetWorkingDir("cd", filepath.Join(wd, tt.wd), nil))
+			var stdout, stderr bytes.Buffer
+			code, err := s.RunWithStdin(context.Background(), tt.cmd, strings.NewReader(tt.stdin), &stdout, &stderr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			if tt.wantCode == 0 {
+				assert.Equal(t, tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestShell_LoadWasmApplets_Cancel(t *testing.T) {
+	dir := buildWasmApplet(t)
+	s := NewShell(dir, []string{})
+	s.LoadWasmApplets(context.Background(), dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	code, err := s.Run(ctx, "wasmtest sleep", &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, 130, code)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestShell_LoadWasmApplets_Timeout(t *testing.T) {
+	dir := buildWasmApplet(t)
+	s := NewShell(dir, []string{}, Option{CommandTimeout: 100 * time.Millisecond})
+	s.LoadWasmApplets(context.Background(), dir)
+
+	start := time.Now()
+	code, err := s.Run(context.Background(), "wasmtest sleep", &bytes.Buffer{}, &bytes.Buffer{})
+	var limitErr *ErrLimitExceeded
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, 124, code)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,205 @@
+package tish
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/shibukawa/tish/parser"
+)
+
+// expandCommand expands wildcards in the session and returns command name and arguments.
+func (s *Shell) expandCommand(ses *parser.Session) (cmdName string, args []string, err error) {
+	var words []string
+	for _, f := range ses.Fragments {
+		expanded, err := s.expandFragment(f)
+		if err != nil {
+			return "", nil, err
+		}
+		words = append(words, expanded...)
+	}
+	if len(words) == 0 {
+		return "", nil, nil
+	}
+	return words[0], words[1:], nil
+}
+
+// expandFragment expands wildcard of the fragment and applies Option.NoMatch when no file matches.
+func (s *Shell) expandFragment(f parser.Fragment) ([]string, error) {
+	files, err := s.expandWildcard(f)
+	if errors.Is(err, ErrWildcardNoMatchError) {
+		switch s.Option().NoMatch {
+		case NullGlob:
+			return nil, nil
+		case FailGlob:
+			return nil, fmt.Errorf("no matches found: %s: %w", f.Term, ErrWildcardNoMatchError)
+		default:
+			return []string{f.Term}, nil
+		}
+	}
+	return files, err
+}
+
+// expandWildcard returns matched file paths sorted by name.
+//
+// If the fragment doesn't have wildcard, it returns Term as is.
+// If no file matches, it returns ErrWildcardNoMatchError.
+func (s *Shell) expandWildcard(f parser.Fragment) ([]string, error) {
+	pattern, ok := f.GlobPattern()
+	if !ok {
+		return []string{f.Term}, nil
+	}
+	if strings.Contains(pattern, "$") {
+		pattern = os.Expand(pattern, func(key string) string {
+			return s.Getenv(key)
+		})
+	}
+	files := s.glob(pattern)
+	if len(files) == 0 {
+		return nil, ErrWildcardNoMatchError
+	}
+	return files, nil
+}
+
+// glob searches files that match the pattern.
+//
+// Separator of pattern is always "/" because back slash is used for escaping wildcard characters.
+// Relative pattern returns relative paths from working directory.
+func (s *Shell) glob(pattern string) []string {
+	candidates := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		candidates = []string{"/"}
+		pattern = strings.TrimLeft(pattern, "/")
+	}
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		var next []string
+		for _, c := range candidates {
+			switch {
+			case seg == "":
+				// "dir//file" or trailing slash that matches only directories
+				if !last {
+					next = append(next, c)
+				} else if s.isDir(c) {
+					next = append(next, joinGlobPath(c, ""))
+				}
+			case seg == "**" && s.Option().GlobStar:
+				next = append(next, s.globStar(c, last)...)
+			case !parser.HasWildcard(seg):
+				p := joinGlobPath(c, unescapeGlob(seg))
+				if _, err := s.FileSystem().Lstat(s.ExpandPath(p)); err == nil {
+					next = append(next, p)
+				}
+			default:
+				entries, err := s.readGlobDir(c)
+				if err != nil {
+					continue
+				}
+				for _, e := range entries {
+					if !s.matchName(seg, e.Name()) {
+						continue
+					}
+					p := joinGlobPath(c, e.Name())
+					if last || s.isDir(p) {
+						next = append(next, p)
+					}
+				}
+			}
+		}
+		candidates = next
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+	return uniqueSorted(candidates)
+}
+
+// globStar returns all descendants of dir. If it is not the last segment, it returns
+// directories including dir itself because "**" matches zero or more directories.
+func (s *Shell) globStar(dir string, last bool) []string {
+	var result []string
+	if !last {
+		result = append(result, dir)
+	}
+	entries, err := s.readGlobDir(dir)
+	if err != nil {
+		return result
+	}
+	for _, e := range entries {
+		if !s.Option().DotGlob && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		p := joinGlobPath(dir, e.Name())
+		// e.IsDir() is false for symlinks, so it doesn't follow links and never loops
+		if e.IsDir() {
+			if last {
+				result = append(result, p)
+			}
+			result = append(result, s.globStar(p, last)...)
+		} else if last {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// readGlobDir reads directory entries. Directories out of Option.AllowPath are not read.
+func (s *Shell) readGlobDir(dir string) ([]os.DirEntry, error) {
+	path, err := s.ResolvePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return s.FileSystem().ReadDir(path)
+}
+
+func (s *Shell) matchName(pattern, name string) bool {
+	if strings.HasPrefix(name, ".") && !s.Option().DotGlob && !strings.HasPrefix(pattern, ".") {
+		return false
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+func (s *Shell) isDir(p string) bool {
+	return IsDir(s.FileSystem(), s.ExpandPath(p))
+}
+
+func joinGlobPath(dir, name string) string {
+	switch {
+	case dir == "":
+		return name
+	case strings.HasSuffix(dir, "/"):
+		return dir + name
+	default:
+		return dir + "/" + name
+	}
+}
+
+func unescapeGlob(pattern string) string {
+	if !strings.Contains(pattern, "\\") {
+		return pattern
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+func uniqueSorted(paths []string) []string {
+	sort.Strings(paths)
+	result := paths[:0]
+	for _, p := range paths {
+		if len(result) == 0 || result[len(result)-1] != p {
+			result = append(result, p)
+		}
+	}
+	return result
+}